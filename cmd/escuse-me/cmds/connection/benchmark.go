@@ -0,0 +1,250 @@
+package connection
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"time"
+
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	phaseDNS     = "dns"
+	phaseConnect = "connect"
+	phaseTLS     = "tls"
+	phaseServer  = "server"
+	phaseTotal   = "total"
+)
+
+// benchmarkPhases are the phases output by the benchmark, in the order of a request.
+var benchmarkPhases = []string{phaseDNS, phaseConnect, phaseTLS, phaseServer, phaseTotal}
+
+type BenchmarkCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &BenchmarkCommand{}
+
+func NewBenchmarkCommand() (*BenchmarkCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &BenchmarkCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"benchmark",
+			cmds.WithShort("Measures the round-trip overhead of requests to the cluster"),
+			cmds.WithLong(`
+Sends --requests lightweight requests to the cluster one after the other, and prints the
+min, percentiles and max duration in milliseconds of each phase of the requests:
+
+  dns      resolving the host name
+  connect  establishing the TCP connection, about one network round trip
+  tls      the TLS handshake
+  server   from sending the request to receiving the first byte of the response, which
+           is one network round trip plus the time the cluster takes to answer
+  total    the whole request, including reading the response
+
+The dns, connect and tls phases only happen when a new connection is opened, so only
+the first request measures them unless --new_connections is set. A server time much
+higher than the connect time points at the cluster rather than the network.
+
+Examples:
+
+   escuse-me connection benchmark
+
+   escuse-me connection benchmark --requests 100 --new_connections
+
+   escuse-me connection benchmark --path /_cluster/health
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"requests",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of requests to send"),
+					parameters.WithDefault(20),
+				),
+				parameters.NewParameterDefinition(
+					"path",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Path of the GET request to send"),
+					parameters.WithDefault("/"),
+				),
+				parameters.NewParameterDefinition(
+					"new_connections",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Open a new connection for each request instead of reusing them"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type BenchmarkSettings struct {
+	Requests       int    `glazed.parameter:"requests"`
+	Path           string `glazed.parameter:"path"`
+	NewConnections bool   `glazed.parameter:"new_connections"`
+}
+
+func (c *BenchmarkCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *BenchmarkCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &BenchmarkSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+	if s.Requests <= 0 {
+		return errors.New("--requests must be positive")
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	durations := map[string][]time.Duration{}
+	failures := 0
+	for i := 0; i < s.Requests; i++ {
+		timings, err := timeRequest(ctx, es.Transport, s.Path, s.NewConnections)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warn().Err(err).Int("request", i+1).Msg("request failed")
+			failures++
+			continue
+		}
+		for phase, d := range timings {
+			durations[phase] = append(durations[phase], d)
+		}
+	}
+	if failures == s.Requests {
+		return errors.Errorf("all %d requests failed", s.Requests)
+	}
+	if failures > 0 {
+		log.Warn().Int("failures", failures).Msg("some requests failed and are not included in the timings")
+	}
+
+	for _, phase := range benchmarkPhases {
+		samples := durations[phase]
+		if len(samples) == 0 {
+			continue
+		}
+		if err := gp.AddRow(ctx, benchmarkPhaseRow(phase, samples)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// timeRequest sends a GET request to path and returns the duration of each of its
+// phases. The phases of opening a connection are missing when a connection is reused.
+func timeRequest(
+	ctx context.Context,
+	transport interface {
+		Perform(*http.Request) (*http.Response, error)
+	},
+	path string,
+	newConnection bool,
+) (map[string]time.Duration, error) {
+	timings := map[string]time.Duration{}
+	var dnsStart, connectStart, tlsStart, wroteRequest time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			timings[phaseDNS] = time.Since(dnsStart)
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(_ string, _ string, err error) {
+			if err == nil {
+				timings[phaseConnect] = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				timings[phaseTLS] = time.Since(tlsStart)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() {
+			timings[phaseServer] = time.Since(wroteRequest)
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Close = newConnection
+
+	start := time.Now()
+	// the request goes through the transport directly, so that the product check of
+	// the client doesn't add a request to the first measurement
+	res, err := transport.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return nil, err
+	}
+	timings[phaseTotal] = time.Since(start)
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, errors.Errorf("[%d] %s", res.StatusCode, http.StatusText(res.StatusCode))
+	}
+	return timings, nil
+}
+
+func benchmarkPhaseRow(phase string, samples []time.Duration) types.Row {
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	ms := func(d time.Duration) float64 {
+		return math.Round(float64(d)/float64(time.Millisecond)*1000) / 1000
+	}
+	return types.NewRow(
+		types.MRP("phase", phase),
+		types.MRP("samples", len(samples)),
+		types.MRP("min_ms", ms(samples[0])),
+		types.MRP("p50_ms", ms(percentile(samples, 50))),
+		types.MRP("p90_ms", ms(percentile(samples, 90))),
+		types.MRP("p99_ms", ms(percentile(samples, 99))),
+		types.MRP("max_ms", ms(samples[len(samples)-1])),
+	)
+}
+
+// percentile returns the nearest-rank percentile p of the sorted samples.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(samples))))
+	return samples[max(rank-1, 0)]
+}
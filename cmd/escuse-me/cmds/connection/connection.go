@@ -0,0 +1,52 @@
+package connection
+
+import (
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	"github.com/spf13/cobra"
+)
+
+func AddToRootCommand(rootCmd *cobra.Command) error {
+	connectionCommand := &cobra.Command{
+		Use:   "connection",
+		Short: "ES connection related commands",
+	}
+	rootCmd.AddCommand(connectionCommand)
+
+	showCommand, err := NewShowCommand()
+	if err != nil {
+		return err
+	}
+	showCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(showCommand)
+	if err != nil {
+		return err
+	}
+	connectionCommand.AddCommand(showCmd)
+
+	benchmarkCommand, err := NewBenchmarkCommand()
+	if err != nil {
+		return err
+	}
+	benchmarkCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(benchmarkCommand)
+	if err != nil {
+		return err
+	}
+	connectionCommand.AddCommand(benchmarkCmd)
+
+	profilesCommand := &cobra.Command{
+		Use:   "profiles",
+		Short: "Connection profile related commands",
+	}
+	connectionCommand.AddCommand(profilesCommand)
+
+	profilesDiffCommand, err := NewProfilesDiffCommand()
+	if err != nil {
+		return err
+	}
+	profilesDiffCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(profilesDiffCommand)
+	if err != nil {
+		return err
+	}
+	profilesCommand.AddCommand(profilesDiffCmd)
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type ProfilesDiffCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ProfilesDiffCommand{}
+
+func NewProfilesDiffCommand() (*ProfilesDiffCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+
+	return &ProfilesDiffCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"diff",
+			cmds.WithShort("Shows the settings that differ between two profiles"),
+			cmds.WithLong(`
+The 'diff' command compares two profiles of the profiles file (selected on other commands
+with --profile) and outputs a row per setting that differs, with the columns layer, key,
+value_a and value_b. Credentials are redacted, and the resulting authentication method
+is compared as well.
+
+Use it to understand what changes when switching between profiles:
+
+   escuse-me connection profiles diff staging prod
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"profiles_file",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Profiles file (default: ~/.config/escuse-me/profiles.yaml)"),
+				),
+			),
+			cmds.WithArguments(
+				parameters.NewParameterDefinition(
+					"profile_a",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("First profile"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"profile_b",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Second profile"),
+					parameters.WithRequired(true),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer),
+		),
+	}, nil
+}
+
+type ProfilesDiffSettings struct {
+	ProfilesFile string `glazed.parameter:"profiles_file"`
+	ProfileA     string `glazed.parameter:"profile_a"`
+	ProfileB     string `glazed.parameter:"profile_b"`
+}
+
+func (c *ProfilesDiffCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ProfilesDiffSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	profilesFile := s.ProfilesFile
+	if profilesFile == "" {
+		var err error
+		profilesFile, err = es_cmds.GetDefaultProfileFile()
+		if err != nil {
+			return err
+		}
+	}
+
+	profiles, err := es_cmds.LoadProfiles(profilesFile)
+	if err != nil {
+		return err
+	}
+	a, ok := profiles[s.ProfileA]
+	if !ok {
+		return errors.Errorf("profile %s not found in %s", s.ProfileA, profilesFile)
+	}
+	b, ok := profiles[s.ProfileB]
+	if !ok {
+		return errors.Errorf("profile %s not found in %s", s.ProfileB, profilesFile)
+	}
+
+	layerNames := unionKeys(a, b)
+	for _, layer := range layerNames {
+		layerA, layerB := a[layer], b[layer]
+		for _, key := range unionKeys(layerA, layerB) {
+			valueA, valueB := layerA[key], layerB[key]
+			if reflect.DeepEqual(valueA, valueB) {
+				continue
+			}
+			if layer == es_layers.EsConnectionSlug && es_layers.IsSecretParameter(key) {
+				valueA, valueB = redactProfileValue(valueA), redactProfileValue(valueB)
+			}
+			if err := addDiffRow(ctx, gp, layer, key, valueA, valueB); err != nil {
+				return err
+			}
+		}
+	}
+
+	authA := profileAuthMethod(a[es_layers.EsConnectionSlug])
+	authB := profileAuthMethod(b[es_layers.EsConnectionSlug])
+	if authA != authB {
+		if err := addDiffRow(ctx, gp, es_layers.EsConnectionSlug, "auth-method", authA, authB); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addDiffRow(
+	ctx context.Context,
+	gp middlewares.Processor,
+	layer string,
+	key string,
+	valueA interface{},
+	valueB interface{},
+) error {
+	row := types.NewRow(
+		types.MRP("layer", layer),
+		types.MRP("key", key),
+		types.MRP("value_a", valueA),
+		types.MRP("value_b", valueB),
+	)
+	return gp.AddRow(ctx, row)
+}
+
+// profileAuthMethod returns the authentication method resulting from the es-connection
+// values of a profile, see EsClientSettings.AuthMethod.
+func profileAuthMethod(values map[string]interface{}) string {
+	s := &es_layers.EsClientSettings{
+		Username:     fmt.Sprint(valueOrEmpty(values, "username")),
+		ApiKey:       fmt.Sprint(valueOrEmpty(values, "api-key")),
+		ServiceToken: fmt.Sprint(valueOrEmpty(values, "service-token")),
+	}
+	return s.AuthMethod()
+}
+
+func valueOrEmpty(values map[string]interface{}, key string) interface{} {
+	if v, ok := values[key]; ok && v != nil {
+		return v
+	}
+	return ""
+}
+
+// redactProfileValue hides credentials, while still showing whether they are set.
+func redactProfileValue(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+	return "<redacted>"
+}
+
+func unionKeys[V any](a map[string]V, b map[string]V) []string {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	ret := make([]string, 0, len(keys))
+	for k := range keys {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
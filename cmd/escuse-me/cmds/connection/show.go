@@ -0,0 +1,103 @@
+package connection
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/pkg/errors"
+)
+
+type ShowCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ShowCommand{}
+
+func NewShowCommand() (*ShowCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ShowCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"show",
+			cmds.WithShort("Prints the resolved connection settings"),
+			cmds.WithLong(`
+The 'show' command prints the connection settings escuse-me will use after merging
+the config file, environment variables and command line flags, without connecting
+to the cluster. Passwords, API keys and service tokens are redacted.
+
+Use it to debug why escuse-me connects to an unexpected cluster:
+
+   escuse-me connection show --output yaml
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"verbose",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Also print retry and transport settings"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ShowSettings struct {
+	Verbose bool `glazed.parameter:"verbose"`
+}
+
+func (c *ShowCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ShowSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	row := esSettings.GetSummary(s.Verbose)
+	if s.Verbose {
+		// the client uses the default http transport, which resolves proxies from
+		// the HTTP_PROXY / HTTPS_PROXY / NO_PROXY environment variables
+		proxies := map[string]interface{}{}
+		for _, address := range esSettings.Addresses {
+			proxies[address] = resolveProxy(address)
+		}
+		row.Set("transport", "default")
+		row.Set("proxies", proxies)
+	}
+
+	return gp.AddRow(ctx, row)
+}
+
+func resolveProxy(address string) string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u})
+	if err != nil || proxy == nil {
+		return ""
+	}
+	return proxy.Redacted()
+}
@@ -0,0 +1,265 @@
+package documents
+
+import (
+	"context"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type BulkCASUpdateCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &BulkCASUpdateCommand{}
+
+func NewBulkCASUpdateCommand() (*BulkCASUpdateCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &BulkCASUpdateCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"bulk-cas-update",
+			cmds.WithShort("Bulk updates documents using compare-and-swap on their sequence number"),
+			cmds.WithLong(`
+The 'bulk-cas-update' command reads entries of the form
+
+   {"_id": "1", "_seq_no": 12, "_primary_term": 1, "doc": {"status": "done"}}
+
+from JSON, YAML or NDJSON files and issues bulk partial updates with if_seq_no and
+if_primary_term set for each document. Documents that were modified since they were read
+are reported as conflicts instead of being overwritten.
+
+An entry can optionally provide "_index" and "routing", otherwise --index is used.
+
+Entries without a "doc" object are the flat rows output by a search run with
+--emit_versioning: all their fields not starting with _ form the partial document, and
+"_routing" is used as routing. The rows are typically edited before being fed back:
+
+   escuse-me documents search --index tasks --emit_versioning --output json \
+      | jq '[.[] | .status = "done"]' > tasks.json
+   escuse-me documents bulk-cas-update --index tasks tasks.json
+
+Each item is output as a row with a result column that is either the ES result
+(updated, noop), "conflict" or "failed".
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Default index for entries which don't provide one"),
+				),
+				parameters.NewParameterDefinition(
+					"chunk_size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of update actions sent per bulk request"),
+					parameters.WithDefault(defaultBulkChunkSize),
+				),
+				parameters.NewParameterDefinition(
+					"refresh",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Control when the changes made by this request are visible to search"),
+					parameters.WithChoices("true", "false", "wait_for"),
+				),
+				parameters.NewParameterDefinition(
+					"only_failures",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only output conflicts and failed items"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithArguments(
+				parameters.NewParameterDefinition(
+					"files",
+					parameters.ParameterTypeObjectListFromFiles,
+					parameters.WithHelp("Files containing the versioned update entries"),
+					parameters.WithRequired(true),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type BulkCASUpdateSettings struct {
+	Index        string                   `glazed.parameter:"index"`
+	ChunkSize    int                      `glazed.parameter:"chunk_size"`
+	Refresh      *string                  `glazed.parameter:"refresh"`
+	OnlyFailures bool                     `glazed.parameter:"only_failures"`
+	Files        []map[string]interface{} `glazed.parameter:"files"`
+}
+
+// newCASUpdateAction builds a bulk update action guarded by if_seq_no/if_primary_term
+// from a versioned entry, either holding a doc object or flat as output by search
+// --emit_versioning.
+func newCASUpdateAction(entry map[string]interface{}, defaultIndex string) (BulkAction, error) {
+	id, ok := entry["_id"]
+	if !ok {
+		return BulkAction{}, errors.New("entry is missing _id")
+	}
+	seqNo, ok := entry["_seq_no"]
+	if !ok {
+		return BulkAction{}, errors.Errorf("entry %v is missing _seq_no", id)
+	}
+	primaryTerm, ok := entry["_primary_term"]
+	if !ok {
+		return BulkAction{}, errors.Errorf("entry %v is missing _primary_term", id)
+	}
+	routing, hasRouting := entry["routing"]
+	doc, ok := entry["doc"].(map[string]interface{})
+	if !ok {
+		if _, hasDoc := entry["doc"]; hasDoc {
+			return BulkAction{}, errors.Errorf("doc of entry %v is not an object", id)
+		}
+		doc = map[string]interface{}{}
+		for k, v := range entry {
+			if !strings.HasPrefix(k, "_") {
+				doc[k] = v
+			}
+		}
+		if len(doc) == 0 {
+			return BulkAction{}, errors.Errorf("entry %v has neither a doc object nor document fields", id)
+		}
+		routing, hasRouting = entry["_routing"]
+	}
+
+	index := defaultIndex
+	if index_, ok := entry["_index"].(string); ok && index_ != "" {
+		index = index_
+	}
+	if index == "" {
+		return BulkAction{}, errors.Errorf("entry %v has no _index and no --index was given", id)
+	}
+
+	meta := map[string]interface{}{
+		"_index":          index,
+		"_id":             id,
+		"if_seq_no":       seqNo,
+		"if_primary_term": primaryTerm,
+	}
+	if hasRouting {
+		meta["routing"] = routing
+	}
+
+	return BulkAction{
+		Action: "update",
+		Meta:   meta,
+		Source: map[string]interface{}{"doc": doc},
+	}, nil
+}
+
+func (c *BulkCASUpdateCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &BulkCASUpdateSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	actions := make([]BulkAction, 0, len(s.Files))
+	for _, entry := range s.Files {
+		action, err := newCASUpdateAction(entry, s.Index)
+		if err != nil {
+			return err
+		}
+		actions = append(actions, action)
+	}
+
+	options := []func(*esapi.BulkRequest){}
+	if s.Refresh != nil {
+		options = append(options, es.Bulk.WithRefresh(*s.Refresh))
+	}
+
+	results, err := executeChunkedBulk(ctx, es, actions, s.ChunkSize, options...)
+	if err != nil {
+		return err
+	}
+
+	stats, err := emitCASUpdateResults(ctx, results, s.OnlyFailures, gp)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("updated", stats.Updated).
+		Int("conflicts", stats.Conflicts).
+		Int("failures", stats.Failures).
+		Msg("bulk compare-and-swap update done")
+
+	return nil
+}
+
+type casUpdateStats struct {
+	Updated   int
+	Conflicts int
+	Failures  int
+}
+
+func (s *casUpdateStats) add(other casUpdateStats) {
+	s.Updated += other.Updated
+	s.Conflicts += other.Conflicts
+	s.Failures += other.Failures
+}
+
+// emitCASUpdateResults outputs a row per bulk update result, with a result column that
+// is either the ES result (updated, noop), "conflict" or "failed".
+func emitCASUpdateResults(
+	ctx context.Context,
+	results []BulkItemResult,
+	onlyFailures bool,
+	gp middlewares.Processor,
+) (casUpdateStats, error) {
+	stats := casUpdateStats{}
+	for _, result := range results {
+		result_ := result.Result
+		switch {
+		case result.IsConflict():
+			stats.Conflicts++
+			result_ = "conflict"
+		case result.IsError():
+			stats.Failures++
+			result_ = "failed"
+		default:
+			stats.Updated++
+			if onlyFailures {
+				continue
+			}
+		}
+
+		row := types.NewRow(
+			types.MRP("_index", result.Index),
+			types.MRP("_id", result.ID),
+			types.MRP("status", result.Status),
+			types.MRP("result", result_),
+			types.MRP("error_type", result.ErrorType),
+			types.MRP("reason", result.ErrorReason),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,83 @@
+package documents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func TestBulkCASUpdateAcceptsSearchRows(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleBulk()
+
+	cmd, err := NewBulkCASUpdateCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a row of search --emit_versioning, and an entry with a doc object
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"index": "tasks",
+			"files": []interface{}{
+				map[string]interface{}{"_id": "1", "_version": 3, "_seq_no": 12, "_primary_term": 1, "status": "done"},
+				map[string]interface{}{"_id": "2", "_seq_no": 13, "_primary_term": 1, "routing": "a", "doc": map[string]interface{}{"status": "done"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	for i, row := range rows {
+		if result, _ := row.Get("result"); result != "updated" {
+			t.Errorf("row %d: expected result updated, got %v", i, result)
+		}
+	}
+
+	requests := append(server.RequestsTo(http.MethodPost, "/_bulk"), server.RequestsTo(http.MethodPost, "/*/_bulk")...)
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 bulk request, got %d", len(requests))
+	}
+	lines := []map[string]map[string]interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(requests[0].Body))
+	for scanner.Scan() {
+		line := map[string]map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 bulk lines, got %d", len(lines))
+	}
+
+	expectedMeta := []map[string]interface{}{
+		{"_index": "tasks", "_id": "1", "if_seq_no": float64(12), "if_primary_term": float64(1)},
+		{"_index": "tasks", "_id": "2", "if_seq_no": float64(13), "if_primary_term": float64(1), "routing": "a"},
+	}
+	for i, expected := range expectedMeta {
+		meta := lines[2*i]["update"]
+		if len(meta) != len(expected) {
+			t.Errorf("entry %d: unexpected action %v", i, meta)
+		}
+		for k, v := range expected {
+			if meta[k] != v {
+				t.Errorf("entry %d: expected %s %v, got %v", i, k, v, meta[k])
+			}
+		}
+		doc := lines[2*i+1]["doc"]
+		if len(doc) != 1 || doc["status"] != "done" {
+			t.Errorf("entry %d: unexpected doc %v", i, doc)
+		}
+	}
+}
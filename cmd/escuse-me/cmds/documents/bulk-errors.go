@@ -0,0 +1,63 @@
+package documents
+
+import (
+	"regexp"
+	"sort"
+)
+
+// BulkErrorGroup counts the failed items of a bulk request sharing the same action,
+// error type and reason pattern.
+type BulkErrorGroup struct {
+	Action       string
+	Type         string
+	Reason       string
+	Count        int
+	SampleID     string
+	SampleReason string
+}
+
+var (
+	quotedValueRegexp = regexp.MustCompile(`'[^']*'`)
+	numberRegexp      = regexp.MustCompile(`\b\d+\b`)
+)
+
+// bulkErrorReasonPattern replaces the document specific parts of a bulk error reason,
+// the quoted values and numbers, so that failures caused by the same problem share a
+// pattern. Bracketed field names and types are kept.
+func bulkErrorReasonPattern(reason string) string {
+	pattern := quotedValueRegexp.ReplaceAllString(reason, "'…'")
+	return numberRegexp.ReplaceAllString(pattern, "N")
+}
+
+// groupBulkErrors groups the failed items of a bulk response, most frequent first.
+func groupBulkErrors(response *BulkErrorResponse) []*BulkErrorGroup {
+	groups := map[[3]string]*BulkErrorGroup{}
+	ret := []*BulkErrorGroup{}
+	for _, item := range response.Items {
+		for action, result := range item {
+			if result.Error.Type == "" {
+				continue
+			}
+			pattern := bulkErrorReasonPattern(result.Error.Reason)
+			key := [3]string{action, result.Error.Type, pattern}
+			group, ok := groups[key]
+			if !ok {
+				group = &BulkErrorGroup{
+					Action:       action,
+					Type:         result.Error.Type,
+					Reason:       pattern,
+					SampleID:     result.ID,
+					SampleReason: result.Error.Reason,
+				}
+				groups[key] = group
+				ret = append(ret, group)
+			}
+			group.Count++
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Count > ret[j].Count
+	})
+	return ret
+}
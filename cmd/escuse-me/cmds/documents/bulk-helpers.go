@@ -0,0 +1,202 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/pkg/errors"
+)
+
+const defaultBulkChunkSize = 500
+
+// BulkAction is a single action of a bulk request, consisting of the action name
+// (index, create, update, delete), its metadata (_index, _id, if_seq_no, ...) and
+// an optional source line (absent for delete actions).
+type BulkAction struct {
+	Action string
+	Meta   map[string]interface{}
+	Source interface{}
+}
+
+// BulkItemResult is the outcome of a single bulk action, as reported in the items
+// of the bulk response.
+type BulkItemResult struct {
+	Action      string `json:"action"`
+	Index       string `json:"_index"`
+	ID          string `json:"_id"`
+	Status      int    `json:"status"`
+	Result      string `json:"result,omitempty"`
+	ErrorType   string `json:"error_type,omitempty"`
+	ErrorReason string `json:"error_reason,omitempty"`
+}
+
+// IsConflict returns true if the action failed because of a version conflict,
+// for example when using if_seq_no/if_primary_term or op_type create.
+func (r *BulkItemResult) IsConflict() bool {
+	return r.Status == 409 || r.ErrorType == "version_conflict_engine_exception"
+}
+
+// IsError returns true if the action failed.
+func (r *BulkItemResult) IsError() bool {
+	return r.ErrorType != "" || r.Status >= 300
+}
+
+type bulkItemResponse struct {
+	Index  string `json:"_index"`
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Result string `json:"result"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+type bulkItemsResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkItemResponse `json:"items"`
+}
+
+func writeBulkAction(buf *bytes.Buffer, action BulkAction) error {
+	meta, err := json.Marshal(map[string]interface{}{
+		action.Action: action.Meta,
+	})
+	if err != nil {
+		return err
+	}
+	buf.Write(meta)
+	buf.WriteString("\n")
+
+	if action.Source != nil {
+		source, err := json.Marshal(action.Source)
+		if err != nil {
+			return err
+		}
+		buf.Write(source)
+		buf.WriteString("\n")
+	}
+
+	return nil
+}
+
+// executeChunkedBulk sends the actions to the bulk API in chunks of chunkSize actions,
+// and returns the per-item results of all chunks in order.
+func executeChunkedBulk(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	actions []BulkAction,
+	chunkSize int,
+	options ...func(*esapi.BulkRequest),
+) ([]BulkItemResult, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultBulkChunkSize
+	}
+
+	ret := []BulkItemResult{}
+
+	for start := 0; start < len(actions); start += chunkSize {
+		end := start + chunkSize
+		if end > len(actions) {
+			end = len(actions)
+		}
+
+		var buf bytes.Buffer
+		for _, action := range actions[start:end] {
+			if err := writeBulkAction(&buf, action); err != nil {
+				return ret, err
+			}
+		}
+
+		options_ := append([]func(*esapi.BulkRequest){es.Bulk.WithContext(ctx)}, options...)
+		results, err := executeBulkRequest(es, &buf, options_...)
+		if err != nil {
+			return ret, errors.Wrapf(err, "bulk request for actions %d-%d failed", start, end)
+		}
+		ret = append(ret, results...)
+	}
+
+	return ret, nil
+}
+
+func executeBulkRequest(
+	es *elasticsearch.Client,
+	body io.Reader,
+	options ...func(*esapi.BulkRequest),
+) ([]BulkItemResult, error) {
+	res, err := es.Bulk(body, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(responseBody); err != nil {
+		return nil, err
+	}
+
+	var response bulkItemsResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal bulk response")
+	}
+
+	ret := make([]BulkItemResult, 0, len(response.Items))
+	for _, item := range response.Items {
+		for action, result := range item {
+			r := BulkItemResult{
+				Action: action,
+				Index:  result.Index,
+				ID:     result.ID,
+				Status: result.Status,
+				Result: result.Result,
+			}
+			if result.Error != nil {
+				r.ErrorType = result.Error.Type
+				r.ErrorReason = result.Error.Reason
+			}
+			ret = append(ret, r)
+		}
+	}
+
+	return ret, nil
+}
+
+// compressBulkBody gzips a bulk body read from files if the compress-request-body
+// setting asks for it, and returns the body to send along with the options setting the
+// matching headers.
+func compressBulkBody(
+	es *elasticsearch.Client,
+	parsedLayers *layers.ParsedLayers,
+	body io.Reader,
+) (io.Reader, []func(*esapi.BulkRequest), error) {
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b, headers, err := esSettings.CompressLargeBody(b)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not compress bulk body")
+	}
+	options := []func(*esapi.BulkRequest){}
+	if len(headers) > 0 {
+		options = append(options, es.Bulk.WithHeader(headers))
+	}
+	return bytes.NewReader(b), options, nil
+}
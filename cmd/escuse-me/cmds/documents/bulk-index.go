@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -14,21 +16,69 @@ import (
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"io"
+	"os"
+	"strconv"
 )
 
-func interleaveBulkIndexObjects(objects []map[string]interface{}, index string) (io.Reader, error) {
+// bulkActionOptions configures the action line interleaveBulkIndexObjects writes before
+// each document.
+type bulkActionOptions struct {
+	Index        string
+	IDField      string
+	RoutingField string
+	// StripFields removes the id and routing fields from the indexed source.
+	StripFields bool
+}
+
+func interleaveBulkIndexObjects(objects []map[string]interface{}, options bulkActionOptions) (io.Reader, error) {
 	var buffer bytes.Buffer
 
-	for _, object := range objects {
-		jsonLine, err := json.Marshal(object)
+	for i, object := range objects {
+		action := map[string]interface{}{}
+		if options.Index != "" {
+			action["_index"] = options.Index
+		}
+
+		source := object
+		if options.StripFields && (options.IDField != "" || options.RoutingField != "") {
+			source = make(map[string]interface{}, len(object))
+			for k, v := range object {
+				source[k] = v
+			}
+		}
+
+		for _, f := range []struct {
+			field string
+			key   string
+		}{
+			{options.IDField, "_id"},
+			{options.RoutingField, "routing"},
+		} {
+			if f.field == "" {
+				continue
+			}
+			v, ok := object[f.field]
+			if !ok || v == nil {
+				return nil, errors.Errorf("document %d has no field %s to use as %s", i, f.field, f.key)
+			}
+			action[f.key] = bulkKeyValue(v)
+			if options.StripFields {
+				delete(source, f.field)
+			}
+		}
+
+		actionLine, err := json.Marshal(map[string]interface{}{"index": action})
 		if err != nil {
 			return nil, err
 		}
-		// Construct the index command with the specified index.
-		// You might need to adjust this if you have a more complex requirement for the index command.
-		indexCommand := fmt.Sprintf(`{ "index" : { "_index" : "%s" } }%s`, index, "\n")
-		buffer.WriteString(indexCommand)
+		jsonLine, err := json.Marshal(source)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(actionLine)
+		buffer.WriteString("\n")
 		buffer.Write(jsonLine)
 		buffer.WriteString("\n")
 	}
@@ -36,6 +86,15 @@ func interleaveBulkIndexObjects(objects []map[string]interface{}, index string)
 	return &buffer, nil
 }
 
+// bulkKeyValue renders a document field as an _id or routing value, avoiding the
+// exponent notation fmt uses for large numbers decoded from JSON.
+func bulkKeyValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
 type BulkIndexCommand struct {
 	*cmds.CommandDescription
 }
@@ -56,6 +115,16 @@ func NewBulkIndexCommand() (*BulkIndexCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"bulk-index",
 			cmds.WithShort("Bulk indexes documents"),
+			cmds.WithLong(`
+Bulk indexes the documents of the given files into --index.
+
+With --json_lines_input, documents are also read from stdin, as one JSON object per
+line (or a JSON array), each object being the source of a document. This allows piping
+the output of another command:
+
+   escuse-me search --index products --output json --output-as-objects \
+      | escuse-me documents bulk-index --index products-copy --json_lines_input
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"index",
@@ -108,13 +177,57 @@ func NewBulkIndexCommand() (*BulkIndexCommand, error) {
 					parameters.ParameterTypeBool,
 					parameters.WithHelp("If true, the request's actions must target an index alias"),
 				),
+				parameters.NewParameterDefinition(
+					"verify_count",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Refresh the index after indexing and check that it contains as many documents as were read from the input files"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"optimize_for_bulk",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Disable refreshes and replicas of the index during the load, then restore the original settings and refresh it"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"json_lines_input",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Read documents to index from stdin, one JSON object per line"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"id_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Field of each document to use as its _id"),
+				),
+				parameters.NewParameterDefinition(
+					"routing_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Field of each document to use as its routing value"),
+				),
+				parameters.NewParameterDefinition(
+					"strip_key_fields",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Remove the --id_field and --routing_field fields from the indexed documents"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"group_errors",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Output failures grouped by error type and reason, with a count and a sample document id, instead of one row per failure"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"expected_count",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Expected number of documents in the index when using --verify_count (default: number of input documents)"),
+				),
 			),
 			cmds.WithArguments(
 				parameters.NewParameterDefinition(
 					"files",
 					parameters.ParameterTypeObjectListFromFiles,
 					parameters.WithHelp("Files containing bulk index documents, the command will interleave it with ES index commands"),
-					parameters.WithRequired(true),
 				),
 			),
 			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
@@ -133,6 +246,14 @@ type BulkIndexSettings struct {
 	SourceIncludes      *[]string                `glazed.parameter:"source_includes"`
 	WaitForActiveShards *string                  `glazed.parameter:"wait_for_active_shards"`
 	RequireAlias        *bool                    `glazed.parameter:"require_alias"`
+	VerifyCount         bool                     `glazed.parameter:"verify_count"`
+	ExpectedCount       *int                     `glazed.parameter:"expected_count"`
+	OptimizeForBulk     bool                     `glazed.parameter:"optimize_for_bulk"`
+	IDField             string                   `glazed.parameter:"id_field"`
+	RoutingField        string                   `glazed.parameter:"routing_field"`
+	StripKeyFields      bool                     `glazed.parameter:"strip_key_fields"`
+	JSONLinesInput      bool                     `glazed.parameter:"json_lines_input"`
+	GroupErrors         bool                     `glazed.parameter:"group_errors"`
 	Files               []map[string]interface{} `glazed.parameter:"files"`
 }
 
@@ -140,7 +261,7 @@ func (c *BulkIndexCommand) RunIntoGlazeProcessor(
 	ctx context.Context,
 	parsedLayers *layers.ParsedLayers,
 	gp middlewares.Processor,
-) error {
+) (err error) {
 	s := &BulkIndexSettings{}
 	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
 		return err
@@ -151,6 +272,36 @@ func (c *BulkIndexCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	if s.JSONLinesInput {
+		documents, err := helpers.ReadJSONObjects(os.Stdin)
+		if err != nil {
+			return errors.Wrap(err, "could not read documents from stdin")
+		}
+		s.Files = append(s.Files, documents...)
+	}
+	if len(s.Files) == 0 {
+		return errors.New("no documents to index, pass files or --json_lines_input")
+	}
+
+	if s.VerifyCount && s.Index == nil {
+		return errors.New("--verify_count requires --index")
+	}
+
+	if s.OptimizeForBulk {
+		if s.Index == nil {
+			return errors.New("--optimize_for_bulk requires --index")
+		}
+		restore, optimizeErr := optimizeIndexForBulk(ctx, es, *s.Index)
+		if optimizeErr != nil {
+			return optimizeErr
+		}
+		defer func() {
+			if restoreErr := restore(); restoreErr != nil && err == nil {
+				err = restoreErr
+			}
+		}()
+	}
+
 	options := []func(*esapi.BulkRequest){
 		es.Bulk.WithContext(ctx),
 	}
@@ -185,10 +336,23 @@ func (c *BulkIndexCommand) RunIntoGlazeProcessor(
 		options = append(options, es.Bulk.WithRequireAlias(*s.RequireAlias))
 	}
 
-	bodyReader, err := interleaveBulkIndexObjects(s.Files, *s.Index)
+	actionOptions := bulkActionOptions{
+		IDField:      s.IDField,
+		RoutingField: s.RoutingField,
+		StripFields:  s.StripKeyFields,
+	}
+	if s.Index != nil {
+		actionOptions.Index = *s.Index
+	}
+	bodyReader, err := interleaveBulkIndexObjects(s.Files, actionOptions)
+	if err != nil {
+		return err
+	}
+	bodyReader, compressionOptions, err := compressBulkBody(es, parsedLayers, bodyReader)
 	if err != nil {
 		return err
 	}
+	options = append(options, compressionOptions...)
 
 	bulkIndexResponse, err := es.Bulk(
 		bodyReader,
@@ -212,6 +376,23 @@ func (c *BulkIndexCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	if bulkErrorResponse.Errors && s.GroupErrors {
+		for _, group := range groupBulkErrors(&bulkErrorResponse) {
+			row := types.NewRow(
+				types.MRP("action", group.Action),
+				types.MRP("type", group.Type),
+				types.MRP("reason", group.Reason),
+				types.MRP("count", group.Count),
+				types.MRP("sample_id", group.SampleID),
+				types.MRP("sample_reason", group.SampleReason),
+			)
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+		}
+		return c.verifyCount(ctx, es, s)
+	}
+
 	if bulkErrorResponse.Errors {
 		for _, item := range bulkErrorResponse.Items {
 			for action, result := range item {
@@ -225,7 +406,7 @@ func (c *BulkIndexCommand) RunIntoGlazeProcessor(
 				}
 			}
 		}
-		return nil
+		return c.verifyCount(ctx, es, s)
 	}
 
 	var bulkResponse BulkResponse
@@ -240,5 +421,45 @@ func (c *BulkIndexCommand) RunIntoGlazeProcessor(
 		}
 	}
 
+	return c.verifyCount(ctx, es, s)
+}
+
+// verifyCount refreshes the target index and checks that it contains the expected number
+// of documents, to catch documents silently dropped by mapping rejections or pipelines.
+func (c *BulkIndexCommand) verifyCount(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	s *BulkIndexSettings,
+) error {
+	if !s.VerifyCount {
+		return nil
+	}
+
+	expected := int64(len(s.Files))
+	if s.ExpectedCount != nil {
+		expected = int64(*s.ExpectedCount)
+	}
+
+	if err := helpers.RefreshIndex(ctx, es, *s.Index); err != nil {
+		return errors.Wrap(err, "could not refresh index")
+	}
+	actual, err := helpers.CountDocuments(ctx, es, *s.Index, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not count documents")
+	}
+
+	if actual != expected {
+		return errors.Errorf(
+			"document count mismatch in %s: expected %d, actual %d (delta %d)",
+			*s.Index, expected, actual, actual-expected,
+		)
+	}
+
+	log.Info().
+		Str("index", *s.Index).
+		Int64("expected", expected).
+		Int64("actual", actual).
+		Msg("document count verified")
+
 	return nil
 }
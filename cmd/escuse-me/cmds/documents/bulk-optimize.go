@@ -0,0 +1,84 @@
+package documents
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// bulkOptimizedSettings are the index settings applied during bulk loads: no periodic
+// refreshes and no replicas, which are rebuilt once the load is done.
+var bulkOptimizedSettings = map[string]interface{}{
+	"index.refresh_interval":   "-1",
+	"index.number_of_replicas": 0,
+}
+
+// restoreSettingsTimeout bounds restoring the index settings after a bulk load, which
+// also happens when the command context has been cancelled.
+const restoreSettingsTimeout = 30 * time.Second
+
+// optimizeIndexForBulk applies bulkOptimizedSettings to the indices matching index and
+// returns a function restoring their original settings and refreshing them. The restore
+// function must be called even if the load fails.
+func optimizeIndexForBulk(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+) (func() error, error) {
+	current, err := helpers.GetIndexSettings(ctx, es, index)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get settings of %s", index)
+	}
+	if len(current) == 0 {
+		return nil, errors.Errorf("index %s not found", index)
+	}
+
+	// settings that were not set explicitly are restored to their default with null
+	original := map[string]map[string]interface{}{}
+	indices := make([]string, 0, len(current))
+	for index_, settings := range current {
+		original[index_] = map[string]interface{}{}
+		for k := range bulkOptimizedSettings {
+			original[index_][k] = settings[k]
+		}
+		indices = append(indices, index_)
+	}
+	sort.Strings(indices)
+
+	restore := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), restoreSettingsTimeout)
+		defer cancel()
+
+		var ret error
+		for _, index_ := range indices {
+			if err := helpers.PutIndexSettings(ctx, es, index_, original[index_]); err != nil {
+				ret = errors.Wrapf(err, "could not restore settings of %s", index_)
+				log.Error().Err(err).Str("index", index_).
+					Interface("settings", original[index_]).
+					Msg("could not restore index settings after bulk load")
+				continue
+			}
+			if err := helpers.RefreshIndex(ctx, es, index_); err != nil {
+				ret = errors.Wrapf(err, "could not refresh %s", index_)
+			}
+		}
+		return ret
+	}
+
+	for _, index_ := range indices {
+		if err := helpers.PutIndexSettings(ctx, es, index_, bulkOptimizedSettings); err != nil {
+			// some indices may already have been modified
+			_ = restore()
+			return nil, errors.Wrapf(err, "could not optimize settings of %s for bulk loading", index_)
+		}
+	}
+
+	log.Info().Strs("indices", indices).Msg("disabled refreshes and replicas for bulk load")
+
+	return restore, nil
+}
@@ -0,0 +1,367 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type BulkUpdateCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &BulkUpdateCommand{}
+
+func NewBulkUpdateCommand() (*BulkUpdateCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &BulkUpdateCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"bulk-update",
+			cmds.WithShort("Updates the documents matching a query, with per-document conflict detection"),
+			cmds.WithLong(`
+The 'bulk-update' command scans the documents matching a query using a point in time,
+and updates each of them with a painless script or a partial document through the bulk API.
+
+Every update is guarded by the if_seq_no and if_primary_term of the scanned document, so
+documents modified concurrently are reported as conflicts instead of being overwritten.
+Unlike _update_by_query, every touched document is output as a row, --max_docs limits the
+number of updated documents, and --dry_run lists the matched documents without updating them.
+
+--routing restricts the scan to the shards of routed documents. A fixed --preference keeps
+scanning the same shard copies, which spreads the load of large scans over fewer nodes and
+keeps the pages stable while shards relocate.
+
+Examples:
+
+   escuse-me documents bulk-update --index tasks \
+      --query '{"term": {"status": "pending"}}' \
+      --script 'ctx._source.status = "cancelled"'
+
+   escuse-me documents bulk-update --index tasks \
+      --query '{"term": {"status": "pending"}}' \
+      --doc '{"status": "cancelled"}' --max_docs 100 --dry_run
+
+With --json_lines_input, the documents to update are read from stdin instead of being
+scanned with --query, as one JSON object per line with an _id and optionally _index,
+_routing, _seq_no and _primary_term. Documents with _seq_no and _primary_term are
+guarded by them, which allows piping search --emit_versioning output:
+
+   escuse-me search --index tasks --query '{"term": {"status": "pending"}}' \
+      --emit_versioning --output json --output-as-objects \
+      | escuse-me documents bulk-update --index tasks --json_lines_input \
+          --doc '{"status": "cancelled"}'
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Indices containing the documents to update"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Query selecting the documents to update (JSON string)"),
+				),
+				parameters.NewParameterDefinition(
+					"json_lines_input",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Read the documents to update from stdin, one JSON object with an _id per line, instead of using --query"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"script",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Painless script applied to each document"),
+				),
+				parameters.NewParameterDefinition(
+					"script_params",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the params passed to the script"),
+				),
+				parameters.NewParameterDefinition(
+					"doc",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Partial document merged into each document (JSON string)"),
+				),
+				parameters.NewParameterDefinition(
+					"max_docs",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Maximum number of documents to update (0 for no limit)"),
+					parameters.WithDefault(0),
+				),
+				parameters.NewParameterDefinition(
+					"page_size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of documents fetched per search request"),
+					parameters.WithDefault(defaultScanPageSize),
+				),
+				parameters.NewParameterDefinition(
+					"chunk_size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of update actions sent per bulk request"),
+					parameters.WithDefault(defaultBulkChunkSize),
+				),
+				parameters.NewParameterDefinition(
+					"keep_alive",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("How long the point in time used for the scan is kept alive between requests"),
+					parameters.WithDefault(defaultScanKeepAlive),
+				),
+				parameters.NewParameterDefinition(
+					"preference",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Shard copies or nodes the documents are scanned on with --query (e.g. _local or a custom string)"),
+				),
+				parameters.NewParameterDefinition(
+					"routing",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Only scan the shards of these routing values with --query"),
+				),
+				parameters.NewParameterDefinition(
+					"refresh",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Control when the changes made by this request are visible to search"),
+					parameters.WithChoices("true", "false", "wait_for"),
+				),
+				parameters.NewParameterDefinition(
+					"dry_run",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only output the documents that would be updated"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"only_failures",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only output conflicts and failed items"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type BulkUpdateSettings struct {
+	Index        []string               `glazed.parameter:"index"`
+	Query        string                 `glazed.parameter:"query"`
+	JSONLines    bool                   `glazed.parameter:"json_lines_input"`
+	Script       string                 `glazed.parameter:"script"`
+	ScriptParams map[string]interface{} `glazed.parameter:"script_params"`
+	Doc          string                 `glazed.parameter:"doc"`
+	MaxDocs      int                    `glazed.parameter:"max_docs"`
+	PageSize     int                    `glazed.parameter:"page_size"`
+	ChunkSize    int                    `glazed.parameter:"chunk_size"`
+	KeepAlive    string                 `glazed.parameter:"keep_alive"`
+	Preference   string                 `glazed.parameter:"preference"`
+	Routing      []string               `glazed.parameter:"routing"`
+	Refresh      *string                `glazed.parameter:"refresh"`
+	DryRun       bool                   `glazed.parameter:"dry_run"`
+	OnlyFailures bool                   `glazed.parameter:"only_failures"`
+}
+
+// buildUpdateSource builds the source line shared by all update actions.
+func buildUpdateSource(s *BulkUpdateSettings) (map[string]interface{}, error) {
+	if (s.Script == "") == (s.Doc == "") {
+		return nil, errors.New("exactly one of --script and --doc is required")
+	}
+
+	if s.Doc != "" {
+		if s.ScriptParams != nil {
+			return nil, errors.New("--script_params requires --script")
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(s.Doc), &doc); err != nil {
+			return nil, errors.Wrap(err, "invalid doc JSON")
+		}
+		return map[string]interface{}{"doc": doc}, nil
+	}
+
+	script := map[string]interface{}{
+		"source": s.Script,
+		"lang":   "painless",
+	}
+	if s.ScriptParams != nil {
+		script["params"] = s.ScriptParams
+	}
+	return map[string]interface{}{"script": script}, nil
+}
+
+func (c *BulkUpdateCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &BulkUpdateSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	source, err := buildUpdateSource(s)
+	if err != nil {
+		return err
+	}
+
+	if (s.Query != "") == s.JSONLines {
+		return errors.New("exactly one of --query and --json_lines_input is required")
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.BulkRequest){}
+	if s.Refresh != nil {
+		options = append(options, es.Bulk.WithRefresh(*s.Refresh))
+	}
+
+	errMaxDocsReached := errors.New("max docs reached")
+	matched := 0
+	stats := casUpdateStats{}
+
+	updateHits := func(hits []ScanHit) error {
+		maxDocsReached := false
+		if s.MaxDocs > 0 && matched+len(hits) >= s.MaxDocs {
+			hits = hits[:s.MaxDocs-matched]
+			maxDocsReached = true
+		}
+		matched += len(hits)
+
+		if s.DryRun {
+			for _, hit := range hits {
+				row := types.NewRow(
+					types.MRP("_index", hit.Index),
+					types.MRP("_id", hit.ID),
+					types.MRP("_seq_no", hit.SeqNo),
+					types.MRP("_primary_term", hit.PrimaryTerm),
+					types.MRP("result", "dry_run"),
+				)
+				if err := gp.AddRow(ctx, row); err != nil {
+					return err
+				}
+			}
+		} else {
+			actions := make([]BulkAction, 0, len(hits))
+			for _, hit := range hits {
+				meta := map[string]interface{}{
+					"_index": hit.Index,
+					"_id":    hit.ID,
+				}
+				// documents read from stdin without versioning have no primary term
+				if hit.PrimaryTerm > 0 {
+					meta["if_seq_no"] = hit.SeqNo
+					meta["if_primary_term"] = hit.PrimaryTerm
+				}
+				if hit.Routing != "" {
+					meta["routing"] = hit.Routing
+				}
+				actions = append(actions, BulkAction{
+					Action: "update",
+					Meta:   meta,
+					Source: source,
+				})
+			}
+
+			results, err := executeChunkedBulk(ctx, es, actions, s.ChunkSize, options...)
+			if err != nil {
+				return err
+			}
+			stats_, err := emitCASUpdateResults(ctx, results, s.OnlyFailures, gp)
+			stats.add(stats_)
+			if err != nil {
+				return err
+			}
+		}
+
+		if maxDocsReached {
+			return errMaxDocsReached
+		}
+		return nil
+	}
+
+	if s.JSONLines {
+		hits, readErr := readJSONLinesHits(s.Index)
+		if readErr != nil {
+			return readErr
+		}
+		pageSize := s.PageSize
+		if pageSize <= 0 {
+			pageSize = defaultScanPageSize
+		}
+		for start := 0; start < len(hits) && err == nil; start += pageSize {
+			end := min(start+pageSize, len(hits))
+			err = updateHits(hits[start:end])
+		}
+	} else {
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(s.Query), &query); err != nil {
+			return errors.Wrap(err, "invalid query JSON")
+		}
+		pitOptions := helpers.PITOptions{
+			Preference: s.Preference,
+			Routing:    s.Routing,
+		}
+		err = scanDocuments(ctx, es, s.Index, query, s.PageSize, s.KeepAlive, pitOptions, updateHits)
+	}
+	if err != nil && err != errMaxDocsReached {
+		return err
+	}
+
+	log.Info().
+		Int("matched", matched).
+		Int("updated", stats.Updated).
+		Int("conflicts", stats.Conflicts).
+		Int("failures", stats.Failures).
+		Bool("dry_run", s.DryRun).
+		Msg("bulk update done")
+
+	return nil
+}
+
+// readJSONLinesHits reads the documents to update from stdin, see DocumentRef.
+func readJSONLinesHits(index []string) ([]ScanHit, error) {
+	defaultIndex := ""
+	if len(index) == 1 {
+		defaultIndex = index[0]
+	}
+	refs, err := readDocumentRefs(os.Stdin, defaultIndex)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read documents from stdin")
+	}
+
+	hits := make([]ScanHit, 0, len(refs))
+	for _, ref := range refs {
+		hit := ScanHit{
+			Index:   ref.Index,
+			ID:      ref.ID,
+			Routing: ref.Routing,
+		}
+		if ref.SeqNo != nil {
+			hit.SeqNo = *ref.SeqNo
+			hit.PrimaryTerm = *ref.PrimaryTerm
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
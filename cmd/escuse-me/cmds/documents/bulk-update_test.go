@@ -0,0 +1,64 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func TestBulkUpdateClosesPITWhenCancelled(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+
+	// the query is validated with a search on the index, the pages use the point in time
+	server.HandleJSON(http.MethodPost, "/tasks/_search", http.StatusOK, estest.SearchResponse())
+	server.HandleJSON(http.MethodPost, "/tasks/_pit", http.StatusOK, map[string]interface{}{"id": "pit-1"})
+	server.HandleJSON(http.MethodDelete, "/_pit", http.StatusOK, map[string]interface{}{
+		"succeeded": true,
+		"num_freed": 1,
+	})
+
+	// the command is cancelled while it fetches the first page
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Handle(http.MethodPost, "/_search", func(w http.ResponseWriter, req *http.Request) {
+		cancel()
+		<-req.Context().Done()
+	})
+
+	cmd, err := NewBulkUpdateCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.RunGlazeCommand(ctx, cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"index": []string{"tasks"},
+			"query": `{"term": {"status": "pending"}}`,
+			"doc":   `{"status": "cancelled"}`,
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the command to be cancelled, got %v", err)
+	}
+
+	closes := server.RequestsTo(http.MethodDelete, "/_pit")
+	if len(closes) != 1 {
+		t.Fatalf("expected 1 close point in time request, got %d", len(closes))
+	}
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := closes[0].DecodeBody(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != "pit-1" {
+		t.Errorf("expected the point in time pit-1 to be closed, got %q", body.ID)
+	}
+	if n := len(server.RequestsTo(http.MethodPost, "/tasks/_bulk")) + len(server.RequestsTo(http.MethodPost, "/_bulk")); n != 0 {
+		t.Errorf("expected no bulk request, got %d", n)
+	}
+}
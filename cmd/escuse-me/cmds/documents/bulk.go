@@ -26,6 +26,7 @@ var _ cmds.GlazeCommand = &BulkCommand{}
 type BulkErrorResponse struct {
 	Errors bool `json:"errors"`
 	Items  []map[string]struct {
+		ID    string `json:"_id"`
 		Error struct {
 			Type   string `json:"type"`
 			Reason string `json:"reason"`
@@ -205,6 +206,11 @@ func (c *BulkCommand) RunIntoGlazeProcessor(
 	if err != nil {
 		return err
 	}
+	bodyReader, compressionOptions, err := compressBulkBody(es, parsedLayers, bodyReader)
+	if err != nil {
+		return err
+	}
+	options = append(options, compressionOptions...)
 
 	bulkIndexResponse, err := es.Bulk(
 		bodyReader,
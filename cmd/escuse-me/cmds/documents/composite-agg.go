@@ -0,0 +1,366 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+const compositeAggregationName = "composite_buckets"
+
+type CompositeAggregationCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &CompositeAggregationCommand{}
+
+func NewCompositeAggregationCommand() (*CompositeAggregationCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &CompositeAggregationCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"composite-agg",
+			cmds.WithShort("Enumerates all buckets of a composite aggregation by following after_key"),
+			cmds.WithLong(`
+The 'composite-agg' command runs a composite aggregation and automatically follows
+the after_key across pages, emitting every bucket as a row. This is the reliable way
+to enumerate all distinct value combinations of high-cardinality fields, which a terms
+aggregation can't do.
+
+Sources are either given as a list of fields (each becoming a terms source named after
+the field), or as a file containing the list of composite sources.
+
+Examples:
+
+   escuse-me documents composite-agg --index logs --field host,status
+
+   escuse-me documents composite-agg --index logs --sources_file sources.yaml \
+      --aggs_file metrics.yaml --size 500 --max_buckets 10000
+
+Metrics are output as one column per metric, multi value metrics such as stats being
+expanded into one column per value (price.min, price.max, ...). Use --pivot long to
+get one row per bucket and metric instead.
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Comma-separated list of data streams, indices, and aliases to aggregate"),
+				),
+				parameters.NewParameterDefinition(
+					"field",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Fields to use as terms sources"),
+				),
+				parameters.NewParameterDefinition(
+					"sources_file",
+					parameters.ParameterTypeObjectListFromFile,
+					parameters.WithHelp("JSON or YAML file containing the list of composite sources"),
+				),
+				parameters.NewParameterDefinition(
+					"aggs_file",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing sub-aggregations computed for each bucket"),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("The query restricting the aggregated documents as a JSON string"),
+				),
+				parameters.NewParameterDefinition(
+					"query_file",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the query restricting the aggregated documents"),
+				),
+				parameters.NewParameterDefinition(
+					"size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of buckets requested per page"),
+					parameters.WithDefault(1000),
+				),
+				parameters.NewParameterDefinition(
+					"max_buckets",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Stop after emitting this many buckets (0 for no limit)"),
+					parameters.WithDefault(0),
+				),
+				parameters.NewParameterDefinition(
+					"pivot",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Output one column per metric (wide) or one row per bucket and metric (long)"),
+					parameters.WithChoices(es_cmds.AggregationPivotWide, es_cmds.AggregationPivotLong),
+					parameters.WithDefault(es_cmds.AggregationPivotWide),
+				),
+				parameters.NewParameterDefinition(
+					"after",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("after_key to start from, as a JSON object"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type CompositeAggregationSettings struct {
+	Index       []string                 `glazed.parameter:"index"`
+	Fields      []string                 `glazed.parameter:"field"`
+	SourcesFile []map[string]interface{} `glazed.parameter:"sources_file"`
+	AggsFile    map[string]interface{}   `glazed.parameter:"aggs_file"`
+	Query       string                   `glazed.parameter:"query"`
+	QueryFile   map[string]interface{}   `glazed.parameter:"query_file"`
+	Size        int                      `glazed.parameter:"size"`
+	MaxBuckets  int                      `glazed.parameter:"max_buckets"`
+	After       string                   `glazed.parameter:"after"`
+	Pivot       string                   `glazed.parameter:"pivot"`
+}
+
+type compositeBucket struct {
+	Key      map[string]interface{}
+	DocCount interface{}
+	SubAggs  map[string]interface{}
+}
+
+type compositeAggregationPage struct {
+	Buckets  []compositeBucket
+	AfterKey map[string]interface{}
+}
+
+func (c *CompositeAggregationCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *CompositeAggregationCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &CompositeAggregationSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	sources := []interface{}{}
+	for _, source := range s.SourcesFile {
+		sources = append(sources, source)
+	}
+	for _, field := range s.Fields {
+		sources = append(sources, map[string]interface{}{
+			field: map[string]interface{}{
+				"terms": map[string]interface{}{"field": field},
+			},
+		})
+	}
+	if len(sources) == 0 {
+		return errors.New("at least one source is required, use --field or --sources_file")
+	}
+
+	query := map[string]interface{}{}
+	if s.QueryFile != nil {
+		query = s.QueryFile
+	}
+	if s.Query != "" {
+		var queryMap map[string]interface{}
+		if err := json.Unmarshal([]byte(s.Query), &queryMap); err != nil {
+			return errors.Wrap(err, "invalid query JSON")
+		}
+		for k, v := range queryMap {
+			query[k] = v
+		}
+	}
+
+	var afterKey map[string]interface{}
+	if s.After != "" {
+		if err := json.Unmarshal([]byte(s.After), &afterKey); err != nil {
+			return errors.Wrap(err, "invalid after JSON")
+		}
+	}
+
+	gp.(*middlewares.TableProcessor).AddRowMiddlewareInFront(
+		es_cmds.NewAggregationPivotMiddleware(s.Pivot, sourceNames(sources)),
+	)
+
+	emitted := 0
+	for {
+		page, err := fetchCompositeAggregationPage(ctx, es, s, sources, query, afterKey)
+		if err != nil {
+			return err
+		}
+
+		for _, bucket := range page.Buckets {
+			if s.MaxBuckets > 0 && emitted >= s.MaxBuckets {
+				return nil
+			}
+
+			row := types.NewRow()
+			for _, k := range sourceNames(sources) {
+				row.Set(k, bucket.Key[k])
+			}
+			row.Set("doc_count", bucket.DocCount)
+
+			for _, row_ := range es_cmds.FlattenBucketAggregations(bucket.SubAggs, row, formatCompositeKey(bucket.Key)) {
+				if err := gp.AddRow(ctx, row_); err != nil {
+					return err
+				}
+			}
+			emitted++
+		}
+
+		if len(page.AfterKey) == 0 || len(page.Buckets) == 0 {
+			return nil
+		}
+		afterKey = page.AfterKey
+	}
+}
+
+func fetchCompositeAggregationPage(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	s *CompositeAggregationSettings,
+	sources []interface{},
+	query map[string]interface{},
+	afterKey map[string]interface{},
+) (*compositeAggregationPage, error) {
+	composite := map[string]interface{}{
+		"size":    s.Size,
+		"sources": sources,
+	}
+	if afterKey != nil {
+		composite["after"] = afterKey
+	}
+	aggregation := map[string]interface{}{
+		"composite": composite,
+	}
+	if len(s.AggsFile) > 0 {
+		aggregation["aggs"] = s.AggsFile
+	}
+
+	body := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			compositeAggregationName: aggregation,
+		},
+	}
+	if len(query) > 0 {
+		body["query"] = query
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(s.Index...),
+		es.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(responseBody); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Aggregations map[string]struct {
+			AfterKey map[string]interface{}   `json:"after_key"`
+			Buckets  []map[string]interface{} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal search response")
+	}
+
+	aggregationResult, ok := response.Aggregations[compositeAggregationName]
+	if !ok {
+		return nil, errors.New("could not find composite aggregation in response")
+	}
+
+	ret := &compositeAggregationPage{
+		AfterKey: aggregationResult.AfterKey,
+	}
+	for _, bucket := range aggregationResult.Buckets {
+		b := compositeBucket{
+			SubAggs: map[string]interface{}{},
+		}
+		for k, v := range bucket {
+			switch k {
+			case "key":
+				b.Key, _ = v.(map[string]interface{})
+			case "doc_count":
+				b.DocCount = v
+			default:
+				b.SubAggs[k] = v
+			}
+		}
+		ret.Buckets = append(ret.Buckets, b)
+	}
+
+	return ret, nil
+}
+
+// sourceNames returns the names of the composite sources, in order.
+func sourceNames(sources []interface{}) []string {
+	ret := []string{}
+	for _, source := range sources {
+		if m, ok := source.(map[string]interface{}); ok {
+			for k := range m {
+				ret = append(ret, k)
+			}
+		}
+	}
+	return ret
+}
+
+// formatCompositeKey formats a composite bucket key as the comma-separated list of
+// its values, sorted by source name.
+func formatCompositeKey(key map[string]interface{}) string {
+	names := make([]string, 0, len(key))
+	for k := range key {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	values := make([]string, 0, len(names))
+	for _, k := range names {
+		values = append(values, fmt.Sprint(key[k]))
+	}
+	return strings.Join(values, ",")
+}
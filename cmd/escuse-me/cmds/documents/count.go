@@ -0,0 +1,218 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type CountDocumentCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &CountDocumentCommand{}
+
+func NewCountDocumentCommand() (*CountDocumentCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &CountDocumentCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"count",
+			cmds.WithShort("Counts the documents matching a query"),
+			cmds.WithLong(`
+The 'count' command counts the documents matching a query with the _count API, without
+fetching any hit. With --per_index, each given index is counted separately and one row
+is output per index (use 'indices count' to count each index of a pattern).
+
+Examples:
+
+   escuse-me documents count --index products --query '{"term": {"status": "active"}}'
+
+   escuse-me documents count --index products,orders --per_index
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Comma-separated list of data streams, indices, and aliases to count in"),
+				),
+				parameters.NewParameterDefinition(
+					"query_file",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the query restricting the counted documents"),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("The query restricting the counted documents as a JSON string"),
+				),
+				parameters.NewParameterDefinition(
+					"routing",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Specific routing values"),
+				),
+				parameters.NewParameterDefinition(
+					"preference",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Specify the node or shard the operation should be performed on"),
+				),
+				parameters.NewParameterDefinition(
+					"terminate_after",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("The maximum count for each shard, upon reaching which the query execution will terminate early"),
+				),
+				parameters.NewParameterDefinition(
+					"min_score",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Only count documents with a score of at least this value"),
+				),
+				parameters.NewParameterDefinition(
+					"per_index",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Count each given index separately"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type CountDocumentSettings struct {
+	Index          []string               `glazed.parameter:"index"`
+	QueryFile      map[string]interface{} `glazed.parameter:"query_file"`
+	Query          string                 `glazed.parameter:"query"`
+	Routing        []string               `glazed.parameter:"routing"`
+	Preference     string                 `glazed.parameter:"preference"`
+	TerminateAfter *int                   `glazed.parameter:"terminate_after"`
+	MinScore       *int                   `glazed.parameter:"min_score"`
+	PerIndex       bool                   `glazed.parameter:"per_index"`
+}
+
+func (c *CountDocumentCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *CountDocumentCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &CountDocumentSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	query := map[string]interface{}{}
+	for k, v := range s.QueryFile {
+		query[k] = v
+	}
+	if s.Query != "" {
+		// merge query with query file
+		var queryMap map[string]interface{}
+		if err := json.Unmarshal([]byte(s.Query), &queryMap); err != nil {
+			return errors.Wrap(err, "invalid query JSON")
+		}
+		for k, v := range queryMap {
+			query[k] = v
+		}
+	}
+
+	if !s.PerIndex || len(s.Index) <= 1 {
+		return countDocuments(ctx, es, s, s.Index, query, gp)
+	}
+	for _, index := range s.Index {
+		if err := countDocuments(ctx, es, s, []string{index}, query, gp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func countDocuments(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	s *CountDocumentSettings,
+	index []string,
+	query map[string]interface{},
+	gp middlewares.Processor,
+) error {
+	options := []func(*esapi.CountRequest){
+		es.Count.WithContext(ctx),
+		es.Count.WithIndex(index...),
+		es.Count.WithRouting(s.Routing...),
+		es.Count.WithPreference(s.Preference),
+	}
+	if s.TerminateAfter != nil {
+		options = append(options, es.Count.WithTerminateAfter(*s.TerminateAfter))
+	}
+	if s.MinScore != nil {
+		options = append(options, es.Count.WithMinScore(*s.MinScore))
+	}
+	if len(query) > 0 {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"query": query}); err != nil {
+			return err
+		}
+		options = append(options, es.Count.WithBody(&buf))
+	}
+
+	res, err := es.Count(options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	var response struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+
+	row := types.NewRow()
+	if s.PerIndex {
+		row.Set("index", index[0])
+	}
+	row.Set("count", response.Count)
+	return gp.AddRow(ctx, row)
+}
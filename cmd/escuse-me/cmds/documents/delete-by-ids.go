@@ -0,0 +1,235 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type DeleteByIDsCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &DeleteByIDsCommand{}
+
+func NewDeleteByIDsCommand() (*DeleteByIDsCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &DeleteByIDsCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"delete-by-ids",
+			cmds.WithShort("Deletes a list of documents by ID"),
+			cmds.WithLong(`
+The 'delete-by-ids' command deletes the documents with the given ids from --index using
+bulk delete actions, and outputs the number of deleted, not found and failed documents.
+
+Ids are given with --ids, or read from --ids_file (- for stdin), one per line. Lines of
+the file can also be JSON objects, such as the output of search --output_hit_id, in
+which case the id is read from the _id field, and the routing from --routing_field for
+indices with custom routing.
+
+Examples:
+
+   escuse-me documents delete-by-ids --index products --ids 1,2,3
+
+   escuse-me documents delete-by-ids --index products --ids_file stale-ids.txt
+
+   escuse-me search --index orders --query '{"term": {"status": "test"}}' \
+      --output_hit_id --output json --output-as-objects \
+      | escuse-me documents delete-by-ids --index orders --ids_file - --routing_field customer_id
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index containing the documents to delete"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"ids",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Ids of the documents to delete"),
+				),
+				parameters.NewParameterDefinition(
+					"ids_file",
+					parameters.ParameterTypeStringListFromFile,
+					parameters.WithHelp("File containing the ids of the documents to delete, one id or JSON object per line (- for stdin)"),
+				),
+				parameters.NewParameterDefinition(
+					"routing_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Field of the JSON objects of --ids_file containing the routing value of each document"),
+				),
+				parameters.NewParameterDefinition(
+					"routing",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Routing value of all the documents"),
+				),
+				parameters.NewParameterDefinition(
+					"chunk_size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of delete actions sent per bulk request"),
+					parameters.WithDefault(defaultBulkChunkSize),
+				),
+				parameters.NewParameterDefinition(
+					"refresh",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Control when the changes made by this request are visible to search"),
+					parameters.WithChoices("true", "false", "wait_for"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type DeleteByIDsSettings struct {
+	Index        string   `glazed.parameter:"index"`
+	IDs          []string `glazed.parameter:"ids"`
+	IDsFile      []string `glazed.parameter:"ids_file"`
+	RoutingField string   `glazed.parameter:"routing_field"`
+	Routing      string   `glazed.parameter:"routing"`
+	ChunkSize    int      `glazed.parameter:"chunk_size"`
+	Refresh      *string  `glazed.parameter:"refresh"`
+}
+
+// buildDeleteActions builds the bulk delete actions for the ids of the settings.
+func buildDeleteActions(s *DeleteByIDsSettings) ([]BulkAction, error) {
+	if s.RoutingField != "" && s.Routing != "" {
+		return nil, errors.New("--routing and --routing_field are mutually exclusive")
+	}
+
+	actions := []BulkAction{}
+	addAction := func(id string, routing string) {
+		meta := map[string]interface{}{
+			"_index": s.Index,
+			"_id":    id,
+		}
+		if routing == "" {
+			routing = s.Routing
+		}
+		if routing != "" {
+			meta["routing"] = routing
+		}
+		actions = append(actions, BulkAction{Action: "delete", Meta: meta})
+	}
+
+	for _, id := range s.IDs {
+		addAction(id, "")
+	}
+
+	for i, line := range s.IDsFile {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "{") {
+			if s.RoutingField != "" {
+				return nil, errors.Errorf("line %d of --ids_file is not a JSON object, required by --routing_field", i+1)
+			}
+			addAction(line, "")
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return nil, errors.Wrapf(err, "could not parse line %d of --ids_file", i+1)
+		}
+		id, ok := object["_id"]
+		if !ok || id == nil {
+			return nil, errors.Errorf("line %d of --ids_file has no _id", i+1)
+		}
+		routing := ""
+		if s.RoutingField != "" {
+			routing_, ok := object[s.RoutingField]
+			if !ok || routing_ == nil {
+				return nil, errors.Errorf("line %d of --ids_file has no field %s", i+1, s.RoutingField)
+			}
+			routing = bulkKeyValue(routing_)
+		}
+		addAction(fmt.Sprint(id), routing)
+	}
+
+	return actions, nil
+}
+
+func (c *DeleteByIDsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &DeleteByIDsSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	actions, err := buildDeleteActions(s)
+	if err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		return errors.New("no ids to delete, use --ids or --ids_file")
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.BulkRequest){}
+	if s.Refresh != nil {
+		options = append(options, es.Bulk.WithRefresh(*s.Refresh))
+	}
+
+	results, err := executeChunkedBulk(ctx, es, actions, s.ChunkSize, options...)
+
+	deleted, notFound, failed := 0, 0, 0
+	for _, result := range results {
+		switch {
+		case result.Result == "deleted":
+			deleted++
+		case result.Result == "not_found":
+			notFound++
+		default:
+			failed++
+			log.Warn().
+				Str("id", result.ID).
+				Int("status", result.Status).
+				Str("type", result.ErrorType).
+				Str("reason", result.ErrorReason).
+				Msg("could not delete document")
+		}
+	}
+
+	row := types.NewRow(
+		types.MRP("index", s.Index),
+		types.MRP("requested", len(actions)),
+		types.MRP("deleted", deleted),
+		types.MRP("not_found", notFound),
+		types.MRP("failed", failed),
+	)
+	if addErr := gp.AddRow(ctx, row); addErr != nil {
+		return addErr
+	}
+
+	return err
+}
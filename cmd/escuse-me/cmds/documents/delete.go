@@ -3,6 +3,7 @@ package documents
 import (
 	"context"
 	"encoding/json"
+	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
@@ -14,6 +15,7 @@ import (
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
 	"io"
+	"os"
 	"strconv"
 )
 
@@ -37,6 +39,17 @@ func NewDeleteDocumentCommand() (*DeleteDocumentCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"delete",
 			cmds.WithShort("Deletes a document"),
+			cmds.WithLong(`
+Deletes the document --id from --index.
+
+With --json_lines_input, the documents to delete are read from stdin instead and deleted
+with bulk requests, as one JSON object per line with an _id and optionally _index,
+_routing, _seq_no and _primary_term (the delete is then guarded by the latter two):
+
+   escuse-me search --index logs --query '{"term": {"level": "debug"}}' \
+      --output_hit_id --output json --output-as-objects \
+      | escuse-me documents delete --index logs --json_lines_input
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"index",
@@ -48,7 +61,12 @@ func NewDeleteDocumentCommand() (*DeleteDocumentCommand, error) {
 					"id",
 					parameters.ParameterTypeString,
 					parameters.WithHelp("Document ID"),
-					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"json_lines_input",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Read the documents to delete from stdin, one JSON object with an _id per line, instead of using --id"),
+					parameters.WithDefault(false),
 				),
 				parameters.NewParameterDefinition(
 					"routing",
@@ -71,6 +89,17 @@ func NewDeleteDocumentCommand() (*DeleteDocumentCommand, error) {
 					parameters.ParameterTypeInteger,
 					parameters.WithHelp("only perform the delete operation if the last operation that has changed the document has the specified primary term"),
 				),
+				parameters.NewParameterDefinition(
+					"version",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Explicit version number for concurrency control"),
+				),
+				parameters.NewParameterDefinition(
+					"version_type",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Specific version type"),
+					parameters.WithChoices("internal", "external", "external_gte", "force"),
+				),
 				parameters.NewParameterDefinition(
 					"wait_for_active_shards",
 					parameters.ParameterTypeString,
@@ -85,10 +114,13 @@ func NewDeleteDocumentCommand() (*DeleteDocumentCommand, error) {
 type DeleteDocumentSettings struct {
 	Index               string `glazed.parameter:"index"`
 	DocumentID          string `glazed.parameter:"id"`
+	JSONLinesInput      bool   `glazed.parameter:"json_lines_input"`
 	Routing             string `glazed.parameter:"routing"`
 	Refresh             string `glazed.parameter:"refresh"`
 	IfSeqNo             *int   `glazed.parameter:"if_seq_no"`
 	IfPrimaryTerm       *int   `glazed.parameter:"if_primary_term"`
+	Version             *int   `glazed.parameter:"version"`
+	VersionType         string `glazed.parameter:"version_type"`
 	WaitForActiveShards string `glazed.parameter:"wait_for_active_shards"`
 }
 
@@ -107,6 +139,16 @@ func (c *DeleteDocumentCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	if s.JSONLinesInput {
+		if s.DocumentID != "" || s.IfSeqNo != nil || s.IfPrimaryTerm != nil || s.Version != nil {
+			return errors.New("--json_lines_input can't be combined with --id, --if_seq_no, --if_primary_term or --version")
+		}
+		return c.deleteJSONLines(ctx, es, s, gp)
+	}
+	if s.DocumentID == "" {
+		return errors.New("one of --id and --json_lines_input is required")
+	}
+
 	options := []func(request *esapi.DeleteRequest){
 		es.Delete.WithContext(ctx),
 		es.Delete.WithRouting(s.Routing),
@@ -119,6 +161,12 @@ func (c *DeleteDocumentCommand) RunIntoGlazeProcessor(
 	if s.IfPrimaryTerm != nil {
 		options = append(options, es.Delete.WithIfPrimaryTerm(*s.IfPrimaryTerm))
 	}
+	if s.Version != nil {
+		options = append(options, es.Delete.WithVersion(*s.Version))
+	}
+	if s.VersionType != "" {
+		options = append(options, es.Delete.WithVersionType(s.VersionType))
+	}
 
 	deleteDocResponse, err := es.Delete(
 		s.Index,
@@ -144,10 +192,83 @@ func (c *DeleteDocumentCommand) RunIntoGlazeProcessor(
 		return gp.AddRow(ctx, row)
 	}
 
-	responseRow := types.NewRow()
-	if err := json.Unmarshal(body, &responseRow); err != nil {
+	// deleting a missing document is not an error, its result is not_found
+	var response struct {
+		Index   string `json:"_index"`
+		ID      string `json:"_id"`
+		Version int64  `json:"_version"`
+		Result  string `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
 		return err
 	}
 
-	return gp.AddRow(ctx, responseRow)
+	return gp.AddRow(ctx, types.NewRow(
+		types.MRP("_id", response.ID),
+		types.MRP("_index", response.Index),
+		types.MRP("result", response.Result),
+		types.MRP("_version", response.Version),
+	))
+}
+
+// deleteJSONLines deletes the documents read from stdin with bulk requests, see
+// DocumentRef.
+func (c *DeleteDocumentCommand) deleteJSONLines(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	s *DeleteDocumentSettings,
+	gp middlewares.Processor,
+) error {
+	refs, err := readDocumentRefs(os.Stdin, s.Index)
+	if err != nil {
+		return errors.Wrap(err, "could not read documents from stdin")
+	}
+
+	actions := make([]BulkAction, 0, len(refs))
+	for _, ref := range refs {
+		meta := map[string]interface{}{
+			"_index": ref.Index,
+			"_id":    ref.ID,
+		}
+		if ref.Routing != "" {
+			meta["routing"] = ref.Routing
+		} else if s.Routing != "" {
+			meta["routing"] = s.Routing
+		}
+		if ref.SeqNo != nil {
+			meta["if_seq_no"] = *ref.SeqNo
+			meta["if_primary_term"] = *ref.PrimaryTerm
+		}
+		if s.VersionType != "" {
+			meta["version_type"] = s.VersionType
+		}
+		actions = append(actions, BulkAction{Action: "delete", Meta: meta})
+	}
+
+	options := []func(*esapi.BulkRequest){}
+	if s.Refresh != "" {
+		options = append(options, es.Bulk.WithRefresh(s.Refresh))
+	}
+	if s.WaitForActiveShards != "" {
+		options = append(options, es.Bulk.WithWaitForActiveShards(s.WaitForActiveShards))
+	}
+
+	results, err := executeChunkedBulk(ctx, es, actions, defaultBulkChunkSize, options...)
+	for _, result := range results {
+		row := types.NewRow(
+			types.MRP("_id", result.ID),
+			types.MRP("_index", result.Index),
+			types.MRP("result", result.Result),
+		)
+		// deleting a missing document is not an error, its result is not_found
+		if result.ErrorType != "" {
+			row.Set("status", strconv.Itoa(result.Status))
+			row.Set("error_type", result.ErrorType)
+			row.Set("error_reason", result.ErrorReason)
+		}
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return err
 }
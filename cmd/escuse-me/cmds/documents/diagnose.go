@@ -0,0 +1,328 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	DiagnosticOK      = "ok"
+	DiagnosticWarning = "warning"
+	DiagnosticFailed  = "failed"
+)
+
+// Diagnostic is the outcome of one of the checks run when a search returns no hits.
+type Diagnostic struct {
+	Check   string
+	Status  string
+	Field   string
+	Message string
+}
+
+// queryClause is a leaf clause of a query targeting a single field.
+type queryClause struct {
+	Type  string
+	Field string
+	Text  string
+}
+
+// fieldQueryClauses are the leaf queries whose body is keyed by the field name.
+var fieldQueryClauses = map[string]bool{
+	"match":               true,
+	"match_phrase":        true,
+	"match_phrase_prefix": true,
+	"match_bool_prefix":   true,
+	"term":                true,
+	"terms":               true,
+	"range":               true,
+	"prefix":              true,
+	"wildcard":            true,
+	"regexp":              true,
+	"fuzzy":               true,
+}
+
+// analyzedQueryClauses are the queries whose text is analyzed with the analyzer of the field.
+var analyzedQueryClauses = map[string]bool{
+	"match":               true,
+	"match_phrase":        true,
+	"match_phrase_prefix": true,
+	"match_bool_prefix":   true,
+	"multi_match":         true,
+}
+
+// termLevelQueryClauses are the queries whose value is compared to the indexed terms as is.
+var termLevelQueryClauses = map[string]bool{
+	"term":     true,
+	"terms":    true,
+	"prefix":   true,
+	"wildcard": true,
+	"fuzzy":    true,
+}
+
+// extractQueryClauses walks query and returns its leaf clauses targeting fields.
+func extractQueryClauses(query interface{}) []queryClause {
+	ret := []queryClause{}
+	switch v := query.(type) {
+	case []interface{}:
+		for _, q := range v {
+			ret = append(ret, extractQueryClauses(q)...)
+		}
+	case map[string]interface{}:
+		for clauseType, clause := range v {
+			clause_, ok := clause.(map[string]interface{})
+			switch {
+			case fieldQueryClauses[clauseType] && ok:
+				for field, spec := range clause_ {
+					if field == "boost" || field == "_name" {
+						continue
+					}
+					ret = append(ret, queryClause{Type: clauseType, Field: field, Text: clauseText(spec)})
+				}
+			case clauseType == "exists" && ok:
+				if field, ok := clause_["field"].(string); ok {
+					ret = append(ret, queryClause{Type: clauseType, Field: field})
+				}
+			case clauseType == "multi_match" && ok:
+				fields, _ := clause_["fields"].([]interface{})
+				for _, field := range fields {
+					if field_, ok := field.(string); ok {
+						// strip the boost, title^2
+						field_ = strings.SplitN(field_, "^", 2)[0]
+						ret = append(ret, queryClause{Type: clauseType, Field: field_, Text: clauseText(clause_)})
+					}
+				}
+			default:
+				ret = append(ret, extractQueryClauses(clause)...)
+			}
+		}
+	}
+	return ret
+}
+
+// clauseText returns the text of a leaf clause, which is either given directly or in
+// the query or value option.
+func clauseText(spec interface{}) string {
+	switch v := spec.(type) {
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	case map[string]interface{}:
+		for _, k := range []string{"query", "value"} {
+			if text, ok := v[k]; ok {
+				return clauseText(text)
+			}
+		}
+	}
+	return ""
+}
+
+// analyzeText returns the tokens text is analyzed into by the analyzer of field in index.
+func analyzeText(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+	field string,
+	text string,
+) ([]string, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"field": field,
+		"text":  text,
+	}); err != nil {
+		return nil, err
+	}
+
+	res, err := es.Indices.Analyze(
+		es.Indices.Analyze.WithContext(ctx),
+		es.Indices.Analyze.WithIndex(index),
+		es.Indices.Analyze.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Tokens []struct {
+			Token string `json:"token"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(response.Tokens))
+	for _, t := range response.Tokens {
+		ret = append(ret, t.Token)
+	}
+	return ret, nil
+}
+
+// diagnoseNoResults runs the checks explaining the most common causes of a search
+// returning no hits: an empty index, fields missing from the mapping, and query text
+// not matching the analyzed terms.
+func diagnoseNoResults(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index []string,
+	query interface{},
+) ([]Diagnostic, error) {
+	ret := []Diagnostic{}
+
+	index_ := strings.Join(index, ",")
+	if index_ == "" {
+		index_ = "_all"
+	}
+	count, err := helpers.CountDocuments(ctx, es, index_, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not count documents")
+	}
+	if count == 0 {
+		return append(ret, Diagnostic{
+			Check:   "match_all",
+			Status:  DiagnosticFailed,
+			Message: fmt.Sprintf("%s contains no documents, check the index name and that the data was indexed (and refreshed)", index_),
+		}), nil
+	}
+	ret = append(ret, Diagnostic{
+		Check:   "match_all",
+		Status:  DiagnosticOK,
+		Message: fmt.Sprintf("%s contains %d documents, the query filters all of them out", index_, count),
+	})
+
+	clauses := extractQueryClauses(query)
+	if len(clauses) == 0 {
+		return ret, nil
+	}
+
+	fields := []string{}
+	seen := map[string]bool{}
+	for _, c := range clauses {
+		if !seen[c.Field] {
+			fields = append(fields, c.Field)
+			seen[c.Field] = true
+		}
+	}
+	sort.Strings(fields)
+
+	fieldCaps, err := helpers.GetFieldCaps(ctx, es, index, fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get field capabilities")
+	}
+
+	fieldTypes := map[string][]string{}
+	for _, field := range fields {
+		caps, ok := fieldCaps.Fields[field]
+		if !ok {
+			ret = append(ret, Diagnostic{
+				Check:   "mapping",
+				Status:  DiagnosticFailed,
+				Field:   field,
+				Message: "field is not in the mapping of any searched index, check its name (including the .keyword suffix)",
+			})
+			continue
+		}
+		for type_, c := range caps {
+			fieldTypes[field] = append(fieldTypes[field], type_)
+			if !c.Searchable {
+				ret = append(ret, Diagnostic{
+					Check:   "mapping",
+					Status:  DiagnosticFailed,
+					Field:   field,
+					Message: fmt.Sprintf("field of type %s is not searchable (index: false)", type_),
+				})
+			}
+		}
+		sort.Strings(fieldTypes[field])
+		status, message := DiagnosticOK, "field is mapped as "+strings.Join(fieldTypes[field], ", ")
+		if len(caps) > 1 {
+			status = DiagnosticWarning
+			message = "field has conflicting types across the searched indices: " + strings.Join(fieldTypes[field], ", ")
+		}
+		ret = append(ret, Diagnostic{Check: "mapping", Status: status, Field: field, Message: message})
+	}
+
+	// analyzing requires a concrete index
+	if len(fieldCaps.Indices) == 0 {
+		return ret, nil
+	}
+	analyzeIndex := fieldCaps.Indices[0]
+
+	for _, c := range clauses {
+		if c.Text == "" || !containsString(fieldTypes[c.Field], "text") {
+			continue
+		}
+		tokens, err := analyzeText(ctx, es, analyzeIndex, c.Field, c.Text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not analyze text for %s", c.Field)
+		}
+
+		switch {
+		case termLevelQueryClauses[c.Type]:
+			if len(tokens) != 1 || tokens[0] != c.Text {
+				ret = append(ret, Diagnostic{
+					Check:  "analysis",
+					Status: DiagnosticWarning,
+					Field:  c.Field,
+					Message: fmt.Sprintf(
+						"%s query on a text field: %q is not analyzed, but was indexed as %s. Use a match query or the keyword sub-field",
+						c.Type, c.Text, formatTokens(tokens)),
+				})
+			}
+		case analyzedQueryClauses[c.Type]:
+			status, message := DiagnosticOK, fmt.Sprintf("%q is analyzed into %s", c.Text, formatTokens(tokens))
+			if len(tokens) == 0 {
+				status = DiagnosticWarning
+				message = fmt.Sprintf("%q is analyzed into no tokens (only stop words?), the %s query can't match", c.Text, c.Type)
+			}
+			ret = append(ret, Diagnostic{Check: "analysis", Status: status, Field: c.Field, Message: message})
+		}
+	}
+
+	return ret, nil
+}
+
+func formatTokens(tokens []string) string {
+	quoted := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		quoted = append(quoted, fmt.Sprintf("%q", t))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func emitDiagnostics(ctx context.Context, diagnostics []Diagnostic, gp middlewares.Processor) error {
+	for _, d := range diagnostics {
+		row := types.NewRow(
+			types.MRP("check", d.Check),
+			types.MRP("status", d.Status),
+			types.MRP("field", d.Field),
+			types.MRP("message", d.Message),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -32,6 +32,16 @@ func AddToRootCommand(rootCmd *cobra.Command) error {
 	}
 	documentsCommand.AddCommand(deleteDocumentCmd)
 
+	deleteByIDsCommand, err := NewDeleteByIDsCommand()
+	if err != nil {
+		return err
+	}
+	deleteByIDsCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(deleteByIDsCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(deleteByIDsCmd)
+
 	deleteByQueryCommand, err := NewDeleteByQueryCommand()
 	if err != nil {
 		return err
@@ -72,6 +82,26 @@ func AddToRootCommand(rootCmd *cobra.Command) error {
 	}
 	documentsCommand.AddCommand(bulkIndexCmd)
 
+	bulkCASUpdateCommand, err := NewBulkCASUpdateCommand()
+	if err != nil {
+		return err
+	}
+	bulkCASUpdateCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(bulkCASUpdateCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(bulkCASUpdateCmd)
+
+	bulkUpdateCommand, err := NewBulkUpdateCommand()
+	if err != nil {
+		return err
+	}
+	bulkUpdateCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(bulkUpdateCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(bulkUpdateCmd)
+
 	multiGetDocumentCommand, err := NewMultiGetDocumentCommand()
 	if err != nil {
 		return err
@@ -92,6 +122,26 @@ func AddToRootCommand(rootCmd *cobra.Command) error {
 	}
 	documentsCommand.AddCommand(searchCmd)
 
+	explainScoreCommand, err := NewExplainScoreCommand()
+	if err != nil {
+		return err
+	}
+	explainScoreCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(explainScoreCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(explainScoreCmd)
+
+	compositeAggregationCommand, err := NewCompositeAggregationCommand()
+	if err != nil {
+		return err
+	}
+	compositeAggregationCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(compositeAggregationCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(compositeAggregationCmd)
+
 	updateCommand, err := NewUpdateDocumentCommand()
 	if err != nil {
 		return err
@@ -102,5 +152,61 @@ func AddToRootCommand(rootCmd *cobra.Command) error {
 	}
 	documentsCommand.AddCommand(updateCmd)
 
+	countCommand, err := NewCountDocumentCommand()
+	if err != nil {
+		return err
+	}
+	countCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(countCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(countCmd)
+
+	fieldStatsCommand, err := NewFieldStatsCommand()
+	if err != nil {
+		return err
+	}
+	fieldStatsCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(fieldStatsCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(fieldStatsCmd)
+
+	tailCommand, err := NewTailCommand()
+	if err != nil {
+		return err
+	}
+	tailCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(tailCommand)
+	if err != nil {
+		return err
+	}
+	documentsCommand.AddCommand(tailCmd)
+
+	historyCommand := &cobra.Command{
+		Use:   "history",
+		Short: "Query history related commands",
+	}
+	documentsCommand.AddCommand(historyCommand)
+
+	historyListCommand, err := NewHistoryListCommand()
+	if err != nil {
+		return err
+	}
+	historyListCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(historyListCommand)
+	if err != nil {
+		return err
+	}
+	historyCommand.AddCommand(historyListCmd)
+
+	historyReplayCommand, err := NewHistoryReplayCommand()
+	if err != nil {
+		return err
+	}
+	historyReplayCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(historyReplayCommand)
+	if err != nil {
+		return err
+	}
+	historyCommand.AddCommand(historyReplayCmd)
+
 	return nil
 }
@@ -0,0 +1,304 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type ExplainScoreCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ExplainScoreCommand{}
+
+func NewExplainScoreCommand() (*ExplainScoreCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ExplainScoreCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"explain-score",
+			cmds.WithShort("Attributes the score of a document to the fields and clauses of a query"),
+			cmds.WithLong(`
+The 'explain-score' command runs the Explain API for a single document and query,
+and walks the resulting explanation tree to attribute the final score to the individual
+query clauses and fields that contributed to it.
+
+Each row contains the clause, the field it targets, its contribution to the final score
+and the percentage of the final score it represents.
+
+Sums are attributed to each of their children, "max of" nodes (dis_max) only to their
+best scoring child, and products (boosts, function scores) are scaled down onto their
+scoring child. The attribution is thus an approximation, but it adds up to the final score
+for the common bool / match / multi_match queries.
+
+Examples:
+
+   escuse-me documents explain-score --index products --id 1 \
+      --query '{"multi_match": {"query": "coffee", "fields": ["name^2", "description"]}}'
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Name of the index"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"id",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Document ID"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("The query to explain as a JSON string"),
+				),
+				parameters.NewParameterDefinition(
+					"query_file",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the query to explain"),
+				),
+				parameters.NewParameterDefinition(
+					"routing",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Specific routing value"),
+				),
+				parameters.NewParameterDefinition(
+					"preference",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Specify the node or shard the operation should be performed on"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ExplainScoreSettings struct {
+	Index      string                 `glazed.parameter:"index"`
+	ID         string                 `glazed.parameter:"id"`
+	Query      string                 `glazed.parameter:"query"`
+	QueryFile  map[string]interface{} `glazed.parameter:"query_file"`
+	Routing    string                 `glazed.parameter:"routing"`
+	Preference string                 `glazed.parameter:"preference"`
+}
+
+type Explanation struct {
+	Value       float64       `json:"value"`
+	Description string        `json:"description"`
+	Details     []Explanation `json:"details"`
+}
+
+type ExplainResponse struct {
+	Index       string      `json:"_index"`
+	ID          string      `json:"_id"`
+	Matched     bool        `json:"matched"`
+	Explanation Explanation `json:"explanation"`
+}
+
+// ScoreContribution is the share of the final score attributed to a single leaf clause
+// of an explanation tree.
+type ScoreContribution struct {
+	Clause       string
+	Field        string
+	Contribution float64
+}
+
+func (c *ExplainScoreCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *ExplainScoreCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ExplainScoreSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	query := map[string]interface{}{}
+	if s.QueryFile != nil {
+		query = s.QueryFile
+	}
+	if s.Query != "" {
+		var queryMap map[string]interface{}
+		if err := json.Unmarshal([]byte(s.Query), &queryMap); err != nil {
+			return errors.Wrap(err, "invalid query JSON")
+		}
+		for k, v := range queryMap {
+			query[k] = v
+		}
+	}
+	if len(query) == 0 {
+		return errors.New("a query is required, use --query or --query_file")
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"query": query}); err != nil {
+		return err
+	}
+
+	options := []func(*esapi.ExplainRequest){
+		es.Explain.WithContext(ctx),
+		es.Explain.WithBody(&buf),
+	}
+	if s.Routing != "" {
+		options = append(options, es.Explain.WithRouting(s.Routing))
+	}
+	if s.Preference != "" {
+		options = append(options, es.Explain.WithPreference(s.Preference))
+	}
+
+	res, err := es.Explain(s.Index, s.ID, options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	var explainResponse ExplainResponse
+	if err := json.Unmarshal(body, &explainResponse); err != nil {
+		return errors.Wrap(err, "could not unmarshal explain response")
+	}
+
+	if !explainResponse.Matched {
+		return errors.Errorf("document %s in index %s does not match the query", s.ID, s.Index)
+	}
+
+	total := explainResponse.Explanation.Value
+	for _, contribution := range AttributeScore(explainResponse.Explanation) {
+		percent := 0.0
+		if total != 0 {
+			percent = contribution.Contribution / total * 100
+		}
+		row := types.NewRow(
+			types.MRP("clause", contribution.Clause),
+			types.MRP("field", contribution.Field),
+			types.MRP("contribution", contribution.Contribution),
+			types.MRP("percent", percent),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AttributeScore walks an explanation tree and returns the contribution of each leaf clause
+// to the root score.
+func AttributeScore(explanation Explanation) []ScoreContribution {
+	return attributeScore(explanation, 1.0)
+}
+
+func attributeScore(e Explanation, scale float64) []ScoreContribution {
+	description := strings.TrimSpace(e.Description)
+
+	if strings.HasPrefix(description, "weight(") || len(e.Details) == 0 {
+		clause, field := parseExplanationClause(description)
+		return []ScoreContribution{{
+			Clause:       clause,
+			Field:        field,
+			Contribution: e.Value * scale,
+		}}
+	}
+
+	switch {
+	case strings.HasPrefix(description, "sum of"):
+		ret := []ScoreContribution{}
+		for _, d := range e.Details {
+			ret = append(ret, attributeScore(d, scale)...)
+		}
+		return ret
+
+	case strings.HasPrefix(description, "max of"):
+		// dis_max, only the best scoring clause counts (tie_breaker is ignored)
+		best := e.Details[0]
+		for _, d := range e.Details[1:] {
+			if d.Value > best.Value {
+				best = d
+			}
+		}
+		return attributeScore(best, scale)
+
+	case strings.HasPrefix(description, "product of"),
+		strings.HasPrefix(description, "function score"):
+		// boosts and function scores multiply a single scoring child,
+		// scale that child so that it accounts for the whole product
+		for _, d := range e.Details {
+			if len(d.Details) > 0 && d.Value != 0 {
+				return attributeScore(d, scale*e.Value/d.Value)
+			}
+		}
+	}
+
+	clause, field := parseExplanationClause(description)
+	return []ScoreContribution{{
+		Clause:       clause,
+		Field:        field,
+		Contribution: e.Value * scale,
+	}}
+}
+
+// parseExplanationClause extracts the clause and field from a lucene explanation
+// description such as "weight(title:coffee in 0) [PerFieldSimilarity], result of:".
+func parseExplanationClause(description string) (string, string) {
+	clause := description
+	if strings.HasPrefix(clause, "weight(") {
+		clause = strings.TrimPrefix(clause, "weight(")
+		if idx := strings.LastIndex(clause, " in "); idx != -1 {
+			clause = clause[:idx]
+		}
+	}
+
+	field := ""
+	// phrase queries look like title:"foo bar", synonyms like Synonym(title:foo title:bar)
+	trimmed := strings.TrimPrefix(clause, "Synonym(")
+	if idx := strings.Index(trimmed, ":"); idx != -1 && !strings.ContainsAny(trimmed[:idx], " ()") {
+		field = trimmed[:idx]
+	}
+
+	return clause, field
+}
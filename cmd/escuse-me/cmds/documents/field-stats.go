@@ -0,0 +1,243 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type FieldStatsCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &FieldStatsCommand{}
+
+func NewFieldStatsCommand() (*FieldStatsCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &FieldStatsCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"field-stats",
+			cmds.WithShort("Describes numeric and date fields: count, min, max, avg, sum, stddev and percentiles"),
+			cmds.WithLong(`
+The 'field-stats' command runs an extended_stats and a percentiles aggregation for each
+of the given numeric or date fields, and outputs one row per field with the columns
+count, min, max, avg, sum, std_deviation and one column per percentile (p50, p99, ...).
+
+Dates are output formatted, as returned by Elasticsearch.
+
+Examples:
+
+   escuse-me documents field-stats --index orders --field price,quantity
+
+   escuse-me documents field-stats --index logs --field duration_ms \
+      --query '{"term": {"status": 500}}' --percentiles 50,90,99,99.9
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Comma-separated list of data streams, indices, and aliases to aggregate"),
+				),
+				parameters.NewParameterDefinition(
+					"field",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Numeric or date fields to describe"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("The query restricting the described documents as a JSON string"),
+				),
+				parameters.NewParameterDefinition(
+					"percentiles",
+					parameters.ParameterTypeFloatList,
+					parameters.WithHelp("Percentiles to compute (empty to skip them)"),
+					parameters.WithDefault([]float64{1, 5, 25, 50, 75, 95, 99}),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type FieldStatsSettings struct {
+	Index       []string  `glazed.parameter:"index"`
+	Fields      []string  `glazed.parameter:"field"`
+	Query       string    `glazed.parameter:"query"`
+	Percentiles []float64 `glazed.parameter:"percentiles"`
+}
+
+type extendedStatsAggregation struct {
+	Count        float64  `json:"count"`
+	Min          *float64 `json:"min"`
+	Max          *float64 `json:"max"`
+	Avg          *float64 `json:"avg"`
+	Sum          float64  `json:"sum"`
+	StdDeviation *float64 `json:"std_deviation"`
+	MinAsString  string   `json:"min_as_string"`
+	MaxAsString  string   `json:"max_as_string"`
+	AvgAsString  string   `json:"avg_as_string"`
+	SumAsString  string   `json:"sum_as_string"`
+}
+
+type percentilesAggregation struct {
+	Values []struct {
+		Key           float64  `json:"key"`
+		Value         *float64 `json:"value"`
+		ValueAsString string   `json:"value_as_string"`
+	} `json:"values"`
+}
+
+func (c *FieldStatsCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *FieldStatsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &FieldStatsSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	// aggregations are named by position, field names may contain characters that are
+	// not allowed in aggregation names
+	aggregations := map[string]interface{}{}
+	for i, field := range s.Fields {
+		aggregations[fmt.Sprintf("stats_%d", i)] = map[string]interface{}{
+			"extended_stats": map[string]interface{}{"field": field},
+		}
+		if len(s.Percentiles) > 0 {
+			aggregations[fmt.Sprintf("percentiles_%d", i)] = map[string]interface{}{
+				"percentiles": map[string]interface{}{
+					"field":    field,
+					"percents": s.Percentiles,
+					"keyed":    false,
+				},
+			}
+		}
+	}
+
+	body := map[string]interface{}{
+		"size":             0,
+		"track_total_hits": false,
+		"aggs":             aggregations,
+	}
+	if s.Query != "" {
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(s.Query), &query); err != nil {
+			return errors.Wrap(err, "invalid query JSON")
+		}
+		body["query"] = query
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(s.Index...),
+		es.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(responseBody)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	var response struct {
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return err
+	}
+
+	for i, field := range s.Fields {
+		stats := extendedStatsAggregation{}
+		if err := json.Unmarshal(response.Aggregations[fmt.Sprintf("stats_%d", i)], &stats); err != nil {
+			return errors.Wrapf(err, "could not parse stats of %s", field)
+		}
+
+		row := types.NewRow(
+			types.MRP("field", field),
+			types.MRP("count", int64(stats.Count)),
+			types.MRP("min", statValue(stats.Min, stats.MinAsString)),
+			types.MRP("max", statValue(stats.Max, stats.MaxAsString)),
+			types.MRP("avg", statValue(stats.Avg, stats.AvgAsString)),
+			types.MRP("sum", statValue(&stats.Sum, stats.SumAsString)),
+			types.MRP("std_deviation", statValue(stats.StdDeviation, "")),
+		)
+
+		if len(s.Percentiles) > 0 {
+			percentiles := percentilesAggregation{}
+			if err := json.Unmarshal(response.Aggregations[fmt.Sprintf("percentiles_%d", i)], &percentiles); err != nil {
+				return errors.Wrapf(err, "could not parse percentiles of %s", field)
+			}
+			for _, p := range percentiles.Values {
+				row.Set("p"+strconv.FormatFloat(p.Key, 'f', -1, 64), statValue(p.Value, p.ValueAsString))
+			}
+		}
+
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statValue returns the formatted value for date fields, the numeric value otherwise.
+// Metrics over no documents are null.
+func statValue(value *float64, asString string) interface{} {
+	if value == nil {
+		return nil
+	}
+	if asString != "" {
+		return asString
+	}
+	return *value
+}
@@ -0,0 +1,110 @@
+package documents
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GeoPoint is a latitude / longitude pair.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func parseCoordinates(values []string) ([]float64, error) {
+	ret := make([]float64, 0, len(values))
+	for _, v := range values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid coordinate %q", v)
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+func newGeoPoint(lat, lon float64) (GeoPoint, error) {
+	if lat < -90 || lat > 90 {
+		return GeoPoint{}, errors.Errorf("latitude %v out of range [-90, 90]", lat)
+	}
+	if lon < -180 || lon > 180 {
+		return GeoPoint{}, errors.Errorf("longitude %v out of range [-180, 180]", lon)
+	}
+	return GeoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// parseGeoDistance parses "distance,lat,lon" (for example "10km,48.85,2.35") into a
+// geo_distance query on field.
+func parseGeoDistance(field string, s string) (map[string]interface{}, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return nil, errors.Errorf("invalid geo distance %q, expected distance,lat,lon", s)
+	}
+	distance := strings.TrimSpace(parts[0])
+	if distance == "" {
+		return nil, errors.Errorf("invalid geo distance %q, missing distance", s)
+	}
+	coordinates, err := parseCoordinates(parts[1:])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid geo distance %q", s)
+	}
+	point, err := newGeoPoint(coordinates[0], coordinates[1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid geo distance %q", s)
+	}
+
+	return map[string]interface{}{
+		"geo_distance": map[string]interface{}{
+			"distance": distance,
+			field:      point,
+		},
+	}, nil
+}
+
+// parseGeoBoundingBox parses "top_left_lat,top_left_lon,bottom_right_lat,bottom_right_lon"
+// into a geo_bounding_box query on field.
+func parseGeoBoundingBox(field string, s string) (map[string]interface{}, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, errors.Errorf("invalid geo bounding box %q, expected top_left_lat,top_left_lon,bottom_right_lat,bottom_right_lon", s)
+	}
+	coordinates, err := parseCoordinates(parts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid geo bounding box %q", s)
+	}
+	topLeft, err := newGeoPoint(coordinates[0], coordinates[1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid geo bounding box %q", s)
+	}
+	bottomRight, err := newGeoPoint(coordinates[2], coordinates[3])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid geo bounding box %q", s)
+	}
+	if topLeft.Lat < bottomRight.Lat {
+		return nil, errors.Errorf("invalid geo bounding box %q, top latitude is below bottom latitude", s)
+	}
+
+	return map[string]interface{}{
+		"geo_bounding_box": map[string]interface{}{
+			field: map[string]interface{}{
+				"top_left":     topLeft,
+				"bottom_right": bottomRight,
+			},
+		},
+	}, nil
+}
+
+// addFiltersToQuery wraps query into a bool query with the given filters.
+func addFiltersToQuery(query interface{}, filters []interface{}) map[string]interface{} {
+	bool_ := map[string]interface{}{
+		"filter": filters,
+	}
+	if query != nil {
+		bool_["must"] = query
+	}
+	return map[string]interface{}{
+		"bool": bool_,
+	}
+}
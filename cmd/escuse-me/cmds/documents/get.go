@@ -122,6 +122,12 @@ escuse-me get --index "my-index" --id "my-document-id" --refresh
 					parameters.WithHelp("Flatten _source fields into the root of the response"),
 					parameters.WithDefault(false),
 				),
+				parameters.NewParameterDefinition(
+					"max_fields_per_row",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Collapse the fields of the row beyond this number into a JSON _overflow column, with their count in _overflow_count (0 for no limit)"),
+					parameters.WithDefault(helpers.DefaultMaxFieldsPerRow),
+				),
 			),
 			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
 		),
@@ -140,6 +146,11 @@ type GetDocumentSettings struct {
 	Version        *int      `glazed.parameter:"version"`
 	VersionType    *string   `glazed.parameter:"version_type"`
 	FlattenSource  bool      `glazed.parameter:"flatten_source"`
+	MaxFields      int       `glazed.parameter:"max_fields_per_row"`
+}
+
+func (c *GetDocumentCommand) IsIdempotent() bool {
+	return true
 }
 
 func (c *GetDocumentCommand) RunIntoGlazeProcessor(
@@ -156,6 +167,15 @@ func (c *GetDocumentCommand) RunIntoGlazeProcessor(
 	if err != nil {
 		return err
 	}
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	maxFields := helpers.NewMaxFieldsMiddleware(s.MaxFields)
+	if !maxFields.IsNoop() {
+		gp.(*middlewares.TableProcessor).AddRowMiddlewareInFront(maxFields)
+	}
 
 	options := []func(*esapi.GetRequest){
 		es.Get.WithContext(ctx),
@@ -205,6 +225,10 @@ func (c *GetDocumentCommand) RunIntoGlazeProcessor(
 	}
 	err_, isError := helpers.ParseErrorResponse(body)
 	if isError {
+		// with command-retries, transient errors are returned for the command to be retried
+		if responseError := err_.ResponseError(); esSettings.CommandRetries > 0 && responseError.IsTransient() {
+			return responseError
+		}
 		row := types.NewRowFromStruct(err_.Error, true)
 		row.Set("status", err_.Status)
 		return gp.AddRow(ctx, row)
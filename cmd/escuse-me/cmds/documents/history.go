@@ -0,0 +1,261 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type HistoryListCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &HistoryListCommand{}
+
+func NewHistoryListCommand() (*HistoryListCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+
+	return &HistoryListCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"ls",
+			cmds.WithShort("Lists the queries recorded with search --track_query"),
+			cmds.WithLong(`
+Lists the searches recorded in the local query history, most recent first. The id column
+can be passed to 'documents history replay' to rerun a query.
+
+The history is stored in ~/.config/escuse-me/history.ndjson and only keeps the last
+1000 queries. It never contains credentials, but does contain the queries themselves.
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"last",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Only list the last N queries (0 lists all)"),
+					parameters.WithDefault(20),
+				),
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only list queries that searched this index"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer),
+		),
+	}, nil
+}
+
+type HistoryListSettings struct {
+	Last  int    `glazed.parameter:"last"`
+	Index string `glazed.parameter:"index"`
+}
+
+func (c *HistoryListCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &HistoryListSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	listed := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		if s.Last > 0 && listed >= s.Last {
+			break
+		}
+		entry := entries[i]
+		if s.Index != "" && !containsString(entry.Index, s.Index) {
+			continue
+		}
+		row := types.NewRow(
+			types.MRP("id", i+1),
+			types.MRP("timestamp", entry.Timestamp),
+			types.MRP("index", strings.Join(entry.Index, ",")),
+			types.MRP("hits", entry.Hits),
+			types.MRP("query", string(entry.Query)),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+		listed++
+	}
+
+	return nil
+}
+
+type HistoryReplayCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &HistoryReplayCommand{}
+
+func NewHistoryReplayCommand() (*HistoryReplayCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &HistoryReplayCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"replay",
+			cmds.WithShort("Reruns a query from the query history"),
+			cmds.WithLong(`
+Reruns a query recorded with search --track_query against the same indices, and
+outputs the hits like the search command does. Use 'documents history ls' to find the id.
+
+   escuse-me documents history replay 42 --index products-v2
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Search these indices instead of the recorded ones"),
+				),
+				parameters.NewParameterDefinition(
+					"full_hit_output",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Whether to return the full output for each hit, or just the source"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"output_hit_id",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Whether to include the hit ID in the output, as the _id column"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithArguments(
+				parameters.NewParameterDefinition(
+					"id",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Id of the query, as listed by 'documents history ls'"),
+					parameters.WithRequired(true),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type HistoryReplaySettings struct {
+	ID            int      `glazed.parameter:"id"`
+	Index         []string `glazed.parameter:"index"`
+	FullHitOutput bool     `glazed.parameter:"full_hit_output"`
+	OutputHitID   bool     `glazed.parameter:"output_hit_id"`
+}
+
+func (c *HistoryReplayCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *HistoryReplayCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &HistoryReplaySettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if s.ID < 1 || s.ID > len(entries) {
+		return errors.Errorf("no query with id %d in the history (%d queries)", s.ID, len(entries))
+	}
+	entry := entries[s.ID-1]
+
+	index := entry.Index
+	if len(s.Index) > 0 {
+		index = s.Index
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index...),
+		es.Search.WithBody(bytes.NewReader(entry.Query)),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	var responseMap map[string]interface{}
+	if err := json.Unmarshal(body, &responseMap); err != nil {
+		return err
+	}
+	hits, err := getSearchHits(responseMap)
+	if err != nil {
+		return err
+	}
+
+	return emitSearchHits(ctx, &SearchDocumentSettings{
+		FullHitOutput:   s.FullHitOutput,
+		OutputHitID:     s.OutputHitID,
+		InnerHitsOutput: innerHitsOutputRows,
+	}, hits, gp)
+}
+
+func loadHistory() ([]es_cmds.HistoryEntry, error) {
+	historyFile, err := es_cmds.GetDefaultHistoryFile()
+	if err != nil {
+		return nil, err
+	}
+	return es_cmds.LoadHistory(historyFile)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
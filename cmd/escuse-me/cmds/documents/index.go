@@ -38,6 +38,16 @@ func NewIndexDocumentCommand() (*IndexDocumentCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"index",
 			cmds.WithShort("Indexes a document"),
+			cmds.WithLong(`
+Indexes a document, creating it or replacing an existing document with the same id.
+
+Use --on_conflict to control what happens when a document with the given id already exists:
+  - overwrite: replace the document (op_type index)
+  - fail: return a version conflict error (op_type create)
+  - skip: leave the existing document alone and output result: skipped
+
+   escuse-me documents index --index products --id 42 --document product.json --on_conflict skip
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"index",
@@ -56,6 +66,12 @@ func NewIndexDocumentCommand() (*IndexDocumentCommand, error) {
 					parameters.WithHelp("Explicit operation type"),
 					parameters.WithChoices("index", "create"),
 				),
+				parameters.NewParameterDefinition(
+					"on_conflict",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("What to do when a document with the given id already exists (requires --id, replaces --op_type)"),
+					parameters.WithChoices("skip", "overwrite", "fail"),
+				),
 				parameters.NewParameterDefinition(
 					"pipeline",
 					parameters.ParameterTypeString,
@@ -78,6 +94,16 @@ func NewIndexDocumentCommand() (*IndexDocumentCommand, error) {
 					parameters.WithHelp("Explicit operation timeout"),
 					parameters.WithDefault("1m"),
 				),
+				parameters.NewParameterDefinition(
+					"if_seq_no",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Only perform the index operation if the last operation that has changed the document has the specified sequence number"),
+				),
+				parameters.NewParameterDefinition(
+					"if_primary_term",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Only perform the index operation if the last operation that has changed the document has the specified primary term"),
+				),
 				parameters.NewParameterDefinition(
 					"version",
 					parameters.ParameterTypeInteger,
@@ -115,9 +141,12 @@ type IndexDocumentSettings struct {
 	Index               string                 `glazed.parameter:"index"`
 	ID                  string                 `glazed.parameter:"id"`
 	OpType              string                 `glazed.parameter:"op_type"`
+	OnConflict          string                 `glazed.parameter:"on_conflict"`
 	Pipeline            string                 `glazed.parameter:"pipeline"`
 	Refresh             string                 `glazed.parameter:"refresh"`
 	Routing             string                 `glazed.parameter:"routing"`
+	IfSeqNo             *int                   `glazed.parameter:"if_seq_no"`
+	IfPrimaryTerm       *int                   `glazed.parameter:"if_primary_term"`
 	Version             int                    `glazed.parameter:"version"`
 	VersionType         string                 `glazed.parameter:"version_type"`
 	WaitForActiveShards string                 `glazed.parameter:"wait_for_active_shards"`
@@ -135,6 +164,21 @@ func (c *IndexDocumentCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	if s.OnConflict != "" {
+		if s.ID == "" {
+			return errors.New("--on_conflict requires --id")
+		}
+		if s.OpType != "" {
+			return errors.New("--on_conflict and --op_type are mutually exclusive")
+		}
+		// skipping relies on create failing for existing documents, which unlike checking
+		// for the document first can't race with a concurrent write
+		s.OpType = "create"
+		if s.OnConflict == "overwrite" {
+			s.OpType = "index"
+		}
+	}
+
 	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
 	if err != nil {
 		return err
@@ -153,6 +197,12 @@ func (c *IndexDocumentCommand) RunIntoGlazeProcessor(
 	if s.Pipeline != "" {
 		indexOpts = append(indexOpts, es.Index.WithPipeline(s.Pipeline))
 	}
+	if s.IfSeqNo != nil {
+		indexOpts = append(indexOpts, es.Index.WithIfSeqNo(*s.IfSeqNo))
+	}
+	if s.IfPrimaryTerm != nil {
+		indexOpts = append(indexOpts, es.Index.WithIfPrimaryTerm(*s.IfPrimaryTerm))
+	}
 	if s.Version != 0 {
 		indexOpts = append(indexOpts, es.Index.WithVersion(s.Version))
 	}
@@ -186,16 +236,42 @@ func (c *IndexDocumentCommand) RunIntoGlazeProcessor(
 		return err
 	}
 	err_, isError := helpers.ParseErrorResponse(body)
+	if isError && s.OnConflict == "skip" && isVersionConflict(err_) {
+		row := types.NewRow(
+			types.MRP("_index", s.Index),
+			types.MRP("_id", s.ID),
+			types.MRP("result", "skipped"),
+		)
+		return gp.AddRow(ctx, row)
+	}
 	if isError {
 		row := types.NewRowFromStruct(err_.Error, true)
 		row.Set("status", err_.Status)
 		return gp.AddRow(ctx, row)
 	}
 
-	responseRow := types.NewRow()
-	if err := json.Unmarshal(body, &responseRow); err != nil {
+	var response struct {
+		Index       string `json:"_index"`
+		ID          string `json:"_id"`
+		Version     int64  `json:"_version"`
+		Result      string `json:"result"`
+		SeqNo       int64  `json:"_seq_no"`
+		PrimaryTerm int64  `json:"_primary_term"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
 		return err
 	}
 
-	return gp.AddRow(ctx, responseRow)
+	return gp.AddRow(ctx, types.NewRow(
+		types.MRP("_id", response.ID),
+		types.MRP("_index", response.Index),
+		types.MRP("result", response.Result),
+		types.MRP("_version", response.Version),
+		types.MRP("_seq_no", response.SeqNo),
+		types.MRP("_primary_term", response.PrimaryTerm),
+	))
+}
+
+func isVersionConflict(err *helpers.ElasticsearchError) bool {
+	return err.Status == 409 || err.Error.Type == "version_conflict_engine_exception"
 }
@@ -0,0 +1,95 @@
+package documents
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+const (
+	innerHitsOutputRows   = "rows"
+	innerHitsOutputColumn = "column"
+	innerHitsOutputIgnore = "ignore"
+)
+
+// innerHitsByName returns the hits of each named inner_hits block of a hit, sorted by
+// name.
+func innerHitsByName(innerHits interface{}) ([]string, map[string][]interface{}) {
+	innerHits_, _ := innerHits.(map[string]interface{})
+	names := make([]string, 0, len(innerHits_))
+	ret := make(map[string][]interface{}, len(innerHits_))
+	for name, block := range innerHits_ {
+		block_, _ := block.(map[string]interface{})
+		hits, _ := block_["hits"].(map[string]interface{})
+		hits_, _ := hits["hits"].([]interface{})
+		names = append(names, name)
+		ret[name] = hits_
+	}
+	sort.Strings(names)
+	return names, ret
+}
+
+// flattenInnerHits returns the inner hits of a hit by inner_hits name, either as full
+// hits or as their _source only.
+func flattenInnerHits(innerHits interface{}, fullHits bool) map[string]interface{} {
+	_, hitsByName := innerHitsByName(innerHits)
+	ret := make(map[string]interface{}, len(hitsByName))
+	for name, hits := range hitsByName {
+		if fullHits {
+			ret[name] = hits
+			continue
+		}
+		sources := make([]interface{}, 0, len(hits))
+		for _, hit := range hits {
+			hit_, _ := hit.(map[string]interface{})
+			if source, ok := hit_["_source"]; ok {
+				sources = append(sources, source)
+			}
+		}
+		ret[name] = sources
+	}
+	return ret
+}
+
+// emitInnerHits outputs a row for each inner hit of the hit parentID, linked to it by
+// the _parent_id column. Inner hits of nested queries share the _id of their parent and
+// are told apart by the _nested column.
+func emitInnerHits(
+	ctx context.Context,
+	s *SearchDocumentSettings,
+	parentID interface{},
+	innerHits interface{},
+	gp middlewares.Processor,
+) error {
+	names, hitsByName := innerHitsByName(innerHits)
+	for _, name := range names {
+		for _, hit := range hitsByName[name] {
+			hitMap, ok := hit.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			row := types.NewRow(
+				types.MRP("_parent_id", parentID),
+				types.MRP("_inner_hits_name", name),
+			)
+			if s.OutputHitID {
+				row.Set("_id", hitMap["_id"])
+			}
+			if nested, ok := hitMap["_nested"]; ok {
+				row.Set("_nested", nested)
+			}
+			if source, ok := hitMap["_source"]; ok {
+				if err := setSourceColumns(row, source); err != nil {
+					return err
+				}
+			}
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
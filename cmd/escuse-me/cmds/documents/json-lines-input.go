@@ -0,0 +1,79 @@
+package documents
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	"github.com/pkg/errors"
+)
+
+// DocumentRef identifies a document read from a JSON lines input, one object per line:
+//
+//	{"_id": "1", "_index": "products", "_routing": "shop-1", "_seq_no": 12, "_primary_term": 1}
+//
+// Only _id is required, _index defaults to the --index of the command. _seq_no and
+// _primary_term, as output by search --emit_versioning, guard updates against concurrent
+// modifications. Other fields are ignored, so that search output can be piped as is.
+type DocumentRef struct {
+	Index       string
+	ID          string
+	Routing     string
+	SeqNo       *int64
+	PrimaryTerm *int64
+}
+
+// readDocumentRefs reads the documents referenced by the JSON objects of r.
+func readDocumentRefs(r io.Reader, defaultIndex string) ([]DocumentRef, error) {
+	objects, err := helpers.ReadJSONObjects(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]DocumentRef, 0, len(objects))
+	for i, object := range objects {
+		ref := DocumentRef{Index: defaultIndex}
+		id, ok := object["_id"]
+		if !ok || id == nil {
+			return nil, errors.Errorf("input line %d has no _id", i+1)
+		}
+		ref.ID = fmt.Sprint(id)
+		if index, ok := object["_index"].(string); ok && index != "" {
+			ref.Index = index
+		}
+		if ref.Index == "" {
+			return nil, errors.Errorf("input line %d has no _index, and no --index was given", i+1)
+		}
+		if routing, ok := object["_routing"]; ok && routing != nil {
+			ref.Routing = fmt.Sprint(routing)
+		}
+		if ref.SeqNo, err = jsonInt64(object["_seq_no"]); err != nil {
+			return nil, errors.Wrapf(err, "invalid _seq_no on input line %d", i+1)
+		}
+		if ref.PrimaryTerm, err = jsonInt64(object["_primary_term"]); err != nil {
+			return nil, errors.Wrapf(err, "invalid _primary_term on input line %d", i+1)
+		}
+		if (ref.SeqNo == nil) != (ref.PrimaryTerm == nil) {
+			return nil, errors.Errorf("input line %d must have both or neither of _seq_no and _primary_term", i+1)
+		}
+		ret = append(ret, ref)
+	}
+
+	return ret, nil
+}
+
+func jsonInt64(v interface{}) (*int64, error) {
+	if v == nil {
+		return nil, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, errors.Errorf("%v is not a number", v)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -138,6 +138,10 @@ type MultiGetDocumentSettings struct {
 	FlattenSource  bool      `glazed.parameter:"flatten_source"`
 }
 
+func (c *MultiGetDocumentCommand) IsIdempotent() bool {
+	return true
+}
+
 func (c *MultiGetDocumentCommand) RunIntoGlazeProcessor(
 	ctx context.Context,
 	parsedLayers *layers.ParsedLayers,
@@ -152,6 +156,10 @@ func (c *MultiGetDocumentCommand) RunIntoGlazeProcessor(
 	if err != nil {
 		return err
 	}
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
 
 	options := []func(*esapi.MgetRequest){
 		es.Mget.WithContext(ctx),
@@ -232,6 +240,10 @@ func (c *MultiGetDocumentCommand) RunIntoGlazeProcessor(
 	}
 	err_, isError := helpers.ParseErrorResponse(body_)
 	if isError {
+		// with command-retries, transient errors are returned for the command to be retried
+		if responseError := err_.ResponseError(); esSettings.CommandRetries > 0 && responseError.IsTransient() {
+			return responseError
+		}
 		row := types.NewRowFromStruct(err_.Error, true)
 		row.Set("status", err_.Status)
 		return gp.AddRow(ctx, row)
@@ -0,0 +1,184 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultScanPageSize  = 1000
+	defaultScanKeepAlive = "1m"
+)
+
+// ScanHit is a document returned by scanDocuments, with the sequence number and
+// primary term needed to update it with optimistic concurrency control.
+type ScanHit struct {
+	Index       string                 `json:"_index"`
+	ID          string                 `json:"_id"`
+	SeqNo       int64                  `json:"_seq_no"`
+	PrimaryTerm int64                  `json:"_primary_term"`
+	Routing     string                 `json:"_routing,omitempty"`
+	Source      map[string]interface{} `json:"_source"`
+	Sort        []interface{}          `json:"sort"`
+}
+
+type scanResponse struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Hits []ScanHit `json:"hits"`
+	} `json:"hits"`
+}
+
+// scanDocuments iterates over all documents of index matching query (all documents if
+// query is nil) using a point in time and search_after, calling fn with each page of hits.
+// Returning an error from fn stops the scan. The point in time is always closed.
+//
+// The query is validated before opening the point in time, so that an invalid query
+// fails right away instead of on the first page, with a point in time left to expire.
+func scanDocuments(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index []string,
+	query map[string]interface{},
+	pageSize int,
+	keepAlive string,
+	pitOptions helpers.PITOptions,
+	fn func(hits []ScanHit) error,
+) error {
+	if pageSize <= 0 {
+		pageSize = defaultScanPageSize
+	}
+	if keepAlive == "" {
+		keepAlive = defaultScanKeepAlive
+	}
+	if query == nil {
+		query = map[string]interface{}{
+			"match_all": map[string]interface{}{},
+		}
+	}
+
+	if err := validateScanQuery(ctx, es, index, query); err != nil {
+		return err
+	}
+
+	pit, err := helpers.OpenPIT(ctx, es, index, keepAlive, pitOptions)
+	if err != nil {
+		return errors.Wrapf(err, "could not open point in time on %s", strings.Join(index, ","))
+	}
+	defer pit.Close()
+
+	var searchAfter []interface{}
+	for {
+		body := map[string]interface{}{
+			"size":                pageSize,
+			"query":               query,
+			"seq_no_primary_term": true,
+			"track_total_hits":    false,
+			"sort":                []interface{}{map[string]interface{}{"_shard_doc": "asc"}},
+			"pit":                 map[string]interface{}{"id": pit.ID, "keep_alive": keepAlive},
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+
+		response, err := scanPage(ctx, es, body)
+		if err != nil {
+			return err
+		}
+		pit.Update(response.PitID)
+
+		hits := response.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+		if err := fn(hits); err != nil {
+			return err
+		}
+		if len(hits) < pageSize {
+			return nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+	}
+}
+
+func scanPage(ctx context.Context, es *elasticsearch.Client, body map[string]interface{}) (*scanResponse, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(responseBody); err != nil {
+		return nil, err
+	}
+
+	response := &scanResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal search response")
+	}
+
+	return response, nil
+}
+
+// validateScanQuery runs query without fetching any document, returning the reason
+// given by ES if it is invalid.
+func validateScanQuery(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index []string,
+	query map[string]interface{},
+) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"size":             0,
+		"query":            query,
+		"track_total_hits": false,
+	}); err != nil {
+		return err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index...),
+		es.Search.WithBody(&buf),
+		es.Search.WithTerminateAfter(1),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return errors.Wrap(err, "invalid query")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// releaseSearchContextTimeout bounds the requests releasing scroll contexts, which are
+// sent even after the command context has been cancelled.
+const releaseSearchContextTimeout = 5 * time.Second
+
+// defaultScrollAllKeepAlive is the scroll period used by search --scroll_all when
+// --scroll is not given.
+const defaultScrollAllKeepAlive = time.Minute
+
+// scrollNextPage fetches the next page of a scroll search, keeping the scroll context
+// alive for keepAlive.
+func scrollNextPage(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	scrollID string,
+	keepAlive time.Duration,
+	orderedSource bool,
+) (map[string]interface{}, error) {
+	res, err := es.Scroll(
+		es.Scroll.WithContext(ctx),
+		es.Scroll.WithScrollID(scrollID),
+		es.Scroll.WithScroll(keepAlive),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	return decodeSearchResponse(body, orderedSource)
+}
+
+// decodeSearchResponse decodes a search or scroll response. If orderedSource is set,
+// the _source of each hit is decoded into a types.Row instead of a map, keeping the
+// order of the fields in the document.
+func decodeSearchResponse(body []byte, orderedSource bool) (map[string]interface{}, error) {
+	var responseMap map[string]interface{}
+	if err := json.Unmarshal(body, &responseMap); err != nil {
+		return nil, err
+	}
+	if !orderedSource {
+		return responseMap, nil
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source types.Row `json:"_source,omitempty"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "could not decode hit sources")
+	}
+
+	hits, err := getSearchHits(responseMap)
+	if err != nil {
+		return responseMap, nil
+	}
+	for i, hit := range hits {
+		hitMap, ok := hit.(map[string]interface{})
+		if !ok || i >= len(response.Hits.Hits) || response.Hits.Hits[i].Source == nil {
+			continue
+		}
+		hitMap["_source"] = response.Hits.Hits[i].Source
+	}
+
+	return responseMap, nil
+}
+
+// clearScroll releases the scroll context on the cluster. It is best effort: failures
+// are only logged, since the context expires after its keep alive anyway.
+//
+// It doesn't use the command context, so that the scroll is also cleared when the
+// command has been cancelled.
+func clearScroll(es *elasticsearch.Client, scrollID string) {
+	if scrollID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseSearchContextTimeout)
+	defer cancel()
+
+	res, err := es.ClearScroll(
+		es.ClearScroll.WithContext(ctx),
+		es.ClearScroll.WithScrollID(scrollID),
+	)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not clear scroll context")
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	if res.IsError() {
+		log.Warn().Str("status", res.Status()).Msg("could not clear scroll context")
+	}
+}
+
+// getSearchHits returns the hits of a search or scroll response.
+func getSearchHits(responseMap map[string]interface{}) ([]interface{}, error) {
+	hits, ok := responseMap["hits"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("could not find hits in response")
+	}
+	hits_, ok := hits["hits"].([]interface{})
+	if !ok {
+		return nil, errors.New("could not find hits in response")
+	}
+	return hits_, nil
+}
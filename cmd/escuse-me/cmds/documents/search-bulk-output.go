@@ -0,0 +1,50 @@
+package documents
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// writeBulkHits writes the hits as bulk actions, one action line and one source line
+// per hit (no source line for delete), keeping the _id and routing of each hit. The
+// actions target the index of their hit unless targetIndex is set.
+func writeBulkHits(w io.Writer, action string, targetIndex string, hits []interface{}) error {
+	var buf bytes.Buffer
+	for _, hit := range hits {
+		hitMap, ok := hit.(map[string]interface{})
+		if !ok {
+			return errors.New("could not find hit in response")
+		}
+
+		meta := map[string]interface{}{
+			"_index": hitMap["_index"],
+			"_id":    hitMap["_id"],
+		}
+		if targetIndex != "" {
+			meta["_index"] = targetIndex
+		}
+		if routing, ok := hitMap["_routing"]; ok {
+			meta["routing"] = routing
+		}
+
+		bulkAction := BulkAction{Action: action, Meta: meta}
+		if action != "delete" {
+			source, ok := hitMap["_source"]
+			if !ok {
+				return errors.Errorf("hit %v has no _source", hitMap["_id"])
+			}
+			bulkAction.Source = source
+			if action == "update" {
+				bulkAction.Source = map[string]interface{}{"doc": source}
+			}
+		}
+		if err := writeBulkAction(&buf, bulkAction); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,191 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	postRetryInitialBackoff = 500 * time.Millisecond
+	postRetryMaxBackoff     = 10 * time.Second
+)
+
+// hitPoster POSTs search hits as JSON to an HTTP endpoint, batchSize hits at a time,
+// with at most concurrency requests in flight. A batch of a single hit is posted as a
+// JSON object, larger batches as a JSON array.
+type hitPoster struct {
+	client    *http.Client
+	url       string
+	batchSize int
+	retries   int
+
+	batch []interface{}
+	sem   chan struct{}
+	wg    sync.WaitGroup
+
+	mu        sync.Mutex
+	hits      int
+	batches   int
+	delivered int
+	failed    int
+}
+
+func newHitPoster(url string, batchSize int, retries int, concurrency int) *hitPoster {
+	return &hitPoster{
+		// the default transport honors the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+		// environment variables, like the ES client
+		client:    &http.Client{Transport: http.DefaultTransport},
+		url:       url,
+		batchSize: max(batchSize, 1),
+		retries:   retries,
+		sem:       make(chan struct{}, max(concurrency, 1)),
+	}
+}
+
+// Add queues the hits, posting every full batch.
+func (p *hitPoster) Add(ctx context.Context, hits []interface{}) error {
+	for _, hit := range hits {
+		p.batch = append(p.batch, hit)
+		if len(p.batch) >= p.batchSize {
+			if err := p.dispatch(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close posts the last partial batch and waits for all the requests to complete.
+func (p *hitPoster) Close(ctx context.Context) error {
+	err := p.dispatch(ctx)
+	p.wg.Wait()
+	return err
+}
+
+// SummaryRow returns the delivery counts.
+func (p *hitPoster) SummaryRow() types.Row {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return types.NewRow(
+		types.MRP("url", p.url),
+		types.MRP("hits", p.hits),
+		types.MRP("batches", p.batches),
+		types.MRP("delivered", p.delivered),
+		types.MRP("failed", p.failed),
+	)
+}
+
+func (p *hitPoster) dispatch(ctx context.Context) error {
+	if len(p.batch) == 0 {
+		return nil
+	}
+	batch := p.batch
+	p.batch = nil
+
+	var body []byte
+	var err error
+	if len(batch) == 1 && p.batchSize == 1 {
+		body, err = json.Marshal(batch[0])
+	} else {
+		body, err = json.Marshal(batch)
+	}
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case p.sem <- struct{}{}:
+	}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+
+		err := p.post(ctx, body)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.hits += len(batch)
+		p.batches++
+		if err != nil {
+			log.Warn().Err(err).Str("url", p.url).Int("hits", len(batch)).Msg("could not post hits")
+			p.failed += len(batch)
+			return
+		}
+		p.delivered += len(batch)
+	}()
+	return nil
+}
+
+// post sends body, retrying network errors and 429, 502, 503 and 504 responses with an
+// exponential backoff.
+func (p *hitPoster) post(ctx context.Context, body []byte) error {
+	backoff := postRetryInitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := p.postOnce(ctx, body)
+		if err == nil {
+			return nil
+		}
+		transient := es_cmds.IsTransientError(err)
+		var statusErr *postStatusError
+		if errors.As(err, &statusErr) {
+			transient = helpers.IsTransientStatus(statusErr.status)
+		}
+		if attempt >= p.retries || !transient || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, postRetryMaxBackoff)
+	}
+}
+
+func (p *hitPoster) postOnce(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return &postStatusError{status: res.StatusCode}
+	}
+	return nil
+}
+
+// postStatusError is the non-2xx status returned by the endpoint hits are posted to.
+type postStatusError struct {
+	status int
+}
+
+func (e *postStatusError) Error() string {
+	return fmt.Sprintf("[%d] %s", e.status, http.StatusText(e.status))
+}
@@ -0,0 +1,73 @@
+package documents
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHitPosterRetriesTransientStatus(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	poster := newHitPoster(server.URL, 1, 2, 1)
+	if err := poster.Add(ctx, []interface{}{map[string]interface{}{"title": "first"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := poster.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	row := poster.SummaryRow()
+	if delivered, _ := row.Get("delivered"); delivered != 1 {
+		t.Errorf("expected 1 delivered hit, got %v", delivered)
+	}
+	if failed, _ := row.Get("failed"); failed != 0 {
+		t.Errorf("expected no failed hit, got %v", failed)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestHitPosterDoesNotRetryClientErrors(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	poster := newHitPoster(server.URL, 1, 2, 1)
+	if err := poster.Add(ctx, []interface{}{map[string]interface{}{"title": "first"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := poster.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if failed, _ := poster.SummaryRow().Get("failed"); failed != 1 {
+		t.Errorf("expected 1 failed hit, got %v", failed)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
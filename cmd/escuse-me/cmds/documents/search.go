@@ -1,11 +1,14 @@
 package documents
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -14,7 +17,10 @@ import (
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"io"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -23,6 +29,22 @@ type SearchDocumentSettings struct {
 	Body                       string                 `glazed.parameter:"body"`
 	BodyFile                   map[string]interface{} `glazed.parameter:"body_file"`
 	Query                      string                 `glazed.parameter:"query"`
+	BodyParams                 []string               `glazed.parameter:"body_param"`
+	TimeField                  string                 `glazed.parameter:"time_field"`
+	Since                      string                 `glazed.parameter:"since"`
+	Until                      string                 `glazed.parameter:"until"`
+	GeoField                   string                 `glazed.parameter:"geo_field"`
+	GeoDistance                string                 `glazed.parameter:"geo_distance"`
+	GeoBoundingBox             string                 `glazed.parameter:"geo_bounding_box"`
+	HighlightFields            []string               `glazed.parameter:"highlight_fields"`
+	HighlightPre               string                 `glazed.parameter:"highlight_pre"`
+	HighlightPost              string                 `glazed.parameter:"highlight_post"`
+	Aggs                       map[string]interface{} `glazed.parameter:"aggs"`
+	AggsOnly                   bool                   `glazed.parameter:"aggs_only"`
+	RescoreQuery               string                 `glazed.parameter:"rescore_query"`
+	RescoreWindow              int                    `glazed.parameter:"rescore_window"`
+	QueryWeight                float64                `glazed.parameter:"query_weight"`
+	RescoreQueryWeight         float64                `glazed.parameter:"rescore_query_weight"`
 	QueryFile                  map[string]interface{} `glazed.parameter:"query_file"`
 	AllowNoIndices             *bool                  `glazed.parameter:"allow_no_indices"`
 	AllowPartialSearchResults  *bool                  `glazed.parameter:"allow_partial_search_results"`
@@ -44,6 +66,7 @@ type SearchDocumentSettings struct {
 	IgnoreUnavailable          *bool                  `glazed.parameter:"ignore_unavailable"`
 	Lenient                    *bool                  `glazed.parameter:"lenient"`
 	MinCompatibleShardNode     string                 `glazed.parameter:"min_compatible_shard_node"`
+	MinScore                   *float64               `glazed.parameter:"min_score"`
 	MaxConcurrentShardRequests *int                   `glazed.parameter:"max_concurrent_shard_requests"`
 	PreFilterShardSize         *int                   `glazed.parameter:"pre_filter_shard_size"`
 	Preference                 string                 `glazed.parameter:"preference"`
@@ -52,6 +75,10 @@ type SearchDocumentSettings struct {
 	RestTotalHitsAsInt         *bool                  `glazed.parameter:"rest_total_hits_as_int"`
 	Routing                    []string               `glazed.parameter:"routing"`
 	Scroll                     int                    `glazed.parameter:"scroll"`
+	ScrollKeepAlive            string                 `glazed.parameter:"scroll_keep_alive"`
+	ScrollAll                  bool                   `glazed.parameter:"scroll_all"`
+	Paginate                   bool                   `glazed.parameter:"paginate"`
+	MaxDocs                    int                    `glazed.parameter:"max_docs"`
 	SearchAfter                []interface{}          `glazed.parameter:"search_after"`
 	SearchType                 string                 `glazed.parameter:"search_type"`
 	SeqNoPrimaryTerm           *bool                  `glazed.parameter:"seq_no_primary_term"`
@@ -60,6 +87,7 @@ type SearchDocumentSettings struct {
 	Source                     []string               `glazed.parameter:"source"`
 	SourceExcludes             []string               `glazed.parameter:"source_excludes"`
 	SourceIncludes             []string               `glazed.parameter:"source_includes"`
+	SourceConfig               map[string]interface{} `glazed.parameter:"source_config"`
 	Stats                      []string               `glazed.parameter:"stats"`
 	StoredFields               []string               `glazed.parameter:"stored_fields"`
 	SuggestField               string                 `glazed.parameter:"suggest_field"`
@@ -87,6 +115,30 @@ type SearchDocumentSettings struct {
 	FullOutput    bool `glazed.parameter:"full_output"`
 	FullHitOutput bool `glazed.parameter:"full_hit_output"`
 	OutputHitID   bool `glazed.parameter:"output_hit_id"`
+	OrderedSource bool `glazed.parameter:"ordered_source"`
+
+	OutputBulkNDJSON bool   `glazed.parameter:"output_bulk_ndjson"`
+	BulkAction       string `glazed.parameter:"bulk_action"`
+	TargetIndex      string `glazed.parameter:"target_index"`
+
+	PostTo          string `glazed.parameter:"post_to"`
+	PostBatchSize   int    `glazed.parameter:"post_batch_size"`
+	PostRetries     int    `glazed.parameter:"post_retries"`
+	PostConcurrency int    `glazed.parameter:"post_concurrency"`
+	// InnerHitsOutput is one of the innerHitsOutput constants.
+	InnerHitsOutput string `glazed.parameter:"inner_hits_output"`
+	EmitVersion     bool   `glazed.parameter:"emit_versioning"`
+
+	ExplainN int `glazed.parameter:"explain_n"`
+
+	DropEmpty    bool                   `glazed.parameter:"drop_empty"`
+	RenameFields map[string]interface{} `glazed.parameter:"rename_fields"`
+	MaxFields    int                    `glazed.parameter:"max_fields_per_row"`
+	DedupField   string                 `glazed.parameter:"dedup_field"`
+
+	TrackQuery        bool   `glazed.parameter:"track_query"`
+	ProfileOutputFile string `glazed.parameter:"profile_output_file"`
+	Diagnose          bool   `glazed.parameter:"diagnose"`
 }
 
 type DocvalueField struct {
@@ -183,6 +235,48 @@ Examples:
 10. Combine different queries using the bool query:
     escuse-me search --query "$(echo '{"bool": {"must": [{"match": {"title": "search"}}, {"match": {"content": "elasticsearch"}}]}}' | temporizer)"
 
+11. Set body options that have no dedicated flag, using dotted paths:
+    escuse-me search --body_param collapse.field=user_id --body_param 'highlight.fields={"title": {}}'
+
+12. Highlight matches in some fields:
+    escuse-me search --query '{"match": {"title": "coffee"}}' --highlight_fields title,description
+
+13. Re-rank the top hits of a cheap query with a more expensive one. Only the top
+    --rescore_window hits of each shard are rescored, the others keep their original score:
+    escuse-me search --query '{"match": {"title": "french coffee"}}' \
+       --rescore_query '{"match_phrase": {"title": {"query": "french coffee", "slop": 2}}}' \
+       --rescore_window 50 --rescore_query_weight 2
+
+14. Fetch all matching documents, 1000 at a time, with search_after:
+    escuse-me search --index logs --sort '@timestamp:asc,event.id:asc' --size 1000 --paginate
+
+15. Record the query in the local history, to list and rerun it later with 'documents history':
+    escuse-me search --index products --query '{"match": {"name": "coffee"}}' --track_query
+
+16. Find out why a query returns no hits:
+    escuse-me search --index products --query '{"term": {"name": "Coffee"}}' --diagnose
+
+17. Search the errors of the last hour, the time range being combined with the query:
+    escuse-me search --index logs --query '{"match": {"level": "error"}}' --time_field @timestamp --since 1h
+    escuse-me search --index logs --time_field @timestamp --since 2024-01-01 --until 2024-01-02T12:00:00Z
+
+18. Copy the matching documents to another index through the bulk API:
+    escuse-me search --index products --query '{"term": {"discontinued": true}}' --scroll_all \
+       --output_bulk_ndjson --target_index products-archive \
+       | escuse-me request --method POST --path /_bulk --body_file -
+
+19. Send the matching documents to another service, 100 hits per request:
+    escuse-me search --index orders --query '{"term": {"status": "paid"}}' --scroll_all \
+       --post_to https://etl.example.com/orders --post_batch_size 100
+
+20. Filter the returned _source with includes and excludes patterns, e.g. with a
+    source.yaml containing "includes: [user.*]" and "excludes: [user.password]":
+    escuse-me search --index users --source_config source.yaml
+
+21. Only keep the best scoring hit of each user across all pages, where collapse can't be used:
+    escuse-me search --index events --query '{"match": {"message": "login failed"}}' --scroll_all \
+       --dedup_field user_id
+
 The command supports many other parameters that can be used to fine-tune the search operation, such as 'allow_no_indices', 'batched_reduce_size', 'default_operator', 'explain', 'scroll', 'search_after', and more. You can also control the output format with flags like 'full_output', 'full_hit_output', and 'output_hit_id'.
 
 For more complex queries and detailed control over the search operation, refer to the Elasticsearch documentation and construct the query JSON accordingly.
@@ -213,6 +307,92 @@ For more complex queries and detailed control over the search operation, refer t
 					parameters.ParameterTypeString,
 					parameters.WithHelp("The query to execute as a JSON string"),
 				),
+				parameters.NewParameterDefinition(
+					"body_param",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Deep-merge a value into the request body at a dotted path (path=value, value parsed as JSON if possible), for body features not exposed as flags"),
+				),
+				parameters.NewParameterDefinition(
+					"time_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Date field used by --since and --until"),
+				),
+				parameters.NewParameterDefinition(
+					"since",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only return documents from this time on (date math like now-1h, duration like 1h, RFC3339 or 2006-01-02)"),
+				),
+				parameters.NewParameterDefinition(
+					"until",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only return documents before this time (date math like now-1h, duration like 1h, RFC3339 or 2006-01-02)"),
+				),
+				parameters.NewParameterDefinition(
+					"geo_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("geo_point field used by --geo_distance and --geo_bounding_box"),
+				),
+				parameters.NewParameterDefinition(
+					"geo_distance",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only return documents within a distance of a point (distance,lat,lon, e.g. 10km,48.85,2.35)"),
+				),
+				parameters.NewParameterDefinition(
+					"geo_bounding_box",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only return documents within a bounding box (top_left_lat,top_left_lon,bottom_right_lat,bottom_right_lon)"),
+				),
+				parameters.NewParameterDefinition(
+					"highlight_fields",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Highlight matches in these fields, output as the _highlight column of each hit"),
+				),
+				parameters.NewParameterDefinition(
+					"highlight_pre",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Tag inserted before each highlighted term"),
+					parameters.WithDefault("<em>"),
+				),
+				parameters.NewParameterDefinition(
+					"highlight_post",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Tag inserted after each highlighted term"),
+					parameters.WithDefault("</em>"),
+				),
+				parameters.NewParameterDefinition(
+					"aggs",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing aggregations, whose buckets are output as rows before the hits"),
+				),
+				parameters.NewParameterDefinition(
+					"aggs_only",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only output the aggregations, without fetching hits unless --size is given"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"rescore_query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Query re-ranking the top --rescore_window hits of each shard, as a JSON string"),
+				),
+				parameters.NewParameterDefinition(
+					"rescore_window",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of top hits per shard that are rescored"),
+					parameters.WithDefault(10),
+				),
+				parameters.NewParameterDefinition(
+					"query_weight",
+					parameters.ParameterTypeFloat,
+					parameters.WithHelp("Weight of the original query score in the rescored score"),
+					parameters.WithDefault(1.0),
+				),
+				parameters.NewParameterDefinition(
+					"rescore_query_weight",
+					parameters.ParameterTypeFloat,
+					parameters.WithHelp("Weight of the rescore query score in the rescored score"),
+					parameters.WithDefault(1.0),
+				),
 				// Add all other flags for search parameters here
 				parameters.NewParameterDefinition(
 					"allow_no_indices",
@@ -322,7 +502,30 @@ For more complex queries and detailed control over the search operation, refer t
 				parameters.NewParameterDefinition(
 					"scroll",
 					parameters.ParameterTypeInteger,
-					parameters.WithHelp("Period to retain the search context for scrolling (in milliseconds)"),
+					parameters.WithHelp("Period to retain the search context for scrolling (in milliseconds). When set, all pages of results are fetched"),
+					parameters.WithDefault(0),
+				),
+				parameters.NewParameterDefinition(
+					"scroll_keep_alive",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Period to extend the search context by on each scroll request (e.g. 1m, default: the --scroll value)"),
+				),
+				parameters.NewParameterDefinition(
+					"scroll_all",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Fetch all pages of results with a scroll, --size hits at a time (default scroll: 1m). Combine with --stream to output hits as they arrive"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"paginate",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Fetch all pages of results with search_after, --size hits at a time. Requires a --sort ending with a unique tiebreaker field"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"max_docs",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Stop after outputting this many documents (0 for no limit)"),
 					parameters.WithDefault(0),
 				),
 				parameters.NewParameterDefinition(
@@ -365,6 +568,11 @@ For more complex queries and detailed control over the search operation, refer t
 					parameters.ParameterTypeStringList,
 					parameters.WithHelp("A comma-separated list of source fields to include in the response"),
 				),
+				parameters.NewParameterDefinition(
+					"source_config",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the _source filtering of the request body (e.g. includes and excludes), overriding --source, --source_includes and --source_excludes"),
+				),
 				parameters.NewParameterDefinition(
 					"stats",
 					parameters.ParameterTypeStringList,
@@ -436,6 +644,11 @@ For more complex queries and detailed control over the search operation, refer t
 					parameters.ParameterTypeString,
 					parameters.WithHelp("Minimum compatible version of a shard node"),
 				),
+				parameters.NewParameterDefinition(
+					"min_score",
+					parameters.ParameterTypeFloat,
+					parameters.WithHelp("Exclude documents with a _score lower than this value. Ignored by Elasticsearch when sorting by another field than _score"),
+				),
 				parameters.NewParameterDefinition(
 					"pretty",
 					parameters.ParameterTypeBool,
@@ -474,6 +687,111 @@ For more complex queries and detailed control over the search operation, refer t
 					parameters.WithHelp("Whether to include the hit ID in the output, as the _id column"),
 					parameters.WithDefault(false),
 				),
+				parameters.NewParameterDefinition(
+					"inner_hits_output",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("How to output the inner_hits of nested and has_child queries: as rows following their parent hit with a _parent_id column, nested under an _inner_hits column, or not at all. With --full_hit_output, rows and column both nest them under _inner_hits"),
+					parameters.WithChoices(innerHitsOutputRows, innerHitsOutputColumn, innerHitsOutputIgnore),
+					parameters.WithDefault(innerHitsOutputRows),
+				),
+				parameters.NewParameterDefinition(
+					"output_bulk_ndjson",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Write the hits to stdout as bulk NDJSON actions instead of rows, to be sent to the bulk API"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"bulk_action",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Bulk action written for each hit with --output_bulk_ndjson (update actions use the source as partial document)"),
+					parameters.WithChoices("index", "create", "update", "delete"),
+					parameters.WithDefault("index"),
+				),
+				parameters.NewParameterDefinition(
+					"target_index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index targeted by the bulk actions of --output_bulk_ndjson (default: the index of each hit)"),
+				),
+				parameters.NewParameterDefinition(
+					"post_to",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("POST the hits as JSON to this URL instead of outputting them, and output the delivery counts"),
+				),
+				parameters.NewParameterDefinition(
+					"post_batch_size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of hits posted per request with --post_to. Hits are posted as a JSON object if 1, as a JSON array otherwise"),
+					parameters.WithDefault(1),
+				),
+				parameters.NewParameterDefinition(
+					"post_retries",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of times a request of --post_to is retried on network errors and 429, 502, 503 and 504 responses"),
+					parameters.WithDefault(3),
+				),
+				parameters.NewParameterDefinition(
+					"post_concurrency",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Maximum number of concurrent requests of --post_to"),
+					parameters.WithDefault(4),
+				),
+				parameters.NewParameterDefinition(
+					"ordered_source",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Output the fields of each document in the order of its _source instead of an arbitrary order"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"emit_versioning",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Include the _id, _version, _seq_no and _primary_term columns, for use with if_seq_no/if_primary_term"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"explain_n",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Explain the ranking of the top N hits: output _id, _score and the clause contributing most to the score instead of the documents"),
+					parameters.WithDefault(0),
+				),
+				parameters.NewParameterDefinition(
+					"drop_empty",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Remove null, empty string, empty array and empty object fields from each document"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"rename_fields",
+					parameters.ParameterTypeKeyValue,
+					parameters.WithHelp("Rename top-level document fields (old:new,old2:new2)"),
+				),
+				parameters.NewParameterDefinition(
+					"max_fields_per_row",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Collapse the fields of a row beyond this number into a JSON _overflow column, with their count in _overflow_count (0 for no limit)"),
+					parameters.WithDefault(helpers.DefaultMaxFieldsPerRow),
+				),
+				parameters.NewParameterDefinition(
+					"dedup_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Drop the rows whose value for this column has already been output, keeping the first one"),
+				),
+				parameters.NewParameterDefinition(
+					"track_query",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Record the query, index and hit count in the local query history (can be enabled permanently in a profile)"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"profile_output_file",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Profile the search and write the profile JSON to this file, the hits being output as usual"),
+				),
+				parameters.NewParameterDefinition(
+					"diagnose",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("If the search returns no hits, output a report of the likely causes (empty index, unmapped fields, analysis) instead"),
+					parameters.WithDefault(false),
+				),
 			),
 			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
 		),
@@ -529,11 +847,97 @@ func initializeSearchRequest(settings *SearchDocumentSettings) (*esapi.SearchReq
 		body["query"] = query
 	}
 
+	filters, err := buildGeoFilters(settings)
+	if err != nil {
+		return nil, err
+	}
+	timeRangeFilter, err := buildTimeRangeFilter(settings.TimeField, settings.Since, settings.Until)
+	if err != nil {
+		return nil, err
+	}
+	if timeRangeFilter != nil {
+		filters = append(filters, timeRangeFilter)
+	}
+	if len(filters) > 0 {
+		body["query"] = addFiltersToQuery(body["query"], filters)
+	}
+
+	if len(settings.HighlightFields) > 0 {
+		body = helpers.DeepMerge(body, map[string]interface{}{
+			"highlight": buildHighlight(settings.HighlightFields, settings.HighlightPre, settings.HighlightPost),
+		})
+	}
+
+	if settings.Aggs != nil {
+		body = helpers.DeepMerge(body, map[string]interface{}{
+			"aggs": settings.Aggs,
+		})
+	}
+	if settings.AggsOnly && settings.Size == nil {
+		size := 0
+		settings.Size = &size
+	}
+
+	if settings.SourceConfig != nil {
+		body = helpers.DeepMerge(body, map[string]interface{}{
+			"_source": settings.SourceConfig,
+		})
+		// the _source query parameters take precedence over the body in ES
+		if len(settings.Source) > 0 || len(settings.SourceIncludes) > 0 || len(settings.SourceExcludes) > 0 {
+			log.Warn().Msg("--source, --source_includes and --source_excludes are ignored when using --source_config")
+		}
+		settings.Source = nil
+		settings.SourceIncludes = nil
+		settings.SourceExcludes = nil
+	}
+
+	if settings.RescoreQuery != "" {
+		rescore, err := buildRescore(settings)
+		if err != nil {
+			return nil, err
+		}
+		body = helpers.DeepMerge(body, map[string]interface{}{
+			"rescore": rescore,
+		})
+	}
+
+	for _, bodyParam := range settings.BodyParams {
+		value, err := helpers.ParsePathValue(bodyParam)
+		if err != nil {
+			return nil, err
+		}
+		body = helpers.DeepMerge(body, value)
+	}
+
+	if settings.ProfileOutputFile != "" {
+		body["profile"] = true
+	}
+
+	if len(settings.SearchAfter) > 0 {
+		body["search_after"] = settings.SearchAfter
+	}
+
+	if settings.MinScore != nil {
+		body["min_score"] = *settings.MinScore
+		if isSortedByNonScoreField(settings.Sort, body["sort"]) {
+			log.Warn().
+				Float64("min_score", *settings.MinScore).
+				Msg("min_score is ignored by Elasticsearch when sorting by a field other than _score")
+		}
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(body); err != nil {
 		return nil, err
 	}
 
+	if settings.EmitVersion {
+		seqNoPrimaryTerm := true
+		version := true
+		settings.SeqNoPrimaryTerm = &seqNoPrimaryTerm
+		settings.Version = &version
+	}
+
 	searchRequest := esapi.SearchRequest{
 		Index:                      settings.Index,
 		Body:                       &buf,
@@ -589,6 +993,113 @@ func initializeSearchRequest(settings *SearchDocumentSettings) (*esapi.SearchReq
 	return &searchRequest, nil
 }
 
+func buildGeoFilters(settings *SearchDocumentSettings) ([]interface{}, error) {
+	if settings.GeoDistance == "" && settings.GeoBoundingBox == "" {
+		return nil, nil
+	}
+	if settings.GeoField == "" {
+		return nil, errors.New("--geo_distance and --geo_bounding_box require --geo_field")
+	}
+
+	filters := []interface{}{}
+	if settings.GeoDistance != "" {
+		filter, err := parseGeoDistance(settings.GeoField, settings.GeoDistance)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if settings.GeoBoundingBox != "" {
+		filter, err := parseGeoBoundingBox(settings.GeoField, settings.GeoBoundingBox)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// buildHighlight builds a highlight block highlighting the given fields with the
+// default highlighter settings.
+func buildHighlight(fields []string, preTag string, postTag string) map[string]interface{} {
+	fields_ := map[string]interface{}{}
+	for _, field := range fields {
+		fields_[field] = map[string]interface{}{}
+	}
+
+	highlight := map[string]interface{}{
+		"fields": fields_,
+	}
+	if preTag != "" {
+		highlight["pre_tags"] = []interface{}{preTag}
+	}
+	if postTag != "" {
+		highlight["post_tags"] = []interface{}{postTag}
+	}
+
+	return highlight
+}
+
+// buildRescore builds a rescore block re-ranking the top hits of each shard with the
+// rescore query.
+func buildRescore(settings *SearchDocumentSettings) (map[string]interface{}, error) {
+	var rescoreQuery map[string]interface{}
+	if err := json.Unmarshal([]byte(settings.RescoreQuery), &rescoreQuery); err != nil {
+		return nil, errors.Wrap(err, "invalid rescore query JSON")
+	}
+	if settings.RescoreWindow <= 0 {
+		return nil, errors.New("--rescore_window must be positive")
+	}
+
+	return map[string]interface{}{
+		"window_size": settings.RescoreWindow,
+		"query": map[string]interface{}{
+			"rescore_query":        rescoreQuery,
+			"query_weight":         settings.QueryWeight,
+			"rescore_query_weight": settings.RescoreQueryWeight,
+		},
+	}, nil
+}
+
+// isSortedByNonScoreField returns true if either the sort query parameters or the sort
+// part of the request body sort by something else than _score first.
+func isSortedByNonScoreField(sortParameters []string, bodySort interface{}) bool {
+	if len(sortParameters) > 0 {
+		field := strings.SplitN(sortParameters[0], ":", 2)[0]
+		return field != "_score"
+	}
+
+	switch v := bodySort.(type) {
+	case string:
+		return v != "_score"
+	case map[string]interface{}:
+		_, ok := v["_score"]
+		return !ok
+	case []interface{}:
+		if len(v) == 0 {
+			return false
+		}
+		return isSortedByNonScoreField(nil, v[0])
+	default:
+		return false
+	}
+}
+
+func (c *SearchDocumentCommand) IsIdempotent() bool {
+	return true
+}
+
+// IsIdempotentWithSettings returns false if the hits are posted, written to stdout as
+// they are fetched, or if the query or profile is recorded, as a retry would repeat these.
+func (c *SearchDocumentCommand) IsIdempotentWithSettings(parsedLayers *layers.ParsedLayers) (bool, error) {
+	s := &SearchDocumentSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return false, err
+	}
+	return s.PostTo == "" && !s.OutputBulkNDJSON && !s.TrackQuery && s.ProfileOutputFile == "", nil
+}
+
 func (c *SearchDocumentCommand) RunIntoGlazeProcessor(
 	ctx context.Context,
 	parsedLayers *layers.ParsedLayers,
@@ -603,12 +1114,56 @@ func (c *SearchDocumentCommand) RunIntoGlazeProcessor(
 	if err != nil {
 		return err
 	}
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	if s.ExplainN > 0 {
+		explain := true
+		s.Explain = &explain
+		s.Size = &s.ExplainN
+	}
+
+	if s.ScrollAll && s.Scroll == 0 {
+		s.Scroll = int(defaultScrollAllKeepAlive / time.Millisecond)
+	}
+	if s.Paginate && s.Scroll != 0 {
+		return errors.New("--paginate can't be combined with scrolling")
+	}
+	if s.OutputBulkNDJSON && (s.FullOutput || s.AggsOnly || s.ExplainN > 0 || s.Diagnose) {
+		return errors.New("--output_bulk_ndjson can't be combined with --full_output, --aggs_only, --explain_n or --diagnose")
+	}
+	if s.PostTo != "" && (s.OutputBulkNDJSON || s.FullOutput || s.AggsOnly || s.ExplainN > 0 || s.Diagnose) {
+		return errors.New("--post_to can't be combined with --output_bulk_ndjson, --full_output, --aggs_only, --explain_n or --diagnose")
+	}
 
 	searchRequest, err := initializeSearchRequest(s)
 	if err != nil {
 		return err
 	}
 
+	// the middlewares added in front run in reverse order: the rows are deduplicated
+	// using the renamed fields, before the fields are limited
+	maxFields := helpers.NewMaxFieldsMiddleware(s.MaxFields)
+	if !maxFields.IsNoop() {
+		gp.(*middlewares.TableProcessor).AddRowMiddlewareInFront(maxFields)
+	}
+	dedup := helpers.NewDedupMiddleware(s.DedupField, helpers.DefaultDedupMaxKeys)
+	if !dedup.IsNoop() {
+		gp.(*middlewares.TableProcessor).AddRowMiddlewareInFront(dedup)
+	}
+	documentTransform := helpers.NewDocumentTransformMiddlewareFromKeyValue(s.DropEmpty, s.RenameFields)
+	if !documentTransform.IsNoop() {
+		gp.(*middlewares.TableProcessor).AddRowMiddlewareInFront(documentTransform)
+	}
+
+	// keep the rendered request body around, the request consumes it
+	var renderedQuery []byte
+	if buf, ok := searchRequest.Body.(*bytes.Buffer); ok {
+		renderedQuery = append(renderedQuery, buf.Bytes()...)
+	}
+
 	searchResponse, err := searchRequest.Do(ctx, es)
 	if err != nil {
 		return err
@@ -622,13 +1177,55 @@ func (c *SearchDocumentCommand) RunIntoGlazeProcessor(
 	if err != nil {
 		return err
 	}
+	if expensiveQueryError, ok := es_cmds.NewExpensiveQueryError(ctx, es, body); ok {
+		return expensiveQueryError
+	}
 	err_, isError := helpers.ParseErrorResponse(body)
 	if isError {
+		// with command-retries, transient errors are returned for the command to be retried
+		if responseError := err_.ResponseError(); esSettings.CommandRetries > 0 && responseError.IsTransient() {
+			return responseError
+		}
 		row := types.NewRowFromStruct(err_.Error, true)
 		row.Set("status", err_.Status)
 		return gp.AddRow(ctx, row)
 	}
 
+	responseMap, err := decodeSearchResponse(body, s.OrderedSource)
+	if err != nil {
+		return err
+	}
+
+	// the scroll context is cleared on every exit path, including errors and cancellation
+	scrollID, _ := responseMap["_scroll_id"].(string)
+	defer func() {
+		clearScroll(es, scrollID)
+	}()
+
+	if s.TrackQuery {
+		trackQuery(s.Index, renderedQuery, responseMap)
+	}
+
+	if s.ProfileOutputFile != "" {
+		if err := writeSearchProfile(s.ProfileOutputFile, responseMap); err != nil {
+			return err
+		}
+	}
+
+	if s.Diagnose {
+		if hits_, err := getSearchHits(responseMap); err == nil && len(hits_) == 0 {
+			var renderedBody map[string]interface{}
+			if err := json.Unmarshal(renderedQuery, &renderedBody); err != nil {
+				return err
+			}
+			diagnostics, err := diagnoseNoResults(ctx, es, s.Index, renderedBody["query"])
+			if err != nil {
+				return err
+			}
+			return emitDiagnostics(ctx, diagnostics, gp)
+		}
+	}
+
 	if s.FullOutput {
 		responseRow := types.NewRow()
 		if err := json.Unmarshal(body, &responseRow); err != nil {
@@ -638,28 +1235,235 @@ func (c *SearchDocumentCommand) RunIntoGlazeProcessor(
 		return gp.AddRow(ctx, responseRow)
 	}
 
-	// If full_output is not set, only return the hits
-	var responseMap map[string]interface{}
-	if err := json.Unmarshal(body, &responseMap); err != nil {
-		return err
+	if aggregations, ok := responseMap["aggregations"].(map[string]interface{}); ok {
+		for _, row := range es_cmds.FlattenAggregations(aggregations) {
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+		}
+	}
+	if s.AggsOnly {
+		return nil
+	}
+
+	// If full_output is not set, only return the hits, fetching all pages when scrolling
+	keepAlive := time.Duration(s.Scroll) * time.Millisecond
+	if s.ScrollKeepAlive != "" {
+		keepAlive, err = time.ParseDuration(s.ScrollKeepAlive)
+		if err != nil {
+			return errors.Wrap(err, "invalid scroll keep alive")
+		}
+	}
+
+	emitHits := func(hits []interface{}) error {
+		return emitSearchHits(ctx, s, hits, gp)
+	}
+	// the bulk actions are written to stdout directly, bypassing the glazed output
+	hitsDone := func() error {
+		return nil
+	}
+	if s.OutputBulkNDJSON {
+		w := bufio.NewWriter(os.Stdout)
+		defer func() {
+			_ = w.Flush()
+		}()
+		emitHits = func(hits []interface{}) error {
+			return writeBulkHits(w, s.BulkAction, s.TargetIndex, hits)
+		}
+		hitsDone = func() error {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			return &cmds.ExitWithoutGlazeError{}
+		}
+	}
+	// the hits are posted instead of output, only the delivery counts are output
+	if s.PostTo != "" {
+		poster := newHitPoster(s.PostTo, s.PostBatchSize, s.PostRetries, s.PostConcurrency)
+		defer func() {
+			_ = poster.Close(ctx)
+		}()
+		emitHits = func(hits []interface{}) error {
+			return poster.Add(ctx, hits)
+		}
+		hitsDone = func() error {
+			if err := poster.Close(ctx); err != nil {
+				return err
+			}
+			return gp.AddRow(ctx, poster.SummaryRow())
+		}
 	}
 
+	emitted := 0
+	for {
+		hits_, err := getSearchHits(responseMap)
+		if err != nil {
+			return err
+		}
+
+		if s.ExplainN > 0 {
+			return emitRelevanceExplanations(ctx, hits_, gp)
+		}
+
+		if s.MaxDocs > 0 && emitted+len(hits_) > s.MaxDocs {
+			hits_ = hits_[:s.MaxDocs-emitted]
+		}
+		if err := emitHits(hits_); err != nil {
+			return err
+		}
+		emitted += len(hits_)
+
+		if s.Paginate {
+			if len(hits_) == 0 || len(hits_) < searchPageSize(s) || (s.MaxDocs > 0 && emitted >= s.MaxDocs) {
+				return hitsDone()
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			lastHit, _ := hits_[len(hits_)-1].(map[string]interface{})
+			searchAfter, ok := lastHit["sort"].([]interface{})
+			if !ok {
+				return errors.New("--paginate requires sorted hits, use --sort")
+			}
+			s.SearchAfter = searchAfter
+			responseMap, err = searchNextPage(ctx, es, s)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if scrollID == "" || len(hits_) == 0 || (s.MaxDocs > 0 && emitted >= s.MaxDocs) {
+			return hitsDone()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		responseMap, err = scrollNextPage(ctx, es, scrollID, keepAlive, s.OrderedSource)
+		if err != nil {
+			return err
+		}
+		if scrollID_, ok := responseMap["_scroll_id"].(string); ok {
+			scrollID = scrollID_
+		}
+	}
+}
+
+// trackQuery records the search in the query history. Failing to do so doesn't fail
+// the search.
+func trackQuery(index []string, query []byte, responseMap map[string]interface{}) {
+	historyFile, err := es_cmds.GetDefaultHistoryFile()
+	if err != nil {
+		log.Warn().Err(err).Msg("could not record query in history")
+		return
+	}
+
+	entry := es_cmds.HistoryEntry{
+		Timestamp: time.Now(),
+		Index:     index,
+		Query:     bytes.TrimSpace(query),
+		Hits:      getTotalHits(responseMap),
+	}
+	if err := es_cmds.AppendHistory(historyFile, entry, es_cmds.DefaultHistorySize); err != nil {
+		log.Warn().Err(err).Str("file", historyFile).Msg("could not record query in history")
+	}
+}
+
+// getTotalHits returns hits.total of a search response, which is either a number or
+// an object with a value, depending on rest_total_hits_as_int.
+func getTotalHits(responseMap map[string]interface{}) int64 {
 	hits, ok := responseMap["hits"].(map[string]interface{})
 	if !ok {
-		return errors.New("could not find hits in response")
+		return 0
+	}
+	switch total := hits["total"].(type) {
+	case float64:
+		return int64(total)
+	case map[string]interface{}:
+		if value, ok := total["value"].(float64); ok {
+			return int64(value)
+		}
 	}
-	hits_, ok := hits["hits"].([]interface{})
+	return 0
+}
+
+// writeSearchProfile writes the profile part of a search response to file, as indented
+// JSON. Only the first page is profiled when fetching several pages.
+func writeSearchProfile(file string, responseMap map[string]interface{}) error {
+	profile, ok := responseMap["profile"]
 	if !ok {
-		return errors.New("could not find hits in response")
+		return errors.New("could not find profile in response")
+	}
+	b, err := json.MarshalIndent(map[string]interface{}{"profile": profile}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		return errors.Wrapf(err, "could not write profile to %s", file)
+	}
+	return nil
+}
+
+// searchPageSize returns the number of hits returned per request.
+func searchPageSize(s *SearchDocumentSettings) int {
+	if s.Size != nil {
+		return *s.Size
+	}
+	return 10
+}
+
+// searchNextPage issues the search request again, for the page following
+// s.SearchAfter.
+func searchNextPage(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	s *SearchDocumentSettings,
+) (map[string]interface{}, error) {
+	searchRequest, err := initializeSearchRequest(s)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := searchRequest.Do(ctx, es)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
 	}
 
-	for _, hit := range hits_ {
+	return decodeSearchResponse(body, s.OrderedSource)
+}
+
+// emitSearchHits outputs the hits of a search response according to the output settings.
+func emitSearchHits(
+	ctx context.Context,
+	s *SearchDocumentSettings,
+	hits []interface{},
+	gp middlewares.Processor,
+) error {
+	for _, hit := range hits {
 		hitMap, ok := hit.(map[string]interface{})
 		if !ok {
 			return errors.New("could not find hit in response")
 		}
 		if s.FullHitOutput {
 			hitRow := types.NewRowFromMap(hitMap)
+			if innerHits, ok := hitMap["inner_hits"]; ok && s.InnerHitsOutput != innerHitsOutputIgnore {
+				hitRow.Delete("inner_hits")
+				hitRow.Set("_inner_hits", flattenInnerHits(innerHits, true))
+			}
 			if err := gp.AddRow(ctx, hitRow); err != nil {
 				return err
 			}
@@ -667,21 +1471,98 @@ func (c *SearchDocumentCommand) RunIntoGlazeProcessor(
 			continue
 		}
 
-		source, ok := hitMap["_source"].(map[string]interface{})
-		if !ok {
-			return errors.New("could not find source in hit")
-		}
-
 		hitRow := types.NewRow()
-		if s.OutputHitID {
+		if s.OutputHitID || s.EmitVersion {
 			hitRow.Set("_id", hitMap["_id"])
 		}
-		for k, v := range source {
-			hitRow.Set(k, v)
+		if s.EmitVersion {
+			hitRow.Set("_version", hitMap["_version"])
+			hitRow.Set("_seq_no", hitMap["_seq_no"])
+			hitRow.Set("_primary_term", hitMap["_primary_term"])
+		}
+		if err := setSourceColumns(hitRow, hitMap["_source"]); err != nil {
+			return err
+		}
+		if highlight, ok := hitMap["highlight"]; ok {
+			hitRow.Set("_highlight", highlight)
+		}
+
+		innerHits, hasInnerHits := hitMap["inner_hits"]
+		if hasInnerHits && s.InnerHitsOutput == innerHitsOutputColumn {
+			hitRow.Set("_inner_hits", flattenInnerHits(innerHits, false))
 		}
 		if err := gp.AddRow(ctx, hitRow); err != nil {
 			return err
 		}
+
+		if hasInnerHits && s.InnerHitsOutput == innerHitsOutputRows {
+			if err := emitInnerHits(ctx, s, hitMap["_id"], innerHits, gp); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setSourceColumns sets a column on row for each field of the _source of a hit.
+func setSourceColumns(row types.Row, source interface{}) error {
+	switch source := source.(type) {
+	case types.Row:
+		for pair := source.Oldest(); pair != nil; pair = pair.Next() {
+			row.Set(pair.Key, pair.Value)
+		}
+	case map[string]interface{}:
+		for k, v := range source {
+			row.Set(k, v)
+		}
+	default:
+		return errors.New("could not find source in hit")
+	}
+	return nil
+}
+
+// emitRelevanceExplanations outputs a compact relevance table for hits returned with
+// explain enabled: the _id, _score and the clause contributing most to the score.
+func emitRelevanceExplanations(ctx context.Context, hits []interface{}, gp middlewares.Processor) error {
+	for _, hit := range hits {
+		hitMap, ok := hit.(map[string]interface{})
+		if !ok {
+			return errors.New("could not find hit in response")
+		}
+
+		row := types.NewRow(
+			types.MRP("_id", hitMap["_id"]),
+			types.MRP("_score", hitMap["_score"]),
+		)
+
+		explanation := Explanation{}
+		if explanation_, ok := hitMap["_explanation"]; ok {
+			b, err := json.Marshal(explanation_)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(b, &explanation); err != nil {
+				return errors.Wrap(err, "could not parse hit explanation")
+			}
+		}
+
+		var top *ScoreContribution
+		for _, contribution := range AttributeScore(explanation) {
+			contribution := contribution
+			if top == nil || contribution.Contribution > top.Contribution {
+				top = &contribution
+			}
+		}
+		if top != nil {
+			row.Set("top_clause", top.Clause)
+			row.Set("top_field", top.Field)
+			row.Set("top_value", top.Contribution)
+		}
+
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,157 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func searchRequests(server *estest.Server, index string) []estest.Request {
+	return append(
+		server.RequestsTo(http.MethodGet, "/"+index+"/_search"),
+		server.RequestsTo(http.MethodPost, "/"+index+"/_search")...,
+	)
+}
+
+func TestSearchOutputsHits(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleSearch("tasks",
+		map[string]interface{}{"title": "first"},
+		map[string]interface{}{"title": "second"},
+	)
+
+	cmd, err := NewSearchDocumentCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"index":         []string{"tasks"},
+			"output_hit_id": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	for i, expected := range []string{"first", "second"} {
+		if title, _ := rows[i].Get("title"); title != expected {
+			t.Errorf("row %d: expected title %q, got %v", i, expected, title)
+		}
+		if id, _ := rows[i].Get("_id"); id != []string{"0", "1"}[i] {
+			t.Errorf("row %d: unexpected _id %v", i, id)
+		}
+	}
+	if n := len(searchRequests(server, "tasks")); n != 1 {
+		t.Errorf("expected 1 search request, got %d", n)
+	}
+}
+
+func TestSearchOutputsErrorRow(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleError(http.MethodPost, "/tasks/_search", http.StatusBadRequest, "parsing_exception", "unknown query")
+
+	cmd, err := NewSearchDocumentCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {"index": []string{"tasks"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if status, _ := rows[0].Get("status"); status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %v", http.StatusBadRequest, status)
+	}
+	if type_, _ := rows[0].Get("type"); type_ != "parsing_exception" {
+		t.Errorf("expected type parsing_exception, got %v", type_)
+	}
+}
+
+func TestSearchReturnsTransientErrorWithCommandRetries(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleError(http.MethodPost, "/tasks/_search", http.StatusServiceUnavailable, "unavailable_shards_exception", "no shard available")
+
+	cmd, err := NewSearchDocumentCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug:         {"index": []string{"tasks"}},
+		es_layers.EsConnectionSlug: {"command-retries": 2},
+	})
+
+	var responseError *helpers.ESResponseError
+	if !errors.As(err, &responseError) {
+		t.Fatalf("expected an ESResponseError, got %v", err)
+	}
+	if responseError.Status != http.StatusServiceUnavailable || !responseError.IsTransient() {
+		t.Errorf("expected a transient 503 error, got %v", responseError)
+	}
+	if len(rows) != 0 {
+		t.Errorf("expected no rows, got %d", len(rows))
+	}
+}
+
+func TestSearchClearsScrollWhenCancelled(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+
+	firstPage := estest.SearchResponse(map[string]interface{}{"title": "first"})
+	firstPage["_scroll_id"] = "scroll-1"
+	server.HandleJSON(http.MethodPost, "/tasks/_search", http.StatusOK, firstPage)
+
+	// the command is cancelled while it fetches the second page
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Handle(http.MethodPost, "/_search/scroll", func(w http.ResponseWriter, req *http.Request) {
+		cancel()
+		<-req.Context().Done()
+	})
+	server.HandleJSON(http.MethodDelete, "/_search/scroll/*", http.StatusOK, map[string]interface{}{
+		"succeeded": true,
+		"num_freed": 1,
+	})
+
+	cmd, err := NewSearchDocumentCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(ctx, cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"index":      []string{"tasks"},
+			"scroll_all": true,
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the command to be cancelled, got %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("expected the row of the first page, got %d rows", len(rows))
+	}
+
+	clears := server.RequestsTo(http.MethodDelete, "/_search/scroll/*")
+	if len(clears) != 1 {
+		t.Fatalf("expected 1 clear scroll request, got %d", len(clears))
+	}
+	if clears[0].Path != "/_search/scroll/scroll-1" {
+		t.Errorf("expected the scroll scroll-1 to be cleared, got %s", clears[0].Path)
+	}
+}
@@ -0,0 +1,407 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type TailCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &TailCommand{}
+
+func NewTailCommand() (*TailCommand, error) {
+	// rows are output as they arrive, which only works for row based output formats
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers(
+		settings.WithOutputParameterLayerOptions(
+			layers.WithDefaults(map[string]interface{}{
+				"output":            "json",
+				"output-as-objects": true,
+				"stream":            true,
+			})))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &TailCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"tail",
+			cmds.WithShort("Outputs the latest documents of a time-based index, optionally following new ones"),
+			cmds.WithLong(`
+Outputs the last documents of an index or data stream, ordered by a timestamp field,
+like tail does for files. With --follow, the index is polled for newer documents,
+which are output as they arrive, until interrupted.
+
+Documents are paged with search_after on the timestamp and the tiebreaker field. Without
+a tiebreaker, polls resume at the last seen timestamp, excluding the documents already
+output with that timestamp by index and id.
+
+   escuse-me documents tail --index logs-app --follow
+   escuse-me documents tail --index logs-app --query '{"term": {"level": "error"}}' --follow --poll_interval 5s
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index, data stream or alias to tail"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"timestamp_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Field the documents are ordered by"),
+					parameters.WithDefault("@timestamp"),
+				),
+				parameters.NewParameterDefinition(
+					"tiebreaker_field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Unique keyword or numeric field ordering documents with the same timestamp"),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only output documents matching this query (JSON)"),
+				),
+				parameters.NewParameterDefinition(
+					"lines",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of latest documents to output first"),
+					parameters.WithDefault(10),
+				),
+				parameters.NewParameterDefinition(
+					"follow",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Keep polling for new documents until interrupted"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"poll_interval",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Time to wait between polls when following (Go duration)"),
+					parameters.WithDefault("2s"),
+				),
+				parameters.NewParameterDefinition(
+					"page_size",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Maximum number of documents fetched per request when following"),
+					parameters.WithDefault(500),
+				),
+				parameters.NewParameterDefinition(
+					"output_hit_id",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Whether to include the hit ID and index in the output, as the _id and _index columns"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type TailSettings struct {
+	Index           string `glazed.parameter:"index"`
+	TimestampField  string `glazed.parameter:"timestamp_field"`
+	TiebreakerField string `glazed.parameter:"tiebreaker_field"`
+	Query           string `glazed.parameter:"query"`
+	Lines           int    `glazed.parameter:"lines"`
+	Follow          bool   `glazed.parameter:"follow"`
+	PollInterval    string `glazed.parameter:"poll_interval"`
+	PageSize        int    `glazed.parameter:"page_size"`
+	OutputHitID     bool   `glazed.parameter:"output_hit_id"`
+}
+
+type tailHit struct {
+	Index  string                 `json:"_index"`
+	ID     string                 `json:"_id"`
+	Source map[string]interface{} `json:"_source"`
+	Sort   []interface{}          `json:"sort"`
+}
+
+// tailCursor is the position of the last document output.
+type tailCursor struct {
+	// sort are the sort values of the last document, nil before the first document
+	sort []interface{}
+	// seenIDs are the ids, by index, of the documents output with the last timestamp,
+	// excluded from the next search when there is no tiebreaker field
+	seenIDs map[string]map[string]bool
+	// exclusive is set when the cursor is seeded from the newest document without
+	// outputting anything, to skip all the documents with its timestamp
+	exclusive bool
+}
+
+func (c *TailCommand) IsIdempotent() bool {
+	return true
+}
+
+// IsIdempotentWithSettings returns false with --follow: retries buffer the rows until the
+// command returns, which it never does when following.
+func (c *TailCommand) IsIdempotentWithSettings(parsedLayers *layers.ParsedLayers) (bool, error) {
+	s := &TailSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return false, err
+	}
+	return !s.Follow, nil
+}
+
+func (c *TailCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &TailSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	pollInterval, err := time.ParseDuration(s.PollInterval)
+	if err != nil {
+		return errors.Wrap(err, "invalid poll interval")
+	}
+	if s.PageSize <= 0 {
+		return errors.New("--page_size must be positive")
+	}
+
+	var query map[string]interface{}
+	if s.Query != "" {
+		if err := json.Unmarshal([]byte(s.Query), &query); err != nil {
+			return errors.Wrap(err, "could not parse query")
+		}
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	cursor := &tailCursor{seenIDs: map[string]map[string]bool{}}
+
+	// the latest documents are fetched in descending order, and output oldest first
+	if s.Lines > 0 {
+		hits, err := searchTail(ctx, es, s, query, "desc", cursor, s.Lines)
+		if err != nil {
+			return err
+		}
+		for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+			hits[i], hits[j] = hits[j], hits[i]
+		}
+		if _, err := emitTailHits(ctx, s, hits, cursor, gp); err != nil {
+			return err
+		}
+	} else if s.Follow {
+		// follow from the newest document, instead of from the oldest one
+		hits, err := searchTail(ctx, es, s, query, "desc", cursor, 1)
+		if err != nil {
+			return err
+		}
+		if len(hits) > 0 {
+			if len(hits[0].Sort) == 0 {
+				return errors.Errorf("hit %s has no sort values", hits[0].ID)
+			}
+			cursor.sort = hits[0].Sort
+			cursor.exclusive = true
+		}
+	}
+
+	if !s.Follow {
+		return nil
+	}
+
+	for {
+		hits, err := searchTail(ctx, es, s, query, "asc", cursor, s.PageSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		emitted, err := emitTailHits(ctx, s, hits, cursor, gp)
+		if err != nil {
+			return err
+		}
+
+		// a full page of new documents means more documents are probably waiting
+		if len(hits) == s.PageSize && emitted > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// searchTail fetches the documents following the cursor in the given order. Without
+// tiebreaker, the search is resumed at the last timestamp (included), since search_after
+// would skip documents with the same timestamp, and the documents already output with
+// that timestamp are excluded. A cursor seeded without output resumes after its timestamp.
+func searchTail(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	s *TailSettings,
+	query map[string]interface{},
+	order string,
+	cursor *tailCursor,
+	size int,
+) ([]tailHit, error) {
+	sort := []interface{}{
+		map[string]interface{}{s.TimestampField: map[string]interface{}{"order": order}},
+	}
+	if s.TiebreakerField != "" {
+		sort = append(sort, map[string]interface{}{s.TiebreakerField: map[string]interface{}{"order": order}})
+	}
+
+	filters := []interface{}{
+		map[string]interface{}{"exists": map[string]interface{}{"field": s.TimestampField}},
+	}
+	if query != nil {
+		filters = append(filters, query)
+	}
+
+	body := map[string]interface{}{
+		"size": size,
+		"sort": sort,
+	}
+	mustNot := []interface{}{}
+	if cursor.sort != nil {
+		if s.TiebreakerField != "" {
+			body["search_after"] = cursor.sort
+		} else {
+			op := "gte"
+			if cursor.exclusive {
+				op = "gt"
+			}
+			filters = append(filters, map[string]interface{}{
+				"range": map[string]interface{}{
+					s.TimestampField: map[string]interface{}{op: cursor.sort[0], "format": "epoch_millis"},
+				},
+			})
+			for index, ids := range cursor.seenIDs {
+				values := make([]string, 0, len(ids))
+				for id := range ids {
+					values = append(values, id)
+				}
+				mustNot = append(mustNot, map[string]interface{}{
+					"bool": map[string]interface{}{
+						"filter": []interface{}{
+							map[string]interface{}{"term": map[string]interface{}{"_index": index}},
+							map[string]interface{}{"ids": map[string]interface{}{"values": values}},
+						},
+					},
+				})
+			}
+		}
+	}
+	boolQuery := map[string]interface{}{"filter": filters}
+	if len(mustNot) > 0 {
+		boolQuery["must_not"] = mustNot
+	}
+	body["query"] = map[string]interface{}{"bool": boolQuery}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(s.Index),
+		es.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(responseBody); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []tailHit `json:"hits"`
+		} `json:"hits"`
+	}
+	// keep the sort values as numbers, long tiebreakers don't fit in a float64
+	decoder := json.NewDecoder(bytes.NewReader(responseBody))
+	decoder.UseNumber()
+	if err := decoder.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return response.Hits.Hits, nil
+}
+
+// emitTailHits outputs the hits not seen yet, advances the cursor and returns the number
+// of hits output.
+func emitTailHits(
+	ctx context.Context,
+	s *TailSettings,
+	hits []tailHit,
+	cursor *tailCursor,
+	gp middlewares.Processor,
+) (int, error) {
+	emitted := 0
+	for _, hit := range hits {
+		if len(hit.Sort) == 0 {
+			return emitted, errors.Errorf("hit %s has no sort values", hit.ID)
+		}
+		if s.TiebreakerField == "" {
+			if cursor.sort != nil && fmt.Sprint(hit.Sort[0]) == fmt.Sprint(cursor.sort[0]) {
+				if cursor.seenIDs[hit.Index][hit.ID] {
+					continue
+				}
+			} else {
+				cursor.seenIDs = map[string]map[string]bool{}
+			}
+			if cursor.seenIDs[hit.Index] == nil {
+				cursor.seenIDs[hit.Index] = map[string]bool{}
+			}
+			cursor.seenIDs[hit.Index][hit.ID] = true
+		}
+		cursor.sort = hit.Sort
+		cursor.exclusive = false
+
+		row := types.NewRow()
+		if s.OutputHitID {
+			row.Set("_index", hit.Index)
+			row.Set("_id", hit.ID)
+		}
+		for k, v := range hit.Source {
+			row.Set(k, v)
+		}
+		if err := gp.AddRow(ctx, row); err != nil {
+			return emitted, err
+		}
+		emitted++
+	}
+	return emitted, nil
+}
@@ -0,0 +1,142 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func tailSearchResponse(hits ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"hits": map[string]interface{}{"hits": hits},
+	}
+}
+
+func tailHitResponse(id string, timestamp int64, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"_index":  "logs",
+		"_id":     id,
+		"_source": map[string]interface{}{"message": message},
+		"sort":    []interface{}{timestamp},
+	}
+}
+
+// tailTimestampRange returns the range filter on @timestamp of a tail search body.
+func tailTimestampRange(t *testing.T, body []byte) map[string]interface{} {
+	var search struct {
+		Query struct {
+			Bool struct {
+				Filter []struct {
+					Range map[string]map[string]interface{} `json:"range"`
+				} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(body, &search); err != nil {
+		t.Fatal(err)
+	}
+	for _, filter := range search.Query.Bool.Filter {
+		if filter.Range != nil {
+			return filter.Range["@timestamp"]
+		}
+	}
+	return nil
+}
+
+func TestTailOutputsLatestLinesOldestFirst(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleJSON(http.MethodPost, "/logs/_search", http.StatusOK, tailSearchResponse(
+		tailHitResponse("2", 2000, "second"),
+		tailHitResponse("1", 1000, "first"),
+	))
+
+	cmd, err := NewTailCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {"index": "logs", "lines": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	for i, expected := range []string{"first", "second"} {
+		if message, _ := rows[i].Get("message"); message != expected {
+			t.Errorf("row %d: expected message %q, got %v", i, expected, message)
+		}
+	}
+}
+
+func TestTailFollowWithoutLinesStartsAtNewestDocument(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	searches := 0
+	server.Handle(http.MethodPost, "/logs/_search", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		searches++
+		switch searches {
+		case 1:
+			// the newest document, which is not output
+			estest.WriteJSON(w, http.StatusOK, tailSearchResponse(tailHitResponse("1", 1000, "old")))
+		case 2:
+			estest.WriteJSON(w, http.StatusOK, tailSearchResponse(tailHitResponse("2", 2000, "new")))
+		default:
+			cancel()
+			estest.WriteJSON(w, http.StatusOK, tailSearchResponse())
+		}
+	})
+
+	cmd, err := NewTailCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(ctx, cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"index":         "logs",
+			"lines":         0,
+			"follow":        true,
+			"poll_interval": "10ms",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if message, _ := rows[0].Get("message"); message != "new" {
+		t.Errorf("expected the new document, got %v", message)
+	}
+
+	requests := server.RequestsTo(http.MethodPost, "/logs/_search")
+	if len(requests) < 3 {
+		t.Fatalf("expected at least 3 searches, got %d", len(requests))
+	}
+	if r := tailTimestampRange(t, requests[0].Body); r != nil {
+		t.Errorf("expected the newest document to be searched without range, got %v", r)
+	}
+	// the first poll starts after the newest document, the next ones at the last output one
+	if r := tailTimestampRange(t, requests[1].Body); r["gt"] != float64(1000) {
+		t.Errorf("expected the first poll to start after 1000, got %v", r)
+	}
+	if r := tailTimestampRange(t, requests[2].Body); r["gte"] != float64(2000) {
+		t.Errorf("expected the second poll to start at 2000, got %v", r)
+	}
+}
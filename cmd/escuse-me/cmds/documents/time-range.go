@@ -0,0 +1,81 @@
+package documents
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// dateMathPattern matches Elasticsearch date math anchored on now, such as now-1h or
+	// now-1d/d.
+	dateMathPattern = regexp.MustCompile(`^now([+-]\d+[yMwdhHms])*(/[yMwdhHms])?$`)
+	// relativeDurationPattern matches durations such as 15m or 1d12h, meaning that long
+	// ago.
+	relativeDurationPattern = regexp.MustCompile(`^(\d+[yMwdhHms])+$`)
+	durationPartPattern     = regexp.MustCompile(`\d+[yMwdhHms]`)
+)
+
+// parseTimeBound parses a bound of a time range given as date math (now-1h), a
+// relative duration (1h, 2d12h, meaning now-1h and now-2d-12h), an RFC3339 timestamp or a
+// date (2006-01-02), and returns the value to use in a range query, along with the
+// absolute time if there is one.
+func parseTimeBound(s string) (string, *time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case dateMathPattern.MatchString(s):
+		return s, nil, nil
+	case relativeDurationPattern.MatchString(s):
+		return "now-" + strings.Join(durationPartPattern.FindAllString(s, -1), "-"), nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return s, &t, nil
+		}
+	}
+
+	return "", nil, errors.Errorf("invalid time %q, expected date math (now-1h), a duration (1h), an RFC3339 timestamp or a date (2006-01-02)", s)
+}
+
+// buildTimeRangeFilter returns a range query on field matching the documents from since
+// (included) until (excluded), either of which can be empty.
+func buildTimeRangeFilter(field string, since string, until string) (map[string]interface{}, error) {
+	if since == "" && until == "" {
+		return nil, nil
+	}
+	if field == "" {
+		return nil, errors.New("--since and --until require --time_field")
+	}
+
+	range_ := map[string]interface{}{}
+	var sinceTime, untilTime *time.Time
+	if since != "" {
+		v, t, err := parseTimeBound(since)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --since")
+		}
+		range_["gte"] = v
+		sinceTime = t
+	}
+	if until != "" {
+		v, t, err := parseTimeBound(until)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --until")
+		}
+		range_["lt"] = v
+		untilTime = t
+	}
+	if sinceTime != nil && untilTime != nil && !sinceTime.Before(*untilTime) {
+		return nil, errors.Errorf("--since %s is not before --until %s", since, until)
+	}
+
+	return map[string]interface{}{
+		"range": map[string]interface{}{
+			field: range_,
+		},
+	}, nil
+}
@@ -2,9 +2,8 @@ package cmds
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	layers2 "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -14,7 +13,6 @@ import (
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
-	"io"
 )
 
 type InfoCommand struct {
@@ -58,6 +56,10 @@ type InfoSettings struct {
 	Full bool `glazed.parameter:"full"`
 }
 
+func (i *InfoCommand) IsIdempotent() bool {
+	return true
+}
+
 func (i *InfoCommand) RunIntoGlazeProcessor(
 	ctx context.Context,
 	parsedLayers *layers.ParsedLayers,
@@ -76,43 +78,29 @@ func (i *InfoCommand) RunIntoGlazeProcessor(
 
 	gp.(*middlewares.TableProcessor).AddRowMiddleware(
 		row.NewReorderColumnOrderMiddleware(
-			[]string{"client_version", "version", "cluster_name"},
+			[]string{"client_version", "cluster_type", "version", "cluster_name"},
 		),
 	)
 
 	clientVersion := elasticsearch.Version
-	res, err := es.Info()
-	if err != nil {
-		return err
-	}
-
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Println(err)
-		}
-	}(res.Body)
-
-	// read all body
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return err
-	}
-
-	body_ := types.NewRow()
-	err = json.Unmarshal(body, &body_)
+	info, err := helpers.GetClusterInfo(ctx, es)
 	if err != nil {
 		return err
 	}
+	clusterType, version := helpers.ClusterTypeFromInfo(info)
 
+	body_ := types.NewRowFromMap(info)
 	if !s.Full {
-		version_, ok := body_.Get("version")
-		if !ok {
-			return errors.New("could not find version in response")
+		// the tagline is left out, it is the same for every cluster and misleading on OpenSearch
+		body_ = types.NewRow()
+		for _, k := range []string{"name", "cluster_name", "cluster_uuid"} {
+			if v, ok := info[k]; ok {
+				body_.Set(k, v)
+			}
 		}
-		version := version_.(map[string]interface{})
-		body_.Set("version", version["number"])
+		body_.Set("version", version)
 	}
+	body_.Set("cluster_type", clusterType)
 	body_.Set("client_version", clientVersion)
 
 	err = gp.AddRow(ctx, body_)
@@ -0,0 +1,301 @@
+package indices
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type CleanupCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &CleanupCommand{}
+
+func NewCleanupCommand() (*CleanupCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &CleanupCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"cleanup",
+			cmds.WithShort("Lists and deletes the indices of a pattern older than a given age"),
+			cmds.WithLong(`
+The 'cleanup' command implements retention for time-based indices: it lists the indices
+matching --index that are older than --older_than, and deletes them when --dry_run is
+turned off. One row is output per old index, with its age and the action taken.
+
+The age of an index is computed from its creation date by default. With --date_pattern,
+it is computed from the date in the index name instead, which is what matters when
+indices are created ahead of time or restored from snapshots. The pattern uses the
+tokens YYYY, MM, DD and HH, for example logs-YYYY.MM.DD.
+
+--older_than accepts days (30d) in addition to Go durations (12h).
+
+Examples:
+
+   escuse-me indices cleanup --index 'logs-*' --older_than 30d
+
+   escuse-me indices cleanup --index 'logs-*' --date_pattern 'logs-YYYY.MM.DD' \
+      --older_than 90d --dry_run=false
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index pattern to clean up"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"older_than",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Minimum age of the indices to delete (30d, 12h)"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"date_pattern",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Pattern of the index names, the age being computed from the date it contains (logs-YYYY.MM.DD)"),
+				),
+				parameters.NewParameterDefinition(
+					"dry_run",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only list the indices that would be deleted"),
+					parameters.WithDefault(true),
+				),
+				parameters.NewParameterDefinition(
+					"expand_wildcards",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Whether to expand wildcard expression to concrete indices that are open, closed or both."),
+					parameters.WithDefault("open"),
+					parameters.WithChoices("open", "closed", "hidden", "none", "all"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type CleanupSettings struct {
+	Index           string `glazed.parameter:"index"`
+	OlderThan       string `glazed.parameter:"older_than"`
+	DatePattern     string `glazed.parameter:"date_pattern"`
+	DryRun          bool   `glazed.parameter:"dry_run"`
+	ExpandWildcards string `glazed.parameter:"expand_wildcards"`
+}
+
+type indexAge struct {
+	Index   string
+	Created time.Time
+}
+
+func (c *CleanupCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &CleanupSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	olderThan, err := parseRetentionDuration(s.OlderThan)
+	if err != nil {
+		return err
+	}
+
+	var nameDate *indexNameDate
+	if s.DatePattern != "" {
+		nameDate, err = newIndexNameDate(s.DatePattern)
+		if err != nil {
+			return err
+		}
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	indices, err := resolveIndices(ctx, es, s.Index, s.ExpandWildcards)
+	if err != nil {
+		return err
+	}
+
+	var indexSettings map[string]map[string]interface{}
+	if nameDate == nil {
+		indexSettings, err = helpers.GetIndexSettings(ctx, es, s.Index)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	old := []indexAge{}
+	for _, index := range indices {
+		var created time.Time
+		if nameDate != nil {
+			var ok bool
+			created, ok = nameDate.Parse(index)
+			if !ok {
+				// indices not following the pattern are never deleted
+				continue
+			}
+		} else {
+			creationDate, _ := indexSettings[index]["index.creation_date"].(string)
+			millis, err := strconv.ParseInt(creationDate, 10, 64)
+			if err != nil {
+				return errors.Errorf("could not get creation date of %s", index)
+			}
+			created = time.UnixMilli(millis)
+		}
+		if now.Sub(created) >= olderThan {
+			old = append(old, indexAge{Index: index, Created: created})
+		}
+	}
+
+	sort.Slice(old, func(i, j int) bool {
+		return old[i].Created.Before(old[j].Created)
+	})
+
+	for _, i := range old {
+		action := "would delete"
+		if !s.DryRun {
+			if err := deleteIndex(ctx, es, i.Index); err != nil {
+				return errors.Wrapf(err, "could not delete %s", i.Index)
+			}
+			action = "deleted"
+		}
+
+		row := types.NewRow(
+			types.MRP("index", i.Index),
+			types.MRP("created", i.Created),
+			types.MRP("age_days", int(now.Sub(i.Created).Hours()/24)),
+			types.MRP("action", action),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseRetentionDuration parses a Go duration, also accepting days (30d).
+func parseRetentionDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.Errorf("invalid duration %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid duration %s", s)
+	}
+	return d, nil
+}
+
+// indexNameDate extracts the date from index names following a pattern like
+// logs-YYYY.MM.DD.
+type indexNameDate struct {
+	re *regexp.Regexp
+}
+
+var indexNameDateTokens = map[string]string{
+	"YYYY": `(?P<YYYY>\d{4})`,
+	"MM":   `(?P<MM>\d{2})`,
+	"DD":   `(?P<DD>\d{2})`,
+	"HH":   `(?P<HH>\d{2})`,
+}
+
+func newIndexNameDate(pattern string) (*indexNameDate, error) {
+	if !strings.Contains(pattern, "YYYY") {
+		return nil, errors.Errorf("date pattern %s contains no year (YYYY)", pattern)
+	}
+
+	expr := regexp.QuoteMeta(pattern)
+	for _, token := range []string{"YYYY", "MM", "DD", "HH"} {
+		expr = strings.Replace(expr, token, indexNameDateTokens[token], 1)
+	}
+	// * in the pattern matches any part of the name, as in index patterns
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil, err
+	}
+	return &indexNameDate{re: re}, nil
+}
+
+// Parse returns the date contained in the index name, in UTC. Missing month and day
+// default to the first.
+func (d *indexNameDate) Parse(index string) (time.Time, bool) {
+	match := d.re.FindStringSubmatch(index)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	values := map[string]int{"MM": 1, "DD": 1}
+	for i, name := range d.re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		v, err := strconv.Atoi(match[i])
+		if err != nil {
+			return time.Time{}, false
+		}
+		values[name] = v
+	}
+	if values["MM"] < 1 || values["MM"] > 12 || values["DD"] < 1 || values["DD"] > 31 || values["HH"] > 23 {
+		return time.Time{}, false
+	}
+
+	return time.Date(values["YYYY"], time.Month(values["MM"]), values["DD"], values["HH"], 0, 0, 0, time.UTC), true
+}
+
+func deleteIndex(ctx context.Context, es *elasticsearch.Client, index string) error {
+	res, err := es.Indices.Delete(
+		[]string{index},
+		es.Indices.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package indices
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
@@ -14,6 +15,7 @@ import (
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
 	"io"
+	"os"
 	"strings"
 )
 
@@ -52,9 +54,9 @@ func NewCloseIndexCommand() (*CloseIndexCommand, error) {
 				),
 				parameters.NewParameterDefinition(
 					"expand_wildcards",
-					parameters.ParameterTypeChoice,
+					parameters.ParameterTypeChoiceList,
 					parameters.WithHelp("Whether to expand wildcard expression to concrete indices that are open, closed or both."),
-					parameters.WithDefault("open"),
+					parameters.WithDefault([]string{"open"}),
 					parameters.WithChoices("open", "closed", "none", "all"),
 				),
 				parameters.NewParameterDefinition(
@@ -67,6 +69,12 @@ func NewCloseIndexCommand() (*CloseIndexCommand, error) {
 					parameters.ParameterTypeString,
 					parameters.WithHelp("Set the number of active shards to wait for before the operation returns."),
 				),
+				parameters.NewParameterDefinition(
+					"non_interactive",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Close the index without asking for confirmation"),
+					parameters.WithDefault(false),
+				),
 			),
 			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
 		),
@@ -79,6 +87,7 @@ type CloseIndexSettings struct {
 	ExpandWildcards     []string `glazed.parameter:"expand_wildcards"`
 	IgnoreUnavailable   bool     `glazed.parameter:"ignore_unavailable"`
 	WaitForActiveShards string   `glazed.parameter:"wait_for_active_shards"`
+	NonInteractive      bool     `glazed.parameter:"non_interactive"`
 }
 
 type CloseIndexResponse struct {
@@ -104,6 +113,18 @@ func (c *CloseIndexCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	ok, err := helpers.ConfirmAction(
+		os.Stderr,
+		fmt.Sprintf("Close %s? Closed indices can't be searched nor written to until reopened.", s.Index),
+		s.NonInteractive,
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("aborted")
+	}
+
 	options := []func(*esapi.IndicesCloseRequest){
 		es.Indices.Close.WithContext(ctx),
 	}
@@ -0,0 +1,189 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type IndicesCountCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &IndicesCountCommand{}
+
+func NewIndicesCountCommand() (*IndicesCountCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &IndicesCountCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"count",
+			cmds.WithShort("Counts documents per concrete index of an index pattern"),
+			cmds.WithLong(`
+The 'count' command resolves an index pattern (for example logs-*) to its concrete
+indices and counts the documents of each of them, optionally restricted by a query.
+Rows are sorted by decreasing count.
+
+Examples:
+
+   escuse-me indices count --index 'logs-*'
+
+   escuse-me indices count --index 'logs-*' --query '{"term": {"level": "error"}}'
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index pattern to resolve"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Only count documents matching this query (JSON string)"),
+				),
+				parameters.NewParameterDefinition(
+					"expand_wildcards",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Whether to expand wildcard expression to concrete indices that are open, closed or both."),
+					parameters.WithDefault("open"),
+					parameters.WithChoices("open", "closed", "hidden", "none", "all"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type IndicesCountSettings struct {
+	Index           string `glazed.parameter:"index"`
+	Query           string `glazed.parameter:"query"`
+	ExpandWildcards string `glazed.parameter:"expand_wildcards"`
+}
+
+type indexCount struct {
+	Index string
+	Count int64
+}
+
+func (c *IndicesCountCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *IndicesCountCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &IndicesCountSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	var query map[string]interface{}
+	if s.Query != "" {
+		if err := json.Unmarshal([]byte(s.Query), &query); err != nil {
+			return errors.Wrap(err, "invalid query JSON")
+		}
+	}
+
+	indices, err := resolveIndices(ctx, es, s.Index, s.ExpandWildcards)
+	if err != nil {
+		return err
+	}
+
+	counts := []indexCount{}
+	for _, index := range indices {
+		count, err := helpers.CountDocuments(ctx, es, index, query)
+		if err != nil {
+			return errors.Wrapf(err, "could not count documents in %s", index)
+		}
+		counts = append(counts, indexCount{Index: index, Count: count})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count == counts[j].Count {
+			return counts[i].Index < counts[j].Index
+		}
+		return counts[i].Count > counts[j].Count
+	})
+
+	for _, c := range counts {
+		row := types.NewRow(
+			types.MRP("index", c.Index),
+			types.MRP("count", c.Count),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveIndices returns the names of the concrete indices matching the given pattern,
+// sorted by name.
+func resolveIndices(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	pattern string,
+	expandWildcards string,
+) ([]string, error) {
+	res, err := es.Indices.Get(
+		[]string{pattern},
+		es.Indices.Get.WithContext(ctx),
+		es.Indices.Get.WithExpandWildcards(expandWildcards),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	response := map[string]interface{}{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	ret := make([]string, 0, len(response))
+	for index := range response {
+		ret = append(ret, index)
+	}
+	sort.Strings(ret)
+
+	return ret, nil
+}
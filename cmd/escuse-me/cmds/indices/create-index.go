@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"io"
 
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/escuse-me/pkg/mappings"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
@@ -15,6 +17,7 @@ import (
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 )
 
 type CreateIndexCommand struct {
@@ -37,6 +40,29 @@ func NewCreateIndexCommand() (*CreateIndexCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"create",
 			cmds.WithShort("Creates a new index"),
+			cmds.WithLong(`
+Creates a new index with the given settings, mappings and aliases.
+
+Indices with many fields can fail to be created, or later fail to index documents, on
+the default limit of 1000 fields (index.mapping.total_fields.limit). Set the limit with
+--total_fields_limit, or use --auto_total_fields_limit to derive it from the number of
+fields in --mappings and in the mappings of --fields_source_index (typically the index a
+migration copies documents from), with a safety margin.
+
+--index_template accepts a composable index template body (index_patterns, template,
+priority, composed_of, ...). The settings, mappings and aliases of its template section
+are used for the new index, unless overridden by --settings, --mappings and --aliases.
+With --as_template, a composable index template named --index is created from it instead
+of an index, so that the same file serves ad-hoc index creation and template
+registration.
+
+Examples:
+
+   escuse-me indices create --index products-v2 --mappings mappings.json \
+      --auto_total_fields_limit --fields_source_index products-v1
+
+   escuse-me indices create --index products-template --index_template products.yaml --as_template
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"index",
@@ -59,6 +85,33 @@ func NewCreateIndexCommand() (*CreateIndexCommand, error) {
 					parameters.ParameterTypeFile,
 					parameters.WithHelp("JSON/YAML file containing index aliases"),
 				),
+				parameters.NewParameterDefinition(
+					"index_template",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON/YAML file containing a composable index template body, providing the settings, mappings and aliases"),
+				),
+				parameters.NewParameterDefinition(
+					"as_template",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Create a composable index template named --index instead of an index (requires index_patterns in --index_template)"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"total_fields_limit",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Set index.mapping.total_fields.limit on the new index"),
+				),
+				parameters.NewParameterDefinition(
+					"auto_total_fields_limit",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Raise index.mapping.total_fields.limit if the mappings have more fields than the default limit allows"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"fields_source_index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Also count the fields of this index's mappings for --auto_total_fields_limit"),
+				),
 				parameters.NewParameterDefinition(
 					"wait_for_active_shards",
 					parameters.ParameterTypeString,
@@ -75,6 +128,11 @@ type CreateIndexSettings struct {
 	Settings            *IndexSettings         `glazed.parameter:"settings,from_json"`
 	Mappings            map[string]interface{} `glazed.parameter:"mappings"`
 	Aliases             *map[string]Alias      `glazed.parameter:"aliases,from_json"`
+	Template            map[string]interface{} `glazed.parameter:"index_template"`
+	AsTemplate          bool                   `glazed.parameter:"as_template"`
+	TotalFieldsLimit    *int                   `glazed.parameter:"total_fields_limit"`
+	AutoTotalFields     bool                   `glazed.parameter:"auto_total_fields_limit"`
+	FieldsSourceIndex   string                 `glazed.parameter:"fields_source_index"`
 	WaitForActiveShards string                 `glazed.parameter:"wait_for_active_shards"`
 }
 
@@ -116,8 +174,80 @@ func (c *CreateIndexCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	if s.TotalFieldsLimit != nil && s.AutoTotalFields {
+		return errors.New("--total_fields_limit and --auto_total_fields_limit are mutually exclusive")
+	}
+	if s.FieldsSourceIndex != "" && !s.AutoTotalFields {
+		return errors.New("--fields_source_index requires --auto_total_fields_limit")
+	}
+
+	if s.AsTemplate {
+		if s.Template == nil {
+			return errors.New("--as_template requires --index_template")
+		}
+		if _, ok := s.Template["index_patterns"]; !ok {
+			return errors.New("--index_template must contain index_patterns to be created with --as_template")
+		}
+	}
+	templateSection := map[string]interface{}{}
+	if s.Template != nil {
+		if section, ok := s.Template["template"]; ok {
+			section_, ok := section.(map[string]interface{})
+			if !ok {
+				return errors.New("the template section of --index_template must be an object")
+			}
+			templateSection = section_
+		}
+	}
+	if s.Mappings == nil {
+		if mappings_, ok := templateSection["mappings"].(map[string]interface{}); ok {
+			s.Mappings = mappings_
+		}
+	}
+
+	totalFieldsLimit := s.TotalFieldsLimit
+	if s.AutoTotalFields {
+		fieldCount := mappings.CountFields(s.Mappings)
+		if s.FieldsSourceIndex != "" {
+			sourceMappings, err := helpers.GetIndexMappings(ctx, es, s.FieldsSourceIndex)
+			if err != nil {
+				return errors.Wrapf(err, "could not get mappings of %s", s.FieldsSourceIndex)
+			}
+			for _, mappings_ := range sourceMappings {
+				fieldCount = max(fieldCount, mappings.CountFields(mappings_))
+			}
+		}
+		if limit, ok := mappings.SuggestTotalFieldsLimit(fieldCount); ok {
+			log.Info().Int("fields", fieldCount).Int("limit", limit).Msg("raising index.mapping.total_fields.limit")
+			totalFieldsLimit = &limit
+		}
+	}
+
 	createIndexRequest := map[string]interface{}{}
-	if s.Settings != nil {
+	for _, k := range []string{"settings", "aliases"} {
+		if v, ok := templateSection[k]; ok {
+			createIndexRequest[k] = v
+		}
+	}
+	if totalFieldsLimit != nil {
+		settings_ := map[string]interface{}{}
+		if templateSettings, ok := createIndexRequest["settings"].(map[string]interface{}); ok && s.Settings == nil {
+			for k, v := range templateSettings {
+				settings_[k] = v
+			}
+		}
+		if s.Settings != nil {
+			b, err := json.Marshal(s.Settings)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(b, &settings_); err != nil {
+				return err
+			}
+		}
+		settings_["index.mapping.total_fields.limit"] = *totalFieldsLimit
+		createIndexRequest["settings"] = settings_
+	} else if s.Settings != nil {
 		createIndexRequest["settings"] = s.Settings
 	}
 	if s.Mappings != nil {
@@ -127,17 +257,39 @@ func (c *CreateIndexCommand) RunIntoGlazeProcessor(
 		createIndexRequest["aliases"] = s.Aliases
 	}
 
-	requestBody, err := json.Marshal(createIndexRequest)
-	if err != nil {
-		return err
-	}
+	var res *esapi.Response
+	if s.AsTemplate {
+		templateRequest := map[string]interface{}{}
+		for k, v := range s.Template {
+			templateRequest[k] = v
+		}
+		templateRequest["template"] = createIndexRequest
 
-	res, err := es.Indices.Create(
-		s.Index,
-		es.Indices.Create.WithBody(bytes.NewReader(requestBody)),
-	)
-	if err != nil {
-		return err
+		requestBody, err := json.Marshal(templateRequest)
+		if err != nil {
+			return err
+		}
+		res, err = es.Indices.PutIndexTemplate(
+			s.Index,
+			bytes.NewReader(requestBody),
+			es.Indices.PutIndexTemplate.WithContext(ctx),
+			es.Indices.PutIndexTemplate.WithCreate(true),
+		)
+		if err != nil {
+			return err
+		}
+	} else {
+		requestBody, err := json.Marshal(createIndexRequest)
+		if err != nil {
+			return err
+		}
+		res, err = es.Indices.Create(
+			s.Index,
+			es.Indices.Create.WithBody(bytes.NewReader(requestBody)),
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	defer func(Body io.ReadCloser) {
@@ -3,6 +3,7 @@ package indices
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
@@ -13,6 +14,7 @@ import (
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
 	"io"
+	"os"
 	"strings"
 )
 
@@ -36,6 +38,21 @@ func NewDeleteIndexCommand() (*DeleteIndexCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"delete",
 			cmds.WithShort("Deletes an index"),
+			cmds.WithLong(`
+Deletes the given indices, after listing them and asking for confirmation.
+
+Wildcard expressions and _all are refused unless --allow_wildcards is given, since a
+mistyped pattern can delete far more than intended. The indices they resolve to are
+listed before asking for confirmation.
+
+Use --non_interactive (or the global --yes) to skip the confirmation, for example in scripts.
+
+Examples:
+
+   escuse-me indices delete --index products-v1
+
+   escuse-me indices delete --index 'logs-2023-*' --allow_wildcards
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"index",
@@ -61,6 +78,18 @@ func NewDeleteIndexCommand() (*DeleteIndexCommand, error) {
 					parameters.ParameterTypeBool,
 					parameters.WithHelp("Whether specified concrete indices should be ignored when unavailable (missing or closed)"),
 				),
+				parameters.NewParameterDefinition(
+					"allow_wildcards",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Allow wildcard expressions and _all in --index"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"non_interactive",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Delete the indices without asking for confirmation"),
+					parameters.WithDefault(false),
+				),
 			),
 			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
 		),
@@ -72,6 +101,13 @@ type DeleteIndexSettings struct {
 	AllowNoIndices    bool     `glazed.parameter:"allow_no_indices"`
 	ExpandWildcards   []string `glazed.parameter:"expand_wildcards"`
 	IgnoreUnavailable bool     `glazed.parameter:"ignore_unavailable"`
+	AllowWildcards    bool     `glazed.parameter:"allow_wildcards"`
+	NonInteractive    bool     `glazed.parameter:"non_interactive"`
+}
+
+// isWildcardIndex returns true if index can match more indices than its name.
+func isWildcardIndex(index string) bool {
+	return strings.Contains(index, "*") || index == "_all"
 }
 
 func (c *DeleteIndexCommand) RunIntoGlazeProcessor(
@@ -89,8 +125,37 @@ func (c *DeleteIndexCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	for _, index := range s.Indices {
+		if isWildcardIndex(index) && !s.AllowWildcards {
+			return errors.Errorf("refusing to delete %s, which contains a wildcard, without --allow_wildcards", index)
+		}
+	}
+
+	if !s.NonInteractive && !helpers.AssumeYes {
+		indices, err := resolveIndices(ctx, es, strings.Join(s.Indices, ","), strings.Join(s.ExpandWildcards, ","))
+		if err != nil {
+			return errors.Wrap(err, "could not resolve indices")
+		}
+		if len(indices) == 0 {
+			return errors.New("no indices match --index")
+		}
+
+		_, _ = fmt.Fprintln(os.Stderr, "The following indices will be deleted:")
+		for _, index := range indices {
+			_, _ = fmt.Fprintf(os.Stderr, "  %s\n", index)
+		}
+		ok, err := helpers.ConfirmAction(os.Stderr, fmt.Sprintf("Delete %d indices?", len(indices)), s.NonInteractive)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted")
+		}
+	}
+
 	deleteIndexResponse, err := es.Indices.Delete(
 		s.Indices,
+		es.Indices.Delete.WithContext(ctx),
 		es.Indices.Delete.WithAllowNoIndices(s.AllowNoIndices),
 		es.Indices.Delete.WithExpandWildcards(strings.Join(s.ExpandWildcards, ",")),
 		es.Indices.Delete.WithIgnoreUnavailable(s.IgnoreUnavailable),
@@ -0,0 +1,249 @@
+package indices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type ExplainUnassignedCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ExplainUnassignedCommand{}
+
+func NewExplainUnassignedCommand() (*ExplainUnassignedCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ExplainUnassignedCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"explain-unassigned",
+			cmds.WithShort("Explains why shards are unassigned"),
+			cmds.WithLong(`
+Finds the unassigned shards with the cat shards API and runs the cluster allocation
+explain API for each of them, outputting one row per shard with the reason it became
+unassigned, the allocation decider preventing its allocation on most nodes and the
+explanation given by that decider.
+
+Replicas of the same shard can't be told apart by the allocation explain API, they are
+explained once. Nothing is output if every shard is assigned.
+
+   escuse-me indices explain-unassigned
+   escuse-me indices explain-unassigned --index 'logs-*'
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Only consider the shards of these indices"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ExplainUnassignedSettings struct {
+	Index []string `glazed.parameter:"index"`
+}
+
+type allocationExplanation struct {
+	Index               string `json:"index"`
+	Shard               int    `json:"shard"`
+	Primary             bool   `json:"primary"`
+	CanAllocate         string `json:"can_allocate"`
+	AllocateExplanation string `json:"allocate_explanation"`
+	UnassignedInfo      struct {
+		Reason string `json:"reason"`
+		At     string `json:"at"`
+	} `json:"unassigned_info"`
+	NodeAllocationDecisions []struct {
+		NodeName string `json:"node_name"`
+		Deciders []struct {
+			Decider     string `json:"decider"`
+			Decision    string `json:"decision"`
+			Explanation string `json:"explanation"`
+		} `json:"deciders"`
+	} `json:"node_allocation_decisions"`
+}
+
+func (c *ExplainUnassignedCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *ExplainUnassignedCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ExplainUnassignedSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	shards, err := getCatShards(ctx, es, s.Index)
+	if err != nil {
+		return err
+	}
+
+	type unassignedShard struct {
+		index   string
+		shard   int
+		primary bool
+	}
+	unassigned := []unassignedShard{}
+	seen := map[unassignedShard]bool{}
+	for _, shard := range shards {
+		if shardNode(shard) != unassignedNode {
+			continue
+		}
+		shardNumber, err := strconv.Atoi(shard.Shard)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse shard number of %s", shard.Index)
+		}
+		u := unassignedShard{index: shard.Index, shard: shardNumber, primary: shard.PriRep == "p"}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		unassigned = append(unassigned, u)
+	}
+	sort.Slice(unassigned, func(i, j int) bool {
+		if unassigned[i].index != unassigned[j].index {
+			return unassigned[i].index < unassigned[j].index
+		}
+		if unassigned[i].shard != unassigned[j].shard {
+			return unassigned[i].shard < unassigned[j].shard
+		}
+		return unassigned[i].primary
+	})
+
+	for _, shard := range unassigned {
+		row := types.NewRow(
+			types.MRP("index", shard.index),
+			types.MRP("shard", shard.shard),
+			types.MRP("primary", shard.primary),
+		)
+
+		explanation, err := explainAllocation(ctx, es, shard.index, shard.shard, shard.primary)
+		if err != nil {
+			// the shard may have been assigned in the meantime
+			row.Set("error", err.Error())
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+			continue
+		}
+
+		decider, deciderExplanation := mainDecider(explanation)
+		if deciderExplanation == "" {
+			deciderExplanation = explanation.AllocateExplanation
+		}
+		row.Set("reason", explanation.UnassignedInfo.Reason)
+		row.Set("unassigned_at", explanation.UnassignedInfo.At)
+		row.Set("can_allocate", explanation.CanAllocate)
+		row.Set("decider", decider)
+		row.Set("explanation", deciderExplanation)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mainDecider returns the decider saying no on the most nodes, along with its explanation,
+// which is usually what prevents the allocation of the shard.
+func mainDecider(explanation *allocationExplanation) (string, string) {
+	counts := map[string]int{}
+	explanations := map[string]string{}
+	for _, node := range explanation.NodeAllocationDecisions {
+		for _, decider := range node.Deciders {
+			if !strings.EqualFold(decider.Decision, "NO") {
+				continue
+			}
+			counts[decider.Decider]++
+			if _, ok := explanations[decider.Decider]; !ok {
+				explanations[decider.Decider] = decider.Explanation
+			}
+		}
+	}
+
+	ret := ""
+	for decider, count := range counts {
+		if ret == "" || count > counts[ret] || (count == counts[ret] && decider < ret) {
+			ret = decider
+		}
+	}
+	return ret, explanations[ret]
+}
+
+func explainAllocation(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+	shard int,
+	primary bool,
+) (*allocationExplanation, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"index":   index,
+		"shard":   shard,
+		"primary": primary,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Cluster.AllocationExplain(
+		es.Cluster.AllocationExplain.WithContext(ctx),
+		es.Cluster.AllocationExplain.WithBody(bytes.NewReader(requestBody)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	ret := &allocationExplanation{}
+	if err := json.Unmarshal(body, ret); err != nil {
+		return nil, errors.Wrap(err, "could not parse allocation explanation")
+	}
+	return ret, nil
+}
@@ -0,0 +1,180 @@
+package indices
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/escuse-me/pkg/mappings"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+// fieldMappingColumns are the mapping parameters output in their own column, the other
+// parameters being grouped in the params column.
+var fieldMappingColumns = map[string]bool{
+	"type":            true,
+	"analyzer":        true,
+	"search_analyzer": true,
+	"normalizer":      true,
+	"fields":          true,
+	"properties":      true,
+}
+
+type FieldMappingCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &FieldMappingCommand{}
+
+func NewFieldMappingCommand() (*FieldMappingCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &FieldMappingCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"field-mapping",
+			cmds.WithShort("Explains the mapping of a single field"),
+			cmds.WithLong(`
+Prints the effective mapping of --field in each index matching --index: its type,
+analyzers, multi-fields and other parameters, the dynamic setting it inherits, the nested
+field containing it, and whether it is searchable and aggregatable according to the field
+capabilities API. Alias fields are resolved to the field they point to, given in the
+alias_path column.
+
+--field is a dotted path, going through object and nested fields as well as
+multi-fields. This helps finding out why a field can't be sorted or aggregated on,
+without dumping the whole mapping.
+
+Examples:
+
+   escuse-me indices field-mapping --index products --field name.keyword
+
+   escuse-me indices field-mapping --index 'logs-*' --field http.response.status_code
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index or index pattern"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"field",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Dotted path of the field"),
+					parameters.WithRequired(true),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type FieldMappingSettings struct {
+	Index string `glazed.parameter:"index"`
+	Field string `glazed.parameter:"field"`
+}
+
+func (c *FieldMappingCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &FieldMappingSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	indexMappings, err := helpers.GetIndexMappings(ctx, es, s.Index)
+	if err != nil {
+		return errors.Wrapf(err, "could not get mappings of %s", s.Index)
+	}
+	indices := make([]string, 0, len(indexMappings))
+	for index := range indexMappings {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	for _, index := range indices {
+		row := types.NewRow(
+			types.MRP("index", index),
+			types.MRP("field", s.Field),
+		)
+
+		field, ok := mappings.LookupField(indexMappings[index], s.Field)
+		if !ok {
+			row.Set("mapped", false)
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+			continue
+		}
+
+		row.Set("mapped", true)
+		if field.AliasOf != "" {
+			row.Set("alias_path", field.Path)
+		}
+		row.Set("type", field.Type())
+		row.Set("dynamic", field.Dynamic)
+		row.Set("nested_path", field.NestedPath)
+		row.Set("multi_field_of", field.MultiFieldOf)
+		row.Set("runtime", field.Runtime)
+		for _, k := range []string{"analyzer", "search_analyzer", "normalizer"} {
+			v, _ := field.Mapping[k].(string)
+			row.Set(k, v)
+		}
+
+		multiFields := map[string]interface{}{}
+		if fields, ok := field.Mapping["fields"].(map[string]interface{}); ok {
+			for name, multiField := range fields {
+				multiField_, _ := multiField.(map[string]interface{})
+				multiFields[name] = multiField_["type"]
+			}
+		}
+		row.Set("multi_fields", multiFields)
+
+		params := map[string]interface{}{}
+		for k, v := range field.Mapping {
+			if !fieldMappingColumns[k] {
+				params[k] = v
+			}
+		}
+		row.Set("params", params)
+
+		fieldCaps, err := helpers.GetFieldCaps(ctx, es, []string{index}, []string{s.Field})
+		if err != nil {
+			return errors.Wrapf(err, "could not get field capabilities of %s", index)
+		}
+		searchable, aggregatable := false, false
+		for _, caps := range fieldCaps.Fields[s.Field] {
+			searchable = searchable || caps.Searchable
+			aggregatable = aggregatable || caps.Aggregatable
+		}
+		row.Set("searchable", searchable)
+		row.Set("aggregatable", aggregatable)
+
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
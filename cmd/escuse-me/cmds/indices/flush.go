@@ -2,7 +2,6 @@ package indices
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
@@ -103,6 +102,7 @@ func (c *FlushCommand) RunIntoGlazeProcessor(
 	}
 
 	flushResponse, err := es.Indices.Flush(
+		es.Indices.Flush.WithContext(ctx),
 		es.Indices.Flush.WithIndex(s.Indices...),
 		es.Indices.Flush.WithAllowNoIndices(s.AllowNoIndices),
 		es.Indices.Flush.WithExpandWildcards(strings.Join(s.ExpandWildcards, ",")),
@@ -129,10 +129,10 @@ func (c *FlushCommand) RunIntoGlazeProcessor(
 		return gp.AddRow(ctx, row)
 	}
 
-	responseRow := types.NewRow()
-	if err := json.Unmarshal(body, &responseRow); err != nil {
+	row, err := shardsSummaryRow(body)
+	if err != nil {
 		return err
 	}
 
-	return gp.AddRow(ctx, responseRow)
+	return gp.AddRow(ctx, row)
 }
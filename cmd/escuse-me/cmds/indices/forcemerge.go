@@ -0,0 +1,226 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type ForcemergeCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ForcemergeCommand{}
+
+func NewForcemergeCommand() (*ForcemergeCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ForcemergeCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"forcemerge",
+			cmds.WithShort("Force merges the segments of one or more indices"),
+			cmds.WithLong(`
+Force merges the segments of the given indices, typically once they are no longer
+written to, to reduce the number of segments and reclaim the space of deleted documents.
+
+Force merges of large indices can take hours. With --wait_for_completion=false, the
+merge runs as a task that is polled every --poll_interval until it completes, instead of
+keeping a single request open. Failed polls are retried with an exponential backoff up to
+--max_poll_interval, the command giving up after --max_poll_failures consecutive failures.
+
+Examples:
+
+   escuse-me indices forcemerge --index logs-2023-01 --max_num_segments 1
+
+   escuse-me indices forcemerge --index products --only_expunge_deletes \
+      --wait_for_completion=false --poll_interval 30s
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Comma-separated list of indices to force merge"),
+				),
+				parameters.NewParameterDefinition(
+					"max_num_segments",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of segments each shard is merged into"),
+				),
+				parameters.NewParameterDefinition(
+					"only_expunge_deletes",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only merge segments containing deleted documents"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"flush",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Flush the indices after the merge"),
+					parameters.WithDefault(true),
+				),
+				parameters.NewParameterDefinition(
+					"allow_no_indices",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Whether to ignore if a wildcard expression matches no indices"),
+					parameters.WithDefault(true),
+				),
+				parameters.NewParameterDefinition(
+					"expand_wildcards",
+					parameters.ParameterTypeChoiceList,
+					parameters.WithHelp("Whether to expand wildcard expression to concrete indices that are open, closed or both"),
+					parameters.WithDefault([]string{"open"}),
+					parameters.WithChoices("open", "closed", "none", "all"),
+				),
+				parameters.NewParameterDefinition(
+					"ignore_unavailable",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Whether specified concrete indices should be ignored when unavailable (missing or closed)"),
+				),
+				parameters.NewParameterDefinition(
+					"wait_for_completion",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Wait for the merge in a single request, otherwise run it as a task and poll it"),
+					parameters.WithDefault(true),
+				),
+				parameters.NewParameterDefinition(
+					"poll_interval",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Interval between polls of the merge task with --wait_for_completion=false"),
+					parameters.WithDefault("10s"),
+				),
+				parameters.NewParameterDefinition(
+					"max_poll_interval",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Maximum interval between polls of the merge task when backing off after failed polls"),
+					parameters.WithDefault("5m"),
+				),
+				parameters.NewParameterDefinition(
+					"max_poll_failures",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of consecutive failed polls of the merge task after which to give up"),
+					parameters.WithDefault(10),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ForcemergeSettings struct {
+	Indices            []string `glazed.parameter:"index"`
+	MaxNumSegments     *int     `glazed.parameter:"max_num_segments"`
+	OnlyExpungeDeletes bool     `glazed.parameter:"only_expunge_deletes"`
+	Flush              bool     `glazed.parameter:"flush"`
+	AllowNoIndices     bool     `glazed.parameter:"allow_no_indices"`
+	ExpandWildcards    []string `glazed.parameter:"expand_wildcards"`
+	IgnoreUnavailable  bool     `glazed.parameter:"ignore_unavailable"`
+	WaitForCompletion  bool     `glazed.parameter:"wait_for_completion"`
+	PollInterval       string   `glazed.parameter:"poll_interval"`
+	MaxPollInterval    string   `glazed.parameter:"max_poll_interval"`
+	MaxPollFailures    int      `glazed.parameter:"max_poll_failures"`
+}
+
+func (c *ForcemergeCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ForcemergeSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	if s.MaxNumSegments != nil && s.OnlyExpungeDeletes {
+		return errors.New("--max_num_segments and --only_expunge_deletes are mutually exclusive")
+	}
+	polling, err := helpers.ParseTaskPolling(s.PollInterval, s.MaxPollInterval, s.MaxPollFailures)
+	if err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.IndicesForcemergeRequest){
+		es.Indices.Forcemerge.WithContext(ctx),
+		es.Indices.Forcemerge.WithIndex(s.Indices...),
+		es.Indices.Forcemerge.WithOnlyExpungeDeletes(s.OnlyExpungeDeletes),
+		es.Indices.Forcemerge.WithFlush(s.Flush),
+		es.Indices.Forcemerge.WithAllowNoIndices(s.AllowNoIndices),
+		es.Indices.Forcemerge.WithExpandWildcards(strings.Join(s.ExpandWildcards, ",")),
+		es.Indices.Forcemerge.WithIgnoreUnavailable(s.IgnoreUnavailable),
+		es.Indices.Forcemerge.WithWaitForCompletion(s.WaitForCompletion),
+	}
+	if s.MaxNumSegments != nil {
+		options = append(options, es.Indices.Forcemerge.WithMaxNumSegments(*s.MaxNumSegments))
+	}
+
+	res, err := es.Indices.Forcemerge(options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	if !s.WaitForCompletion {
+		var taskResponse struct {
+			Task string `json:"task"`
+		}
+		if err := json.Unmarshal(body, &taskResponse); err != nil {
+			return err
+		}
+		if taskResponse.Task == "" {
+			return errors.New("could not find task in forcemerge response")
+		}
+
+		response, err := helpers.WaitForTask(ctx, es, taskResponse.Task, polling)
+		if err != nil {
+			return err
+		}
+		body, err = json.Marshal(response)
+		if err != nil {
+			return err
+		}
+	}
+
+	row, err := shardsSummaryRow(body)
+	if err != nil {
+		return err
+	}
+
+	return gp.AddRow(ctx, row)
+}
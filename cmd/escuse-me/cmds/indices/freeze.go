@@ -0,0 +1,362 @@
+package indices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Frozen indices were deprecated in Elasticsearch 7.14 and the freeze API removed in
+// 8.0, in favor of searchable snapshots mounted on the frozen tier.
+const (
+	freezeDeprecatedMajorVersion = 7
+	freezeRemovedMajorVersion    = 8
+)
+
+type FreezeIndexCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &FreezeIndexCommand{}
+
+func NewFreezeIndexCommand() (*FreezeIndexCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &FreezeIndexCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"freeze",
+			cmds.WithShort("Freezes an index, or mounts a snapshot of it on the frozen tier"),
+			cmds.WithLong(`
+Freezes --index, making it read-only and keeping its data structures out of the heap
+until it is searched, to keep rarely searched data at a lower cost.
+
+The freeze API only exists up to Elasticsearch 7 (where it is deprecated since 7.14).
+On later versions, and with --repository and --snapshot, the index is instead mounted
+from a snapshot containing it as a partially cached searchable snapshot, which is what
+the frozen tier is made of. The mounted index is named --mounted_index, by default the
+name of the index in the snapshot.
+
+Examples:
+
+   escuse-me indices freeze --index logs-2021-01
+
+   escuse-me indices freeze --index logs-2023-01 --repository backups \
+      --snapshot snapshot-2023-02-01 --mounted_index frozen-logs-2023-01
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Name of the index to freeze"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"repository",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Snapshot repository to mount the index from, instead of freezing it"),
+				),
+				parameters.NewParameterDefinition(
+					"snapshot",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Snapshot containing the index to mount, instead of freezing it"),
+				),
+				parameters.NewParameterDefinition(
+					"mounted_index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Name of the mounted index (defaults to the name of the index in the snapshot)"),
+				),
+				parameters.NewParameterDefinition(
+					"non_interactive",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Freeze the index without asking for confirmation"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type FreezeIndexSettings struct {
+	Index          string `glazed.parameter:"index"`
+	Repository     string `glazed.parameter:"repository"`
+	Snapshot       string `glazed.parameter:"snapshot"`
+	MountedIndex   string `glazed.parameter:"mounted_index"`
+	NonInteractive bool   `glazed.parameter:"non_interactive"`
+}
+
+func (c *FreezeIndexCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &FreezeIndexSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	if isWildcardIndex(s.Index) {
+		return errors.Errorf("refusing to freeze %s, which contains a wildcard", s.Index)
+	}
+	if (s.Repository == "") != (s.Snapshot == "") {
+		return errors.New("--repository and --snapshot must be given together")
+	}
+	if s.MountedIndex != "" && s.Snapshot == "" {
+		return errors.New("--mounted_index requires --repository and --snapshot")
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	mount := s.Snapshot != ""
+	if !mount {
+		if err := checkFreezeSupported(ctx, es); err != nil {
+			return err
+		}
+	}
+
+	prompt := fmt.Sprintf("Freeze %s? Frozen indices are read-only until unfrozen.", s.Index)
+	if mount {
+		prompt = fmt.Sprintf("Mount %s from %s/%s on the frozen tier?", s.Index, s.Repository, s.Snapshot)
+	}
+	ok, err := helpers.ConfirmAction(os.Stderr, prompt, s.NonInteractive)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("aborted")
+	}
+
+	var body []byte
+	if mount {
+		body, err = mountFrozenIndex(ctx, es, s)
+	} else {
+		body, err = freezeIndex(ctx, es, s.Index)
+	}
+	if err != nil {
+		return err
+	}
+
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	responseRow := types.NewRow()
+	if err := json.Unmarshal(body, &responseRow); err != nil {
+		return err
+	}
+
+	return gp.AddRow(ctx, responseRow)
+}
+
+// checkFreezeSupported returns an error if the cluster doesn't have the freeze API
+// anymore, and warns if it is deprecated.
+func checkFreezeSupported(ctx context.Context, es *elasticsearch.Client) error {
+	clusterType, version, err := helpers.DetectClusterType(ctx, es)
+	if err != nil {
+		return errors.Wrap(err, "could not detect cluster version")
+	}
+	if clusterType != helpers.ClusterTypeElasticsearch {
+		return errors.Errorf("%s doesn't support frozen indices", clusterType)
+	}
+	major, err := helpers.MajorVersion(version)
+	if err != nil {
+		return err
+	}
+	if major >= freezeRemovedMajorVersion {
+		return errors.Errorf(
+			"the freeze API was removed in Elasticsearch %d.0 (cluster is %s), mount a snapshot of the index on the frozen tier with --repository and --snapshot instead",
+			freezeRemovedMajorVersion, version,
+		)
+	}
+	if major == freezeDeprecatedMajorVersion {
+		log.Warn().Str("version", version).
+			Msg("frozen indices are deprecated, consider searchable snapshots on the frozen tier instead")
+	}
+	return nil
+}
+
+// freezeIndex calls the freeze API, which the client doesn't provide anymore.
+func freezeIndex(ctx context.Context, es *elasticsearch.Client, index string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/"+index+"/_freeze", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Transport.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	return io.ReadAll(res.Body)
+}
+
+// mountFrozenIndex mounts the index from a snapshot as a partially cached searchable
+// snapshot, which is how indices are stored on the frozen tier.
+func mountFrozenIndex(ctx context.Context, es *elasticsearch.Client, s *FreezeIndexSettings) ([]byte, error) {
+	mountRequest := map[string]interface{}{
+		"index": s.Index,
+	}
+	if s.MountedIndex != "" {
+		mountRequest["renamed_index"] = s.MountedIndex
+	}
+	requestBody, err := json.Marshal(mountRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.SearchableSnapshotsMount(
+		s.Repository,
+		s.Snapshot,
+		bytes.NewReader(requestBody),
+		es.SearchableSnapshotsMount.WithContext(ctx),
+		es.SearchableSnapshotsMount.WithStorage("shared_cache"),
+		es.SearchableSnapshotsMount.WithWaitForCompletion(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	return io.ReadAll(res.Body)
+}
+
+type UnfreezeIndexCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &UnfreezeIndexCommand{}
+
+func NewUnfreezeIndexCommand() (*UnfreezeIndexCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &UnfreezeIndexCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"unfreeze",
+			cmds.WithShort("Unfreezes a frozen index"),
+			cmds.WithLong(`
+Unfreezes --index, making it writable again. Elasticsearch 8 still supports unfreezing
+indices frozen on earlier versions.
+
+Searchable snapshots mounted with freeze --repository are regular indices that can't be
+unfrozen. Restore them from their snapshot instead.
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Name of the index to unfreeze"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"wait_for_active_shards",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Set the number of active shards to wait for before the operation returns."),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type UnfreezeIndexSettings struct {
+	Index               string `glazed.parameter:"index"`
+	WaitForActiveShards string `glazed.parameter:"wait_for_active_shards"`
+}
+
+func (c *UnfreezeIndexCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &UnfreezeIndexSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	if isWildcardIndex(s.Index) {
+		return errors.Errorf("refusing to unfreeze %s, which contains a wildcard", s.Index)
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.IndicesUnfreezeRequest){
+		es.Indices.Unfreeze.WithContext(ctx),
+	}
+	if s.WaitForActiveShards != "" {
+		options = append(options, es.Indices.Unfreeze.WithWaitForActiveShards(s.WaitForActiveShards))
+	}
+
+	res, err := es.Indices.Unfreeze(s.Index, options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	responseRow := types.NewRow()
+	if err := json.Unmarshal(body, &responseRow); err != nil {
+		return err
+	}
+
+	return gp.AddRow(ctx, responseRow)
+}
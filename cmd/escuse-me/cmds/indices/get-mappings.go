@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
 	"github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	layers2 "github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -104,6 +105,10 @@ type Index struct {
 
 type MappingsResponse = *orderedmap.OrderedMap[string, Index]
 
+func (i *IndicesGetMappingCommand) IsIdempotent() bool {
+	return true
+}
+
 func (i *IndicesGetMappingCommand) RunIntoGlazeProcessor(
 	ctx context.Context,
 	parsedLayers *layers2.ParsedLayers,
@@ -133,7 +138,7 @@ func (i *IndicesGetMappingCommand) RunIntoGlazeProcessor(
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println(err)
+			es_cmds.PrintSummary(err)
 		}
 	}(res.Body)
 
@@ -193,7 +198,7 @@ func (i *IndicesGetMappingCommand) RunIntoGlazeProcessor(
 	return nil
 }
 
-func flattenMappingField(name string, v map[string]interface{}) []map[string]interface{} {
+func flattenMappingField(name string, v_ map[string]interface{}) []map[string]interface{} {
 	row := map[string]interface{}{
 		"field": name,
 	}
@@ -202,7 +207,7 @@ func flattenMappingField(name string, v map[string]interface{}) []map[string]int
 		row,
 	}
 
-	for k, v := range v {
+	for k, v := range v_ {
 		if k == "fields" {
 			fields := []string{}
 			for k3 := range v.(map[string]interface{}) {
@@ -213,6 +218,9 @@ func flattenMappingField(name string, v map[string]interface{}) []map[string]int
 			for k3, v3 := range v.(map[string]interface{}) {
 				ret = append(ret, flattenMappingField(name+"."+k3, v3.(map[string]interface{}))...)
 			}
+		} else if k == "path" && v_["type"] == "alias" {
+			// alias fields only consist of the path of the field they point to
+			row["alias_path"] = v
 		} else {
 			row[k] = v
 		}
@@ -32,6 +32,16 @@ func AddToRootCommand(rootCmd *cobra.Command) error {
 	}
 	indicesCommand.AddCommand(indicesStatsCmd)
 
+	indicesCountCommand, err := NewIndicesCountCommand()
+	if err != nil {
+		return err
+	}
+	indicesCountCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(indicesCountCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(indicesCountCmd)
+
 	indicesGetMappingCommand, err := NewIndicesGetMappingCommand()
 	if err != nil {
 		return err
@@ -92,5 +102,125 @@ func AddToRootCommand(rootCmd *cobra.Command) error {
 	}
 	indicesCommand.AddCommand(cloneIndexCmd)
 
+	reindexCommand, err := NewReindexCommand()
+	if err != nil {
+		return err
+	}
+	reindexCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(reindexCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(reindexCmd)
+
+	cleanupCommand, err := NewCleanupCommand()
+	if err != nil {
+		return err
+	}
+	cleanupCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(cleanupCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(cleanupCmd)
+
+	reindexPlanCommand, err := NewReindexPlanCommand()
+	if err != nil {
+		return err
+	}
+	reindexPlanCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(reindexPlanCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(reindexPlanCmd)
+
+	mergeMappingsCommand, err := NewMergeMappingsCommand()
+	if err != nil {
+		return err
+	}
+	mergeMappingsCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(mergeMappingsCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(mergeMappingsCmd)
+
+	shardAllocationCommand, err := NewShardAllocationCommand()
+	if err != nil {
+		return err
+	}
+	shardAllocationCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(shardAllocationCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(shardAllocationCmd)
+
+	flushCommand, err := NewFlushCommand()
+	if err != nil {
+		return err
+	}
+	flushCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(flushCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(flushCmd)
+
+	refreshCommand, err := NewRefreshCommand()
+	if err != nil {
+		return err
+	}
+	refreshCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(refreshCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(refreshCmd)
+
+	forcemergeCommand, err := NewForcemergeCommand()
+	if err != nil {
+		return err
+	}
+	forcemergeCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(forcemergeCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(forcemergeCmd)
+
+	freezeCommand, err := NewFreezeIndexCommand()
+	if err != nil {
+		return err
+	}
+	freezeCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(freezeCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(freezeCmd)
+
+	unfreezeCommand, err := NewUnfreezeIndexCommand()
+	if err != nil {
+		return err
+	}
+	unfreezeCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(unfreezeCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(unfreezeCmd)
+
+	fieldMappingCommand, err := NewFieldMappingCommand()
+	if err != nil {
+		return err
+	}
+	fieldMappingCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(fieldMappingCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(fieldMappingCmd)
+
+	explainUnassignedCommand, err := NewExplainUnassignedCommand()
+	if err != nil {
+		return err
+	}
+	explainUnassignedCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(explainUnassignedCommand)
+	if err != nil {
+		return err
+	}
+	indicesCommand.AddCommand(explainUnassignedCmd)
+
 	return nil
 }
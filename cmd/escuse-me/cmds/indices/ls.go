@@ -3,7 +3,9 @@ package indices
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
 	"github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	layers2 "github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -37,6 +39,20 @@ func NewIndicesListCommand() (*IndicesListCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"ls",
 			cmds.WithShort("Prints the list of available ES indices"),
+			cmds.WithLong(`
+Prints the indices of the cluster using the cat indices API. Without --columns, only
+health, status and index are output, or every default column of the API with --full.
+
+--columns selects the columns fetched from the API (its h parameter), --sort the columns
+the API sorts on (its s parameter, with an optional :desc suffix) and --bytes the unit
+sizes are printed in.
+
+Examples:
+
+   escuse-me indices ls --columns index,docs.count,store.size,pri,rep
+
+   escuse-me indices ls --columns index,store.size --sort store.size:desc --bytes mb
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"full",
@@ -44,6 +60,22 @@ func NewIndicesListCommand() (*IndicesListCommand, error) {
 					parameters.WithHelp("Prints the full version response"),
 					parameters.WithDefault(false),
 				),
+				parameters.NewParameterDefinition(
+					"columns",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Columns to fetch and print (e.g. index,docs.count,store.size,pri,rep)"),
+				),
+				parameters.NewParameterDefinition(
+					"sort",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Columns to sort on, suffixed with :desc for descending order (e.g. store.size:desc)"),
+				),
+				parameters.NewParameterDefinition(
+					"bytes",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Unit to print sizes in"),
+					parameters.WithChoices("b", "kb", "mb", "gb"),
+				),
 			),
 			cmds.WithLayersList(
 				glazedParameterLayer,
@@ -54,7 +86,14 @@ func NewIndicesListCommand() (*IndicesListCommand, error) {
 }
 
 type IndicesListSettings struct {
-	Full bool `glazed.parameter:"full"`
+	Full    bool     `glazed.parameter:"full"`
+	Columns []string `glazed.parameter:"columns"`
+	Sort    []string `glazed.parameter:"sort"`
+	Bytes   string   `glazed.parameter:"bytes"`
+}
+
+func (i *IndicesListCommand) IsIdempotent() bool {
+	return true
 }
 
 func (i *IndicesListCommand) RunIntoGlazeProcessor(
@@ -72,13 +111,28 @@ func (i *IndicesListCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	columnOrder := []string{"health", "status", "index", "uuid", "pri", "rep", "docs.count", "docs.deleted", "store.size", "pri.store.size"}
+	if len(s.Columns) > 0 {
+		columnOrder = s.Columns
+	}
 	gp.(*middlewares.TableProcessor).AddRowMiddleware(
-		row.NewReorderColumnOrderMiddleware(
-			[]string{"health", "status", "index", "uuid", "pri", "rep", "docs.count", "docs.deleted", "store.size", "pri.store.size"},
-		),
+		row.NewReorderColumnOrderMiddleware(columnOrder),
 	)
 
-	res, err := es.Cat.Indices(es.Cat.Indices.WithFormat("json"))
+	options := []func(*esapi.CatIndicesRequest){
+		es.Cat.Indices.WithFormat("json"),
+	}
+	if len(s.Columns) > 0 {
+		options = append(options, es.Cat.Indices.WithH(s.Columns...))
+	}
+	if len(s.Sort) > 0 {
+		options = append(options, es.Cat.Indices.WithS(s.Sort...))
+	}
+	if s.Bytes != "" {
+		options = append(options, es.Cat.Indices.WithBytes(s.Bytes))
+	}
+
+	res, err := es.Cat.Indices(options...)
 	if err != nil {
 		return err
 	}
@@ -86,7 +140,7 @@ func (i *IndicesListCommand) RunIntoGlazeProcessor(
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println(err)
+			es_cmds.PrintSummary(err)
 		}
 	}(res.Body)
 
@@ -96,13 +150,19 @@ func (i *IndicesListCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	// unknown columns in --columns or --sort are reported as errors
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return err
+	}
+
 	body_ := []types.Row{}
 	err = json.Unmarshal(body, &body_)
 	if err != nil {
 		return err
 	}
 	for _, index := range body_ {
-		if !s.Full {
+		// the columns requested with --columns are output as returned
+		if !s.Full && len(s.Columns) == 0 {
 			health_, _ := index.Get("health")
 			status_, _ := index.Get("status")
 			index_, _ := index.Get("index")
@@ -0,0 +1,136 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/escuse-me/pkg/mappings"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type MergeMappingsCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &MergeMappingsCommand{}
+
+func NewMergeMappingsCommand() (*MergeMappingsCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &MergeMappingsCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"merge-mappings",
+			cmds.WithShort("Merges the mappings of several indices into mappings for a single index"),
+			cmds.WithLong(`
+Fetches the mappings of the --index indices and merges them into mappings holding the
+fields of all of them, to create the destination index of a reindex of several indices
+into one.
+
+A field mapped with different types, or with different parameters that can't be
+updated (analyzer, format, index, ...), can't be merged: the command then fails, listing
+each conflicting field along with the index mapping it differently from the indices
+before it in name order. Otherwise, the merged mappings are output as a single row, and
+written to --mappings_file as JSON.
+
+Examples:
+
+   escuse-me indices merge-mappings --index logs-2023-*
+
+   escuse-me indices merge-mappings --index logs-2023-01,logs-2023-02 --mappings_file mappings.json
+   escuse-me indices reindex-plan --source_index logs-2023-01 --dest_index logs-2023 \
+      --mappings mappings.json
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Indices whose mappings are merged, with wildcards"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"mappings_file",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Write the merged mappings as JSON to this file"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type MergeMappingsSettings struct {
+	Index        []string `glazed.parameter:"index"`
+	MappingsFile string   `glazed.parameter:"mappings_file"`
+}
+
+func (c *MergeMappingsCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *MergeMappingsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &MergeMappingsSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	index := strings.Join(s.Index, ",")
+	indexMappings, err := helpers.GetIndexMappings(ctx, es, index)
+	if err != nil {
+		return errors.Wrapf(err, "could not get mappings of %s", index)
+	}
+	if len(indexMappings) == 0 {
+		return errors.Errorf("no index matches %s", index)
+	}
+
+	merged, conflicts := mappings.MergeMappings(indexMappings)
+	if len(conflicts) > 0 {
+		lines := make([]string, 0, len(conflicts))
+		for _, conflict := range conflicts {
+			lines = append(lines, "  "+conflict.String())
+		}
+		return errors.Errorf("the mappings of %s have %d conflicts:\n%s", index, len(conflicts), strings.Join(lines, "\n"))
+	}
+
+	if s.MappingsFile != "" {
+		b, err := json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(s.MappingsFile, append(b, '\n'), 0644); err != nil {
+			return errors.Wrapf(err, "could not write %s", s.MappingsFile)
+		}
+	}
+
+	indices := sortedIndexNames(indexMappings)
+	return gp.AddRow(ctx, types.NewRow(
+		types.MRP("indices", indices),
+		types.MRP("fields", mappings.CountFields(merged)),
+		types.MRP("mappings", merged),
+	))
+}
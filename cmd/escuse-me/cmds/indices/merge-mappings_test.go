@@ -0,0 +1,72 @@
+package indices
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/escuse-me/pkg/mappings"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func TestMergeMappings(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleMappings("logs-1", map[string]interface{}{
+		"properties": map[string]interface{}{
+			"message": map[string]interface{}{"type": "text"},
+		},
+	})
+
+	cmd, err := NewMergeMappingsCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {"index": []string{"logs-1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	merged, _ := rows[0].Get("mappings")
+	if n := mappings.CountFields(merged.(map[string]interface{})); n != 1 {
+		t.Errorf("expected 1 field, got %d", n)
+	}
+}
+
+func TestMergeMappingsFailsOnConflicts(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleJSON(http.MethodGet, "/logs-*/_mapping", http.StatusOK, map[string]interface{}{
+		"logs-1": map[string]interface{}{"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword"},
+			},
+		}},
+		"logs-2": map[string]interface{}{"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"status": map[string]interface{}{"type": "long"},
+			},
+		}},
+	})
+
+	cmd, err := NewMergeMappingsCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {"index": []string{"logs-*"}},
+	})
+	if err == nil {
+		t.Fatalf("expected the conflict to fail the command, got %d rows", len(rows))
+	}
+	if !strings.Contains(err.Error(), "logs-2") || !strings.Contains(err.Error(), "status") {
+		t.Errorf("expected the conflict on status in logs-2 to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// internalSettingPrefixes are the settings ES sets on an index itself, which can't be
+// set when creating a new index.
+var internalSettingPrefixes = []string{
+	"index.uuid",
+	"index.creation_date",
+	"index.provided_name",
+	"index.version.",
+	"index.history.uuid",
+	"index.resize.",
+	"index.shrink.",
+	"index.routing.allocation.initial_recovery.",
+	"index.blocks.",
+	"index.frozen",
+	"index.search.throttled",
+	"index.verified_before_close",
+	"index.lifecycle.indexing_complete",
+}
+
+type ReindexPlanCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ReindexPlanCommand{}
+
+func NewReindexPlanCommand() (*ReindexPlanCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ReindexPlanCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"reindex-plan",
+			cmds.WithShort("Generates the requests migrating an index to new mappings, without running them"),
+			cmds.WithLong(`
+Generates the plan migrating --source_index to the mappings of --mappings, without
+changing anything on the cluster:
+
+  1. create_index creates --dest_index with the mappings and the settings of the source
+     index, leaving out the settings ES sets itself (uuid, creation date, version, ...)
+  2. reindex copies the documents of the source index into the new index
+  3. swap_aliases atomically moves the aliases of the source index (or --alias) to the
+     new index, keeping their filter and routing
+
+Each step is output as a row with the method, path and body of its request. With
+--output_dir, the bodies are written to numbered files instead, along with the escuse-me
+command running each step, so that the plan can be reviewed and committed to version
+control before being run.
+
+When --source_index is an alias pointing to several indices, the command fails unless
+--allow_multi_source is set, in which case the documents of all of them are reindexed
+into the new index, whose settings are taken from the first index in name order. The
+aliases are then removed from all the source indices.
+
+Examples:
+
+   escuse-me indices reindex-plan --source_index products-v1 --dest_index products-v2 \
+      --mappings mappings.json --output json
+
+   escuse-me indices reindex-plan --source_index products-v1 --dest_index products-v2 \
+      --mappings mappings.json --alias products --output_dir migrations/products-v2
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"source_index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Index to migrate"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"dest_index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Name of the new index"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"mappings",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON/YAML file containing the mappings of the new index"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"alias",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Aliases to move to the new index (default: the aliases of the source index)"),
+				),
+				parameters.NewParameterDefinition(
+					"allow_multi_source",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Allow --source_index to be an alias pointing to several indices, reindexing all of them"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"output_dir",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Directory to write the request bodies of the plan to"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ReindexPlanSettings struct {
+	SourceIndex      string                 `glazed.parameter:"source_index"`
+	DestIndex        string                 `glazed.parameter:"dest_index"`
+	Mappings         map[string]interface{} `glazed.parameter:"mappings"`
+	Aliases          []string               `glazed.parameter:"alias"`
+	AllowMultiSource bool                   `glazed.parameter:"allow_multi_source"`
+	OutputDir        string                 `glazed.parameter:"output_dir"`
+}
+
+type reindexPlanStep struct {
+	Name   string
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+func (c *ReindexPlanCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *ReindexPlanCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ReindexPlanSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	if isWildcardIndex(s.SourceIndex) || strings.Contains(s.SourceIndex, ",") {
+		return errors.New("--source_index must be a single index")
+	}
+	if s.SourceIndex == s.DestIndex {
+		return errors.New("--dest_index must be different from --source_index")
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	indexSettings, err := helpers.GetIndexSettings(ctx, es, s.SourceIndex)
+	if err != nil {
+		return errors.Wrapf(err, "could not get settings of %s", s.SourceIndex)
+	}
+	// the settings are returned under the concrete index names, which differ from
+	// --source_index if it is an alias
+	sourceIndices := sortedIndexNames(indexSettings)
+	if len(sourceIndices) == 0 {
+		return errors.Errorf("%s doesn't match any index", s.SourceIndex)
+	}
+	if len(sourceIndices) > 1 {
+		if !s.AllowMultiSource {
+			return errors.Errorf(
+				"%s points to %d indices (%s), use --allow_multi_source to reindex all of them into %s",
+				s.SourceIndex, len(sourceIndices), strings.Join(sourceIndices, ", "), s.DestIndex)
+		}
+		log.Warn().
+			Strs("indices", sourceIndices).
+			Str("settings_from", sourceIndices[0]).
+			Msg("reindexing several indices, the settings of the new index are taken from the first one")
+	}
+	sourceSettings := indexSettings[sourceIndices[0]]
+
+	sourceAliases, err := helpers.GetIndexAliases(ctx, es, strings.Join(sourceIndices, ","))
+	if err != nil {
+		return errors.Wrapf(err, "could not get aliases of %s", s.SourceIndex)
+	}
+
+	steps := []reindexPlanStep{
+		{
+			Name:   "create_index",
+			Method: http.MethodPut,
+			Path:   "/" + s.DestIndex,
+			Body: map[string]interface{}{
+				"settings": carriedOverSettings(sourceSettings),
+				"mappings": s.Mappings,
+			},
+		},
+	}
+
+	reindexBody, err := buildReindexBody(&ReindexSettings{
+		SourceIndex: sourceIndices,
+		DestIndex:   s.DestIndex,
+		OpType:      "index",
+		Conflicts:   "abort",
+	})
+	if err != nil {
+		return err
+	}
+	steps = append(steps, reindexPlanStep{
+		Name:   "reindex",
+		Method: http.MethodPost,
+		Path:   "/_reindex",
+		Body:   reindexBody,
+	})
+
+	aliasActions := buildAliasSwapActions(sourceIndices, s.DestIndex, s.Aliases, sourceAliases)
+	if len(aliasActions) > 0 {
+		steps = append(steps, reindexPlanStep{
+			Name:   "swap_aliases",
+			Method: http.MethodPost,
+			Path:   "/_aliases",
+			Body:   map[string]interface{}{"actions": aliasActions},
+		})
+	} else {
+		log.Info().Strs("indices", sourceIndices).Msg("no aliases to move to the new index")
+	}
+
+	if s.OutputDir != "" {
+		if err := os.MkdirAll(s.OutputDir, 0755); err != nil {
+			return errors.Wrapf(err, "could not create %s", s.OutputDir)
+		}
+	}
+
+	for i, step := range steps {
+		row := types.NewRow(
+			types.MRP("step", i+1),
+			types.MRP("name", step.Name),
+			types.MRP("method", step.Method),
+			types.MRP("path", step.Path),
+		)
+
+		if s.OutputDir == "" {
+			row.Set("body", step.Body)
+		} else {
+			file := filepath.Join(s.OutputDir, fmt.Sprintf("%d-%s.json", i+1, strings.ReplaceAll(step.Name, "_", "-")))
+			b, err := json.MarshalIndent(step.Body, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, append(b, '\n'), 0644); err != nil {
+				return errors.Wrapf(err, "could not write %s", file)
+			}
+			row.Set("file", file)
+			row.Set("command", fmt.Sprintf("escuse-me request --method %s --path %s --body-file %s", step.Method, step.Path, file))
+		}
+
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// carriedOverSettings returns the flat settings of the source index that can be set on
+// a new index.
+func carriedOverSettings(sourceSettings map[string]interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for k, v := range sourceSettings {
+		internal := false
+		for _, prefix := range internalSettingPrefixes {
+			if k == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(k, prefix)) {
+				internal = true
+				break
+			}
+		}
+		if !internal {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// buildAliasSwapActions returns the actions atomically moving aliases from the source
+// indices to destIndex, keeping their definition on the first source index having them.
+// sourceAliases are the aliases of each source index. All the aliases of the source
+// indices are moved if aliases is empty.
+func buildAliasSwapActions(
+	sourceIndices []string,
+	destIndex string,
+	aliases []string,
+	sourceAliases map[string]map[string]map[string]interface{},
+) []interface{} {
+	if len(aliases) == 0 {
+		allAliases := map[string]bool{}
+		for _, index := range sourceIndices {
+			for alias := range sourceAliases[index] {
+				allAliases[alias] = true
+			}
+		}
+		aliases = sortedIndexNames(allAliases)
+	}
+
+	actions := []interface{}{}
+	for _, alias := range aliases {
+		add := map[string]interface{}{
+			"index": destIndex,
+			"alias": alias,
+		}
+		// aliases given with --alias that don't point to a source index yet are
+		// only added
+		removes := []interface{}{}
+		for _, index := range sourceIndices {
+			definition, ok := sourceAliases[index][alias]
+			if !ok {
+				continue
+			}
+			if len(removes) == 0 {
+				for k, v := range definition {
+					add[k] = v
+				}
+			}
+			removes = append(removes, map[string]interface{}{
+				"remove": map[string]interface{}{
+					"index": index,
+					"alias": alias,
+				},
+			})
+		}
+		actions = append(actions, map[string]interface{}{"add": add})
+		actions = append(actions, removes...)
+	}
+	return actions
+}
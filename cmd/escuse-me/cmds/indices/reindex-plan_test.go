@@ -0,0 +1,183 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func TestReindexPlanMovesAliases(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleJSON(http.MethodGet, "/logs/_settings", http.StatusOK, map[string]interface{}{
+		"logs-1": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"index.number_of_shards": "3",
+				"index.uuid":             "abc",
+			},
+		},
+	})
+	server.HandleAliases(map[string][]string{"logs-1": {"logs", "logs-read"}})
+
+	cmd, err := NewReindexPlanCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"source_index": "logs",
+			"dest_index":   "logs-2",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(rows))
+	}
+	for i, expected := range []string{"create_index", "reindex", "swap_aliases"} {
+		if name, _ := rows[i].Get("name"); name != expected {
+			t.Errorf("step %d: expected %s, got %v", i+1, expected, name)
+		}
+	}
+
+	body, _ := rows[2].Get("body")
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var swap struct {
+		Actions []map[string]map[string]interface{} `json:"actions"`
+	}
+	if err := json.Unmarshal(b, &swap); err != nil {
+		t.Fatal(err)
+	}
+	// each alias is added to the new index and removed from the old one
+	if len(swap.Actions) != 4 {
+		t.Fatalf("expected 4 alias actions, got %s", b)
+	}
+	for i, alias := range []string{"logs", "logs", "logs-read", "logs-read"} {
+		for _, action := range swap.Actions[i] {
+			if action["alias"] != alias {
+				t.Errorf("action %d: expected alias %s, got %v", i, alias, action["alias"])
+			}
+		}
+	}
+
+	// the plan is only output, nothing is changed on the cluster
+	for _, request := range server.Requests() {
+		if request.Method != http.MethodGet {
+			t.Errorf("unexpected %s %s", request.Method, request.Path)
+		}
+	}
+}
+
+func handleTwoLogsIndices(server *estest.Server) {
+	server.HandleJSON(http.MethodGet, "/logs/_settings", http.StatusOK, map[string]interface{}{
+		"logs-1": map[string]interface{}{
+			"settings": map[string]interface{}{"index.number_of_shards": "3"},
+		},
+		"logs-2": map[string]interface{}{
+			"settings": map[string]interface{}{"index.number_of_shards": "1"},
+		},
+	})
+	server.HandleAliases(map[string][]string{
+		"logs-1": {"logs"},
+		"logs-2": {"logs"},
+	})
+}
+
+func TestReindexPlanReindexesAllIndicesOfAnAlias(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	handleTwoLogsIndices(server)
+
+	cmd, err := NewReindexPlanCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"source_index":       "logs",
+			"dest_index":         "logs-3",
+			"allow_multi_source": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(rows))
+	}
+
+	body, _ := rows[1].Get("body")
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reindex struct {
+		Source struct {
+			Index []string `json:"index"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(b, &reindex); err != nil {
+		t.Fatal(err)
+	}
+	if len(reindex.Source.Index) != 2 || reindex.Source.Index[0] != "logs-1" || reindex.Source.Index[1] != "logs-2" {
+		t.Errorf("expected to reindex logs-1 and logs-2, got %s", b)
+	}
+
+	body, _ = rows[2].Get("body")
+	b, err = json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var swap struct {
+		Actions []map[string]map[string]interface{} `json:"actions"`
+	}
+	if err := json.Unmarshal(b, &swap); err != nil {
+		t.Fatal(err)
+	}
+	// the alias is added to the new index and removed from both old ones
+	if len(swap.Actions) != 3 {
+		t.Fatalf("expected 3 alias actions, got %s", b)
+	}
+	if add := swap.Actions[0]["add"]; add["index"] != "logs-3" || add["alias"] != "logs" {
+		t.Errorf("expected logs to be added to logs-3, got %v", swap.Actions[0])
+	}
+	for i, index := range []string{"logs-1", "logs-2"} {
+		remove := swap.Actions[i+1]["remove"]
+		if remove["index"] != index || remove["alias"] != "logs" {
+			t.Errorf("expected logs to be removed from %s, got %v", index, swap.Actions[i+1])
+		}
+	}
+}
+
+func TestReindexPlanRequiresAllowMultiSource(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	handleTwoLogsIndices(server)
+
+	cmd, err := NewReindexPlanCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"source_index": "logs",
+			"dest_index":   "logs-3",
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "logs-1, logs-2") {
+		t.Errorf("expected an error listing logs-1 and logs-2, got %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("expected no steps, got %d", len(rows))
+	}
+}
@@ -0,0 +1,665 @@
+package indices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+// defaultReindexPollInterval is the poll interval of --structured_progress without
+// --poll_interval.
+const defaultReindexPollInterval = "10s"
+
+// generateIDsScript resets the document id so that ES assigns a new one in the destination index.
+const generateIDsScript = "ctx._id = null;"
+
+type ReindexCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ReindexCommand{}
+
+func NewReindexCommand() (*ReindexCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ReindexCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"reindex",
+			cmds.WithShort("Copies documents from one or more source indices into a destination index"),
+			cmds.WithLong(`
+The 'reindex' command copies documents from the source indices into the destination index,
+optionally filtering them with a query and transforming them with a painless script.
+
+Source document ids are preserved by default. When merging several source indices that
+share ids into a single destination, use --generate_ids to let ES assign new ids, otherwise
+documents with the same id overwrite each other.
+
+Note that --generate_ids can't be combined with --op_type create: create only prevents
+overwriting documents that already exist in the destination, which requires stable ids.
+
+Complex migration scripts can be stored server-side and referenced by id with
+--reindex_script_id, which can't be combined with an inline --script. Parameters are passed
+to either kind of script with --script_params.
+
+With --remote_host, the documents are copied from the source indices of another cluster.
+The remote host has to be whitelisted in the reindex.remote.whitelist setting of the
+destination cluster (e.g. reindex.remote.whitelist: "old-cluster:9200"). Reindexing from
+a remote cluster can't be sliced.
+
+--dest_version_type external keeps the version of the source documents, only overwriting
+destination documents with an older version, so that re-running a reindex into an existing
+index doesn't overwrite newer documents. Combine it with --conflicts proceed to skip them.
+
+--dest_routing controls the routing of the destination documents:
+  keep (default) keeps the source routing, discard removes it, and =<value> sets it to <value>.
+
+Reindexing large indices can take hours. With --poll_interval, the reindex runs as a task
+that is polled until it completes, instead of keeping a single request open. Failed polls
+are retried with an exponential backoff up to --max_poll_interval, the command giving up
+after --max_poll_failures consecutive failures.
+
+With --structured_progress, a row is output for each poll of the task instead of the
+final response, with its document counts, batches, throttling and running time, the last
+row having completed set. It polls every 10s unless --poll_interval is given.
+
+The reindex task keeps running when the command is interrupted. --resume_task reattaches
+to it by its task id (output with --structured_progress, or by 'escuse-me tasks list
+--actions *reindex'), polling it like --poll_interval without starting a new reindex.
+The source and destination flags are then ignored.
+
+Examples:
+
+   escuse-me indices reindex --source_index products-v1 --dest_index products-v2
+
+   escuse-me indices reindex --source_index logs-2023-01,logs-2023-02 \
+      --dest_index logs-2023 --generate_ids
+
+   escuse-me indices reindex --source_index products-v1 --dest_index products-active \
+      --query_string '{"term": {"status": "active"}}'
+
+   escuse-me indices reindex --source_index logs-2023 --dest_index logs-2023-v2 \
+      --poll_interval 30s --max_poll_interval 10m
+
+   escuse-me indices reindex --resume_task oTUltX4IQMOUUVeiohTt8A:12345 --structured_progress
+
+   escuse-me indices reindex --source_index products --dest_index products \
+      --remote_host https://old-cluster:9200 --remote_username elastic --remote_password secret
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"source_index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Source indices to copy documents from (required unless resuming a task)"),
+				),
+				parameters.NewParameterDefinition(
+					"dest_index",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Destination index (required unless resuming a task)"),
+				),
+				parameters.NewParameterDefinition(
+					"remote_host",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("URL of the remote cluster to copy the source indices from (e.g. https://old-cluster:9200)"),
+				),
+				parameters.NewParameterDefinition(
+					"remote_username",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Username to connect to the remote cluster"),
+				),
+				parameters.NewParameterDefinition(
+					"remote_password",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Password to connect to the remote cluster"),
+				),
+				parameters.NewParameterDefinition(
+					"remote_socket_timeout",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Timeout of reads on the connection to the remote cluster (e.g. 1m)"),
+				),
+				parameters.NewParameterDefinition(
+					"query",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the query selecting the documents to reindex"),
+				),
+				parameters.NewParameterDefinition(
+					"query_string",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("The query selecting the documents to reindex as a JSON string, merged over --query"),
+				),
+				parameters.NewParameterDefinition(
+					"script",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Painless script applied to each document"),
+				),
+				parameters.NewParameterDefinition(
+					"reindex_script_id",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Id of a stored script applied to each document, instead of an inline --script"),
+				),
+				parameters.NewParameterDefinition(
+					"script_params",
+					parameters.ParameterTypeObjectFromFile,
+					parameters.WithHelp("JSON or YAML file containing the params passed to the script"),
+				),
+				parameters.NewParameterDefinition(
+					"pipeline",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Ingest pipeline applied to the destination documents"),
+				),
+				parameters.NewParameterDefinition(
+					"op_type",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Set to create to only index documents that don't exist in the destination"),
+					parameters.WithChoices("index", "create"),
+					parameters.WithDefault("index"),
+				),
+				parameters.NewParameterDefinition(
+					"dest_version_type",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Versioning of the destination documents: internal overwrites them, external keeps the source versions"),
+					parameters.WithChoices("internal", "external"),
+				),
+				parameters.NewParameterDefinition(
+					"generate_ids",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Let ES generate new ids for the destination documents instead of preserving the source ids"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"dest_routing",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Routing of the destination documents: keep, discard or =<value>"),
+				),
+				parameters.NewParameterDefinition(
+					"conflicts",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("What to do when the reindex hits version conflicts"),
+					parameters.WithChoices("abort", "proceed"),
+					parameters.WithDefault("abort"),
+				),
+				parameters.NewParameterDefinition(
+					"max_docs",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Maximum number of documents to reindex"),
+				),
+				parameters.NewParameterDefinition(
+					"slices",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Number of slices the task is divided into (a number or auto)"),
+				),
+				parameters.NewParameterDefinition(
+					"requests_per_second",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Throttle for the reindex in sub-requests per second"),
+				),
+				parameters.NewParameterDefinition(
+					"refresh",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Refresh the destination index once the reindex is done"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"timeout",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Time each individual bulk request waits for unavailable shards (e.g. 1m)"),
+				),
+				parameters.NewParameterDefinition(
+					"wait_for_completion",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Wait for the reindex to complete, otherwise output the task id"),
+					parameters.WithDefault(true),
+				),
+				parameters.NewParameterDefinition(
+					"poll_interval",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Run the reindex as a task polled at this interval (e.g. 30s) instead of waiting in a single request"),
+				),
+				parameters.NewParameterDefinition(
+					"structured_progress",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Poll the reindex task and output a progress row for each poll"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"resume_task",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Id of a running reindex task (node:id) to wait for instead of starting a new reindex"),
+				),
+				parameters.NewParameterDefinition(
+					"max_poll_interval",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Maximum interval between polls of the reindex task when backing off after failed polls"),
+					parameters.WithDefault("5m"),
+				),
+				parameters.NewParameterDefinition(
+					"max_poll_failures",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of consecutive failed polls of the reindex task after which to give up"),
+					parameters.WithDefault(10),
+				),
+				parameters.NewParameterDefinition(
+					"summary_file",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Write a JSON summary of the reindex (indices, task id, document counts, took) to this file"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ReindexSettings struct {
+	SourceIndex         []string               `glazed.parameter:"source_index"`
+	DestIndex           string                 `glazed.parameter:"dest_index"`
+	RemoteHost          string                 `glazed.parameter:"remote_host"`
+	RemoteUsername      string                 `glazed.parameter:"remote_username"`
+	RemotePassword      string                 `glazed.parameter:"remote_password"`
+	RemoteSocketTimeout string                 `glazed.parameter:"remote_socket_timeout"`
+	Query               map[string]interface{} `glazed.parameter:"query"`
+	QueryString         string                 `glazed.parameter:"query_string"`
+	Script              string                 `glazed.parameter:"script"`
+	ScriptID            string                 `glazed.parameter:"reindex_script_id"`
+	ScriptParams        map[string]interface{} `glazed.parameter:"script_params"`
+	Pipeline            string                 `glazed.parameter:"pipeline"`
+	OpType              string                 `glazed.parameter:"op_type"`
+	DestVersionType     string                 `glazed.parameter:"dest_version_type"`
+	GenerateIDs         bool                   `glazed.parameter:"generate_ids"`
+	DestRouting         string                 `glazed.parameter:"dest_routing"`
+	Conflicts           string                 `glazed.parameter:"conflicts"`
+	MaxDocs             *int                   `glazed.parameter:"max_docs"`
+	Slices              string                 `glazed.parameter:"slices"`
+	RequestsPerSecond   *int                   `glazed.parameter:"requests_per_second"`
+	Refresh             bool                   `glazed.parameter:"refresh"`
+	Timeout             string                 `glazed.parameter:"timeout"`
+	WaitForCompletion   bool                   `glazed.parameter:"wait_for_completion"`
+	PollInterval        string                 `glazed.parameter:"poll_interval"`
+	StructuredProgress  bool                   `glazed.parameter:"structured_progress"`
+	ResumeTask          string                 `glazed.parameter:"resume_task"`
+	MaxPollInterval     string                 `glazed.parameter:"max_poll_interval"`
+	MaxPollFailures     int                    `glazed.parameter:"max_poll_failures"`
+	SummaryFile         string                 `glazed.parameter:"summary_file"`
+}
+
+// ReindexSummary is the machine-readable summary written by --summary_file. The counts
+// are only known when waiting for completion, otherwise only the task id is set.
+type ReindexSummary struct {
+	Source   []string `json:"source"`
+	Target   string   `json:"target"`
+	Task     string   `json:"task,omitempty"`
+	Total    int64    `json:"total"`
+	Created  int64    `json:"created"`
+	Updated  int64    `json:"updated"`
+	Deleted  int64    `json:"deleted"`
+	Failures int      `json:"failures"`
+	Took     int64    `json:"took"`
+}
+
+// writeReindexSummary writes the summary of the reindex response to file.
+func writeReindexSummary(file string, s *ReindexSettings, responseBody []byte) error {
+	var response struct {
+		Task     string        `json:"task"`
+		Total    int64         `json:"total"`
+		Created  int64         `json:"created"`
+		Updated  int64         `json:"updated"`
+		Deleted  int64         `json:"deleted"`
+		Failures []interface{} `json:"failures"`
+		Took     int64         `json:"took"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return errors.Wrap(err, "could not parse reindex response")
+	}
+
+	summary := ReindexSummary{
+		Source:   s.SourceIndex,
+		Target:   s.DestIndex,
+		Task:     response.Task,
+		Total:    response.Total,
+		Created:  response.Created,
+		Updated:  response.Updated,
+		Deleted:  response.Deleted,
+		Failures: len(response.Failures),
+		Took:     response.Took,
+	}
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		return errors.Wrapf(err, "could not write summary to %s", file)
+	}
+	return nil
+}
+
+// buildReindexBody builds the body of the reindex request from the settings.
+func buildReindexBody(s *ReindexSettings) (map[string]interface{}, error) {
+	if s.GenerateIDs && s.OpType == "create" {
+		return nil, errors.New("--generate_ids can't be combined with --op_type create, which requires stable ids")
+	}
+	if s.GenerateIDs && s.DestVersionType == "external" {
+		return nil, errors.New("--generate_ids can't be combined with --dest_version_type external, which compares versions of documents with the same id")
+	}
+	if s.ScriptID != "" && s.Script != "" {
+		return nil, errors.New("--script and --reindex_script_id are mutually exclusive")
+	}
+	if s.ScriptID != "" && s.GenerateIDs {
+		return nil, errors.New("--generate_ids can't be combined with a stored script, reset ctx._id in the stored script instead")
+	}
+
+	source := map[string]interface{}{
+		"index": s.SourceIndex,
+	}
+	query := map[string]interface{}{}
+	for k, v := range s.Query {
+		query[k] = v
+	}
+	if s.QueryString != "" {
+		// merge query with query file
+		var queryMap map[string]interface{}
+		if err := json.Unmarshal([]byte(s.QueryString), &queryMap); err != nil {
+			return nil, errors.Wrap(err, "invalid query JSON")
+		}
+		for k, v := range queryMap {
+			query[k] = v
+		}
+	}
+	if len(query) > 0 {
+		source["query"] = query
+	}
+	remote, err := buildReindexRemote(s)
+	if err != nil {
+		return nil, err
+	}
+	if remote != nil {
+		source["remote"] = remote
+	}
+
+	dest := map[string]interface{}{
+		"index": s.DestIndex,
+	}
+	if s.OpType != "" && s.OpType != "index" {
+		dest["op_type"] = s.OpType
+	}
+	if s.DestVersionType != "" {
+		dest["version_type"] = s.DestVersionType
+	}
+	if s.Pipeline != "" {
+		dest["pipeline"] = s.Pipeline
+	}
+	if s.DestRouting != "" {
+		dest["routing"] = s.DestRouting
+	}
+
+	body := map[string]interface{}{
+		"source": source,
+		"dest":   dest,
+	}
+	if s.Conflicts != "" && s.Conflicts != "abort" {
+		body["conflicts"] = s.Conflicts
+	}
+
+	script := s.Script
+	if s.GenerateIDs {
+		script = generateIDsScript + " " + script
+	}
+	switch {
+	case s.ScriptID != "":
+		body["script"] = map[string]interface{}{
+			"id": s.ScriptID,
+		}
+	case script != "":
+		body["script"] = map[string]interface{}{
+			"source": script,
+			"lang":   "painless",
+		}
+	}
+	if s.ScriptParams != nil {
+		script_, ok := body["script"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("--script_params requires --script or --reindex_script_id")
+		}
+		script_["params"] = s.ScriptParams
+	}
+
+	return body, nil
+}
+
+// buildReindexRemote builds the source.remote block of the reindex request, or returns
+// nil when reindexing from the local cluster.
+func buildReindexRemote(s *ReindexSettings) (map[string]interface{}, error) {
+	if s.RemoteHost == "" {
+		if s.RemoteUsername != "" || s.RemotePassword != "" || s.RemoteSocketTimeout != "" {
+			return nil, errors.New("--remote_username, --remote_password and --remote_socket_timeout require --remote_host")
+		}
+		return nil, nil
+	}
+
+	u, err := url.Parse(s.RemoteHost)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("--remote_host %s must be a full URL with scheme, host and port (e.g. https://old-cluster:9200)", s.RemoteHost)
+	}
+	if u.Port() == "" {
+		return nil, errors.Errorf("--remote_host %s must include the port (e.g. https://old-cluster:9200)", s.RemoteHost)
+	}
+	if s.Slices != "" && s.Slices != "1" {
+		return nil, errors.New("--slices can't be used when reindexing from a remote cluster")
+	}
+
+	remote := map[string]interface{}{
+		"host": s.RemoteHost,
+	}
+	if s.RemoteUsername != "" {
+		remote["username"] = s.RemoteUsername
+	}
+	if s.RemotePassword != "" {
+		remote["password"] = s.RemotePassword
+	}
+	if s.RemoteSocketTimeout != "" {
+		remote["socket_timeout"] = s.RemoteSocketTimeout
+	}
+	return remote, nil
+}
+
+func (c *ReindexCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ReindexSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	if s.ResumeTask == "" && (len(s.SourceIndex) == 0 || s.DestIndex == "") {
+		return errors.New("--source_index and --dest_index are required")
+	}
+
+	if (s.StructuredProgress || s.ResumeTask != "") && s.PollInterval == "" {
+		s.PollInterval = defaultReindexPollInterval
+	}
+	pollTask := s.PollInterval != ""
+	var polling helpers.TaskPolling
+	if pollTask {
+		if !s.WaitForCompletion {
+			return errors.New("--poll_interval and --resume_task can't be combined with --wait_for_completion=false")
+		}
+		polling, err = helpers.ParseTaskPolling(s.PollInterval, s.MaxPollInterval, s.MaxPollFailures)
+		if err != nil {
+			return err
+		}
+		if s.StructuredProgress {
+			polling.OnPoll = func(taskID string, task map[string]interface{}) error {
+				return gp.AddRow(ctx, reindexProgressRow(taskID, task))
+			}
+		}
+	}
+
+	if s.ResumeTask != "" {
+		responseBody, err := waitForReindexTask(ctx, es, s.ResumeTask, polling)
+		if err != nil {
+			return err
+		}
+		return addReindexResponse(ctx, gp, s, responseBody)
+	}
+
+	body, err := buildReindexBody(s)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return err
+	}
+
+	options := []func(*esapi.ReindexRequest){
+		es.Reindex.WithContext(ctx),
+		es.Reindex.WithWaitForCompletion(s.WaitForCompletion && !pollTask),
+		es.Reindex.WithRefresh(s.Refresh),
+	}
+	if s.MaxDocs != nil {
+		options = append(options, es.Reindex.WithMaxDocs(*s.MaxDocs))
+	}
+	if s.Slices != "" {
+		options = append(options, es.Reindex.WithSlices(s.Slices))
+	}
+	if s.RequestsPerSecond != nil {
+		options = append(options, es.Reindex.WithRequestsPerSecond(*s.RequestsPerSecond))
+	}
+	if s.Timeout != "" {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return errors.Wrap(err, "invalid timeout")
+		}
+		options = append(options, es.Reindex.WithTimeout(timeout))
+	}
+
+	res, err := es.Reindex(&buf, options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(responseBody)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	if pollTask {
+		var taskResponse struct {
+			Task string `json:"task"`
+		}
+		if err := json.Unmarshal(responseBody, &taskResponse); err != nil {
+			return err
+		}
+		if taskResponse.Task == "" {
+			return errors.New("could not find task in reindex response")
+		}
+		responseBody, err = waitForReindexTask(ctx, es, taskResponse.Task, polling)
+		if err != nil {
+			return err
+		}
+	}
+
+	return addReindexResponse(ctx, gp, s, responseBody)
+}
+
+// addReindexResponse writes the summary file and outputs the response of a completed
+// (or, without waiting for completion, started) reindex.
+func addReindexResponse(
+	ctx context.Context,
+	gp middlewares.Processor,
+	s *ReindexSettings,
+	responseBody []byte,
+) error {
+	if s.SummaryFile != "" {
+		if err := writeReindexSummary(s.SummaryFile, s, responseBody); err != nil {
+			return err
+		}
+	}
+
+	// the last progress row already reports the final counts
+	if s.StructuredProgress {
+		return nil
+	}
+
+	responseRow := types.NewRow()
+	if err := json.Unmarshal(responseBody, &responseRow); err != nil {
+		return err
+	}
+
+	return gp.AddRow(ctx, responseRow)
+}
+
+// waitForReindexTask polls the reindex task taskID, and returns the response of the
+// reindex once the task has completed, along with the task id.
+func waitForReindexTask(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	taskID string,
+	polling helpers.TaskPolling,
+) ([]byte, error) {
+	response, err := helpers.WaitForTask(ctx, es, taskID, polling)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		response = map[string]interface{}{}
+	}
+	response["task"] = taskID
+	return json.Marshal(response)
+}
+
+// reindexProgressRow returns the progress of a reindex from the info of its task.
+func reindexProgressRow(taskID string, task map[string]interface{}) types.Row {
+	info, _ := task["task"].(map[string]interface{})
+	status, _ := info["status"].(map[string]interface{})
+	completed, _ := task["completed"].(bool)
+
+	row := types.NewRow(
+		types.MRP("task_id", taskID),
+		types.MRP("completed", completed),
+	)
+	for _, k := range []string{"total", "created", "updated", "deleted", "version_conflicts", "batches", "requests_per_second"} {
+		row.Set(k, status[k])
+	}
+	runningTime, _ := info["running_time_in_nanos"].(float64)
+	row.Set("running_time_seconds", runningTime/float64(time.Second))
+	return row
+}
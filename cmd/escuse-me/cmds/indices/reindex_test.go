@@ -0,0 +1,273 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func TestReindexPollsTask(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleReindex("node-1:42", 2, 5)
+
+	cmd, err := NewReindexCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"source_index":        []string{"logs-1"},
+			"dest_index":          "logs-2",
+			"poll_interval":       "10ms",
+			"structured_progress": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a progress row for each poll, the last one finding the task completed
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 progress rows, got %d", len(rows))
+	}
+	for i, row := range rows {
+		completed, _ := row.Get("completed")
+		if completed != (i == len(rows)-1) {
+			t.Errorf("row %d: unexpected completed %v", i, completed)
+		}
+		if taskID, _ := row.Get("task_id"); taskID != "node-1:42" {
+			t.Errorf("row %d: unexpected task_id %v", i, taskID)
+		}
+	}
+	if created, _ := rows[2].Get("created"); created != float64(5) {
+		t.Errorf("expected 5 created documents, got %v", created)
+	}
+
+	reindexes := server.RequestsTo(http.MethodPost, "/_reindex")
+	if len(reindexes) != 1 {
+		t.Fatalf("expected 1 reindex request, got %d", len(reindexes))
+	}
+	if waitForCompletion := reindexes[0].Query["wait_for_completion"]; len(waitForCompletion) != 1 || waitForCompletion[0] != "false" {
+		t.Errorf("expected the reindex to run as a task, got wait_for_completion=%v", waitForCompletion)
+	}
+	var body struct {
+		Source struct {
+			Index []string `json:"index"`
+		} `json:"source"`
+		Dest struct {
+			Index string `json:"index"`
+		} `json:"dest"`
+	}
+	if err := reindexes[0].DecodeBody(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Source.Index) != 1 || body.Source.Index[0] != "logs-1" || body.Dest.Index != "logs-2" {
+		t.Errorf("unexpected reindex body %s", reindexes[0].Body)
+	}
+
+	if n := len(server.RequestsTo(http.MethodGet, "/_tasks/node-1:42")); n != 3 {
+		t.Errorf("expected 3 task polls, got %d", n)
+	}
+}
+
+func TestReindexResumesTask(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleReindex("node-1:42", 1, 5)
+
+	cmd, err := NewReindexCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"resume_task":   "node-1:42",
+			"poll_interval": "10ms",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if created, _ := rows[0].Get("created"); created != float64(5) {
+		t.Errorf("expected 5 created documents, got %v", created)
+	}
+	if n := len(server.RequestsTo(http.MethodPost, "/_reindex")); n != 0 {
+		t.Errorf("expected no new reindex, got %d reindex requests", n)
+	}
+	if n := len(server.RequestsTo(http.MethodGet, "/_tasks/node-1:42")); n != 2 {
+		t.Errorf("expected 2 task polls, got %d", n)
+	}
+}
+
+func TestReindexResumeFailsOnUnknownTask(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleError(http.MethodGet, "/_tasks/*", http.StatusNotFound, "resource_not_found_exception", "task [node-1:99] isn't running and hasn't stored its results")
+
+	cmd, err := NewReindexCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"resume_task":   "node-1:99",
+			"poll_interval": "10ms",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected polling an unknown task to fail")
+	}
+	// a permanent error is not retried
+	if n := len(server.RequestsTo(http.MethodGet, "/_tasks/*")); n != 1 {
+		t.Errorf("expected 1 task poll, got %d", n)
+	}
+}
+
+func TestReindexRetriesTransientPollFailures(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+
+	var mu sync.Mutex
+	polls := 0
+	server.Handle(http.MethodGet, "/_tasks/*", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		if polls == 1 {
+			estest.WriteJSON(w, http.StatusServiceUnavailable, estest.ErrorResponse(
+				http.StatusServiceUnavailable, "node_not_connected_exception", "node not connected"))
+			return
+		}
+		estest.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"completed": true,
+			"response":  map[string]interface{}{"total": 5, "created": 5},
+		})
+	})
+
+	cmd, err := NewReindexCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"resume_task":   "node-1:42",
+			"poll_interval": "10ms",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if n := len(server.RequestsTo(http.MethodGet, "/_tasks/*")); n != 2 {
+		t.Errorf("expected 2 task polls, got %d", n)
+	}
+}
+
+func TestReindexRejectsNonPositiveMaxPollFailures(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+
+	cmd, err := NewReindexCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"resume_task":       "node-1:42",
+			"poll_interval":     "10ms",
+			"max_poll_failures": 0,
+		},
+	})
+	if err == nil {
+		t.Fatal("expected --max_poll_failures 0 to be rejected")
+	}
+	if n := len(server.RequestsTo(http.MethodGet, "/_tasks/*")); n != 0 {
+		t.Errorf("expected no task poll, got %d", n)
+	}
+}
+
+func TestReindexCountsFailuresOnce(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleJSON(http.MethodPost, "/_reindex", http.StatusOK, map[string]interface{}{
+		"task": "node-1:42",
+	})
+
+	// every poll reports the same two failures, the task completing on the third one
+	failures := []interface{}{
+		map[string]interface{}{"index": "logs-2", "id": "1", "status": 400},
+		map[string]interface{}{"index": "logs-2", "id": "2", "status": 400},
+	}
+	var mu sync.Mutex
+	polls := 0
+	server.Handle(http.MethodGet, "/_tasks/node-1:42", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		response := map[string]interface{}{
+			"completed": polls == 3,
+			"task": map[string]interface{}{
+				"status": map[string]interface{}{"total": 5, "created": 3, "failures": failures},
+			},
+		}
+		if polls == 3 {
+			response["response"] = map[string]interface{}{"total": 5, "created": 3, "failures": failures}
+		}
+		estest.WriteJSON(w, http.StatusOK, response)
+	})
+
+	summaryFile := filepath.Join(t.TempDir(), "summary.json")
+	cmd, err := NewReindexCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"source_index":  []string{"logs-1"},
+			"dest_index":    "logs-2",
+			"poll_interval": "10ms",
+			"summary_file":  summaryFile,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(server.RequestsTo(http.MethodGet, "/_tasks/node-1:42")); n != 3 {
+		t.Errorf("expected 3 task polls, got %d", n)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	rowFailures, _ := rows[0].Get("failures")
+	if failures_, _ := rowFailures.([]interface{}); len(failures_) != 2 {
+		t.Errorf("expected 2 failures, got %v", rowFailures)
+	}
+
+	b, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary ReindexSummary
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Failures != 2 {
+		t.Errorf("expected 2 failures in the summary, got %d", summary.Failures)
+	}
+}
@@ -0,0 +1,383 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+const unassignedNode = "UNASSIGNED"
+
+type ShardAllocationCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &ShardAllocationCommand{}
+
+func NewShardAllocationCommand() (*ShardAllocationCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &ShardAllocationCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"shard-allocation",
+			cmds.WithShort("Summarizes how shards are distributed across nodes, flagging hotspots"),
+			cmds.WithLong(`
+Combines the cat shards and cat allocation APIs to show how shards are distributed.
+
+With --by node (default), outputs one row per node with its shard, primary and replica
+counts and its disk usage. A node is flagged as a hotspot if it holds more than
+--hotspot_factor times the average number of primaries per node.
+
+With --by index, outputs one row per index with its shard counts, the number of nodes
+holding its shards and the distribution per node. An index is flagged as a hotspot if
+a single node holds more than --hotspot_factor times its fair share of the primaries.
+
+   escuse-me indices shard-allocation
+   escuse-me indices shard-allocation --by index --index 'logs-*' --only_hotspots
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"by",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Output one row per node or per index"),
+					parameters.WithChoices("node", "index"),
+					parameters.WithDefault("node"),
+				),
+				parameters.NewParameterDefinition(
+					"index",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Only consider the shards of these indices"),
+				),
+				parameters.NewParameterDefinition(
+					"hotspot_factor",
+					parameters.ParameterTypeFloat,
+					parameters.WithHelp("Ratio to the fair share of primaries above which a node or index is flagged as a hotspot"),
+					parameters.WithDefault(1.5),
+				),
+				parameters.NewParameterDefinition(
+					"only_hotspots",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only output hotspots"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type ShardAllocationSettings struct {
+	By            string   `glazed.parameter:"by"`
+	Index         []string `glazed.parameter:"index"`
+	HotspotFactor float64  `glazed.parameter:"hotspot_factor"`
+	OnlyHotspots  bool     `glazed.parameter:"only_hotspots"`
+}
+
+type catShard struct {
+	Index  string `json:"index"`
+	Shard  string `json:"shard"`
+	PriRep string `json:"prirep"`
+	State  string `json:"state"`
+	Store  string `json:"store"`
+	Node   string `json:"node"`
+}
+
+type catAllocation struct {
+	DiskIndices string `json:"disk.indices"`
+	DiskUsed    string `json:"disk.used"`
+	DiskTotal   string `json:"disk.total"`
+	DiskPercent string `json:"disk.percent"`
+	Node        string `json:"node"`
+}
+
+type nodeShards struct {
+	Primaries int
+	Replicas  int
+}
+
+func (n nodeShards) String() string {
+	return fmt.Sprintf("%dp/%dr", n.Primaries, n.Replicas)
+}
+
+func (c *ShardAllocationCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *ShardAllocationCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ShardAllocationSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	shards, err := getCatShards(ctx, es, s.Index)
+	if err != nil {
+		return err
+	}
+	allocations, err := getCatAllocation(ctx, es)
+	if err != nil {
+		return err
+	}
+
+	dataNodes := 0
+	for _, a := range allocations {
+		if a.Node != unassignedNode {
+			dataNodes++
+		}
+	}
+
+	if s.By == "index" {
+		return emitShardAllocationByIndex(ctx, s, shards, dataNodes, gp)
+	}
+	return emitShardAllocationByNode(ctx, s, shards, allocations, dataNodes, gp)
+}
+
+func emitShardAllocationByNode(
+	ctx context.Context,
+	s *ShardAllocationSettings,
+	shards []catShard,
+	allocations []catAllocation,
+	dataNodes int,
+	gp middlewares.Processor,
+) error {
+	perNode := map[string]*nodeShards{}
+	totalPrimaries := 0
+	for _, shard := range shards {
+		node := shardNode(shard)
+		if perNode[node] == nil {
+			perNode[node] = &nodeShards{}
+		}
+		if shard.PriRep == "p" {
+			perNode[node].Primaries++
+			if node != unassignedNode {
+				totalPrimaries++
+			}
+		} else {
+			perNode[node].Replicas++
+		}
+	}
+
+	averagePrimaries := 0.0
+	if dataNodes > 0 {
+		averagePrimaries = float64(totalPrimaries) / float64(dataNodes)
+	}
+
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Node < allocations[j].Node
+	})
+	for _, a := range allocations {
+		counts := perNode[a.Node]
+		if counts == nil {
+			counts = &nodeShards{}
+		}
+		hotspot := a.Node != unassignedNode &&
+			counts.Primaries > 1 &&
+			float64(counts.Primaries) > s.HotspotFactor*averagePrimaries
+		if s.OnlyHotspots && !hotspot {
+			continue
+		}
+
+		row := types.NewRow(
+			types.MRP("node", a.Node),
+			types.MRP("shards", counts.Primaries+counts.Replicas),
+			types.MRP("primaries", counts.Primaries),
+			types.MRP("replicas", counts.Replicas),
+			types.MRP("disk_indices", parseCatBytes(a.DiskIndices)),
+			types.MRP("disk_used", parseCatBytes(a.DiskUsed)),
+			types.MRP("disk_total", parseCatBytes(a.DiskTotal)),
+			types.MRP("disk_percent", parseCatBytes(a.DiskPercent)),
+			types.MRP("hotspot", hotspot),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func emitShardAllocationByIndex(
+	ctx context.Context,
+	s *ShardAllocationSettings,
+	shards []catShard,
+	dataNodes int,
+	gp middlewares.Processor,
+) error {
+	perIndex := map[string]map[string]*nodeShards{}
+	for _, shard := range shards {
+		if perIndex[shard.Index] == nil {
+			perIndex[shard.Index] = map[string]*nodeShards{}
+		}
+		node := shardNode(shard)
+		if perIndex[shard.Index][node] == nil {
+			perIndex[shard.Index][node] = &nodeShards{}
+		}
+		if shard.PriRep == "p" {
+			perIndex[shard.Index][node].Primaries++
+		} else {
+			perIndex[shard.Index][node].Replicas++
+		}
+	}
+
+	indices := make([]string, 0, len(perIndex))
+	for index := range perIndex {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	for _, index := range indices {
+		nodes := perIndex[index]
+		primaries, replicas, unassigned, maxPrimaries := 0, 0, 0, 0
+		nodeNames := []string{}
+		for node, counts := range nodes {
+			primaries += counts.Primaries
+			replicas += counts.Replicas
+			if node == unassignedNode {
+				unassigned += counts.Primaries + counts.Replicas
+				continue
+			}
+			nodeNames = append(nodeNames, node)
+			maxPrimaries = max(maxPrimaries, counts.Primaries)
+		}
+		sort.Strings(nodeNames)
+
+		fairShare := 0.0
+		if dataNodes > 0 {
+			fairShare = math.Ceil(float64(primaries) / float64(dataNodes))
+		}
+		hotspot := maxPrimaries > 1 && float64(maxPrimaries) > s.HotspotFactor*fairShare
+		if s.OnlyHotspots && !hotspot {
+			continue
+		}
+
+		distribution := make([]string, 0, len(nodeNames))
+		for _, node := range nodeNames {
+			distribution = append(distribution, node+":"+nodes[node].String())
+		}
+
+		row := types.NewRow(
+			types.MRP("index", index),
+			types.MRP("primaries", primaries),
+			types.MRP("replicas", replicas),
+			types.MRP("unassigned", unassigned),
+			types.MRP("nodes", len(nodeNames)),
+			types.MRP("max_primaries_per_node", maxPrimaries),
+			types.MRP("distribution", strings.Join(distribution, ",")),
+			types.MRP("hotspot", hotspot),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func shardNode(shard catShard) string {
+	if shard.Node == "" || shard.State == "UNASSIGNED" {
+		return unassignedNode
+	}
+	return shard.Node
+}
+
+// parseCatBytes parses the numbers returned as strings by the cat APIs, keeping the
+// value as is if it isn't a number (for example for the UNASSIGNED node).
+func parseCatBytes(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v
+	}
+	return s
+}
+
+func getCatShards(ctx context.Context, es *elasticsearch.Client, index []string) ([]catShard, error) {
+	res, err := es.Cat.Shards(
+		es.Cat.Shards.WithContext(ctx),
+		es.Cat.Shards.WithIndex(index...),
+		es.Cat.Shards.WithFormat("json"),
+		es.Cat.Shards.WithBytes("b"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	ret := []catShard{}
+	if err := json.Unmarshal(body, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func getCatAllocation(ctx context.Context, es *elasticsearch.Client) ([]catAllocation, error) {
+	res, err := es.Cat.Allocation(
+		es.Cat.Allocation.WithContext(ctx),
+		es.Cat.Allocation.WithFormat("json"),
+		es.Cat.Allocation.WithBytes("b"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	ret := []catAllocation{}
+	if err := json.Unmarshal(body, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
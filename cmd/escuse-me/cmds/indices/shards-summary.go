@@ -0,0 +1,34 @@
+package indices
+
+import (
+	"encoding/json"
+
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+// shardsSummaryRow returns a row with the _shards summary of a broadcast response such
+// as refresh, flush or forcemerge: the number of shards the operation ran on, and how
+// many succeeded or failed.
+func shardsSummaryRow(body []byte) (types.Row, error) {
+	var response struct {
+		Shards struct {
+			Total      int           `json:"total"`
+			Successful int           `json:"successful"`
+			Failed     int           `json:"failed"`
+			Failures   []interface{} `json:"failures"`
+		} `json:"_shards"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	row := types.NewRow(
+		types.MRP("total", response.Shards.Total),
+		types.MRP("successful", response.Shards.Successful),
+		types.MRP("failed", response.Shards.Failed),
+	)
+	if len(response.Shards.Failures) > 0 {
+		row.Set("failures", response.Shards.Failures)
+	}
+	return row, nil
+}
@@ -0,0 +1,142 @@
+package indices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+)
+
+type indexSizeStats struct {
+	Shards struct {
+		Total      int `json:"total"`
+		Successful int `json:"successful"`
+		Failed     int `json:"failed"`
+	} `json:"_shards"`
+	Indices map[string]struct {
+		Primaries struct {
+			Docs struct {
+				Count int64 `json:"count"`
+			} `json:"docs"`
+		} `json:"primaries"`
+		Total struct {
+			Store struct {
+				SizeInBytes int64 `json:"size_in_bytes"`
+			} `json:"store"`
+		} `json:"total"`
+	} `json:"indices"`
+}
+
+type clusterHealth struct {
+	ClusterName         string  `json:"cluster_name"`
+	Status              string  `json:"status"`
+	NumberOfNodes       int     `json:"number_of_nodes"`
+	NumberOfDataNodes   int     `json:"number_of_data_nodes"`
+	ActivePrimaryShards int     `json:"active_primary_shards"`
+	ActiveShards        int     `json:"active_shards"`
+	RelocatingShards    int     `json:"relocating_shards"`
+	InitializingShards  int     `json:"initializing_shards"`
+	UnassignedShards    int     `json:"unassigned_shards"`
+	ActiveShardsPercent float64 `json:"active_shards_percent_as_number"`
+}
+
+func getClusterHealth(ctx context.Context, es *elasticsearch.Client) (*clusterHealth, error) {
+	res, err := es.Cluster.Health(es.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	ret := &clusterHealth{}
+	if err := json.Unmarshal(body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// renderStatsDashboard renders the cluster health, the totals of the index stats and
+// the top indices by store size as a box-drawn summary.
+func renderStatsDashboard(
+	w io.Writer,
+	health *clusterHealth,
+	stats *indexSizeStats,
+	top int,
+) error {
+	type indexSize struct {
+		Index string
+		Docs  int64
+		Size  int64
+	}
+	indices := make([]indexSize, 0, len(stats.Indices))
+	var totalDocs, totalSize int64
+	for index, s := range stats.Indices {
+		indices = append(indices, indexSize{
+			Index: index,
+			Docs:  s.Primaries.Docs.Count,
+			Size:  s.Total.Store.SizeInBytes,
+		})
+		totalDocs += s.Primaries.Docs.Count
+		totalSize += s.Total.Store.SizeInBytes
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		if indices[i].Size == indices[j].Size {
+			return indices[i].Index < indices[j].Index
+		}
+		return indices[i].Size > indices[j].Size
+	})
+	if top > 0 && len(indices) > top {
+		indices = indices[:top]
+	}
+
+	sections := []helpers.DashboardSection{}
+	if health != nil {
+		sections = append(sections,
+			helpers.DashboardSection{
+				Title: "Cluster " + health.ClusterName,
+				Rows: [][]string{
+					{"status", health.Status},
+					{"nodes", fmt.Sprintf("%d (%d data)", health.NumberOfNodes, health.NumberOfDataNodes)},
+					{"shards", fmt.Sprintf("%d active (%d primary), %.1f%%",
+						health.ActiveShards, health.ActivePrimaryShards, health.ActiveShardsPercent)},
+					{"", fmt.Sprintf("%d relocating, %d initializing, %d unassigned",
+						health.RelocatingShards, health.InitializingShards, health.UnassignedShards)},
+				},
+			})
+	}
+
+	sections = append(sections, helpers.DashboardSection{
+		Title: "Indices",
+		Rows: [][]string{
+			{"indices", fmt.Sprint(len(stats.Indices))},
+			{"documents", fmt.Sprint(totalDocs)},
+			{"store size", helpers.FormatBytes(totalSize)},
+			{"shards", fmt.Sprintf("%d total, %d failed", stats.Shards.Total, stats.Shards.Failed)},
+		},
+	})
+
+	topRows := [][]string{{"INDEX", "DOCS", "SIZE"}}
+	for _, i := range indices {
+		topRows = append(topRows, []string{i.Index, fmt.Sprint(i.Docs), helpers.FormatBytes(i.Size)})
+	}
+	sections = append(sections, helpers.DashboardSection{
+		Title: fmt.Sprintf("Top %d indices by size", len(indices)),
+		Rows:  topRows,
+	})
+
+	return helpers.RenderDashboard(w, sections)
+}
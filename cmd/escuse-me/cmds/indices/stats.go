@@ -3,7 +3,7 @@ package indices
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
 	layers2 "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -12,7 +12,10 @@ import (
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"io"
+	"os"
+	"sort"
 )
 
 type IndicesStatsCommand struct {
@@ -53,6 +56,42 @@ func NewIndicesStatsCommand() (*IndicesStatsCommand, error) {
 					parameters.WithHelp("Prints the full version response"),
 					parameters.WithDefault(false),
 				),
+				parameters.NewParameterDefinition(
+					"deleted_ratio",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Only output the ratio of deleted documents per index, to find forcemerge candidates"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"threshold",
+					parameters.ParameterTypeFloat,
+					parameters.WithHelp("Only output indices whose deleted documents ratio is at least this value (used with --deleted_ratio)"),
+					parameters.WithDefault(0.0),
+				),
+				parameters.NewParameterDefinition(
+					"dashboard",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Print a compact summary of the cluster health and the largest indices instead of the stats"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"order_by",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("Output a row per index with its size and activity, sorted by this column (largest first)"),
+					parameters.WithChoices(statsSortKeys...),
+				),
+				parameters.NewParameterDefinition(
+					"ascending",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Sort --order_by rows smallest first"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"top",
+					parameters.ParameterTypeInteger,
+					parameters.WithHelp("Number of indices shown in the dashboard or output with --order_by (0 for all)"),
+					parameters.WithDefault(10),
+				),
 			),
 			cmds.WithLayersList(
 				glazedParameterLayer,
@@ -63,8 +102,136 @@ func NewIndicesStatsCommand() (*IndicesStatsCommand, error) {
 }
 
 type IndicesStatsSettings struct {
-	Index string `glazed.parameter:"index"`
-	Full  bool   `glazed.parameter:"full"`
+	Index        string  `glazed.parameter:"index"`
+	Full         bool    `glazed.parameter:"full"`
+	DeletedRatio bool    `glazed.parameter:"deleted_ratio"`
+	Threshold    float64 `glazed.parameter:"threshold"`
+	Dashboard    bool    `glazed.parameter:"dashboard"`
+	OrderBy      string  `glazed.parameter:"order_by"`
+	Ascending    bool    `glazed.parameter:"ascending"`
+	Top          int     `glazed.parameter:"top"`
+}
+
+const (
+	statsSortDocCount      = "doc_count"
+	statsSortStoreSize     = "store_size_bytes"
+	statsSortIndexingOps   = "indexing_ops_total"
+	statsSortSearchQueries = "search_query_ops_total"
+)
+
+var statsSortKeys = []string{statsSortDocCount, statsSortStoreSize, statsSortIndexingOps, statsSortSearchQueries}
+
+type indexActivityStats struct {
+	Indices map[string]struct {
+		Primaries struct {
+			Docs struct {
+				Count int64 `json:"count"`
+			} `json:"docs"`
+		} `json:"primaries"`
+		Total struct {
+			Store struct {
+				SizeInBytes int64 `json:"size_in_bytes"`
+			} `json:"store"`
+			Indexing struct {
+				IndexTotal int64 `json:"index_total"`
+			} `json:"indexing"`
+			Search struct {
+				QueryTotal int64 `json:"query_total"`
+			} `json:"search"`
+		} `json:"total"`
+	} `json:"indices"`
+}
+
+type indexActivity struct {
+	Index  string
+	Values map[string]int64
+}
+
+// sortIndexActivity returns the size and activity of each index, sorted by orderBy
+// (largest first unless ascending) and limited to top indices if top > 0. Indices with
+// the same value are sorted by name.
+func sortIndexActivity(stats *indexActivityStats, orderBy string, ascending bool, top int) []indexActivity {
+	ret := make([]indexActivity, 0, len(stats.Indices))
+	for index, indexStats := range stats.Indices {
+		ret = append(ret, indexActivity{
+			Index: index,
+			Values: map[string]int64{
+				statsSortDocCount:      indexStats.Primaries.Docs.Count,
+				statsSortStoreSize:     indexStats.Total.Store.SizeInBytes,
+				statsSortIndexingOps:   indexStats.Total.Indexing.IndexTotal,
+				statsSortSearchQueries: indexStats.Total.Search.QueryTotal,
+			},
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		vi, vj := ret[i].Values[orderBy], ret[j].Values[orderBy]
+		if vi == vj {
+			return ret[i].Index < ret[j].Index
+		}
+		if ascending {
+			return vi < vj
+		}
+		return vi > vj
+	})
+
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+	return ret
+}
+
+type indexDocsStats struct {
+	Indices map[string]struct {
+		Primaries struct {
+			Docs struct {
+				Count   int64 `json:"count"`
+				Deleted int64 `json:"deleted"`
+			} `json:"docs"`
+		} `json:"primaries"`
+	} `json:"indices"`
+}
+
+type deletedRatio struct {
+	Index   string
+	Docs    int64
+	Deleted int64
+	Ratio   float64
+}
+
+// computeDeletedRatios returns the indices whose ratio of deleted documents
+// (deleted / (count + deleted)) is at least threshold, sorted by decreasing ratio.
+func computeDeletedRatios(stats *indexDocsStats, threshold float64) []deletedRatio {
+	ret := []deletedRatio{}
+	for index, indexStats := range stats.Indices {
+		docs := indexStats.Primaries.Docs
+		ratio := 0.0
+		if docs.Count+docs.Deleted > 0 {
+			ratio = float64(docs.Deleted) / float64(docs.Count+docs.Deleted)
+		}
+		if ratio < threshold {
+			continue
+		}
+		ret = append(ret, deletedRatio{
+			Index:   index,
+			Docs:    docs.Count,
+			Deleted: docs.Deleted,
+			Ratio:   ratio,
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Ratio == ret[j].Ratio {
+			return ret[i].Index < ret[j].Index
+		}
+		return ret[i].Ratio > ret[j].Ratio
+	})
+
+	return ret
+}
+
+func (i *IndicesStatsCommand) IsIdempotent() bool {
+	return true
 }
 
 func (i *IndicesStatsCommand) RunIntoGlazeProcessor(
@@ -93,7 +260,7 @@ func (i *IndicesStatsCommand) RunIntoGlazeProcessor(
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println(err)
+			es_cmds.PrintSummary(err)
 		}
 	}(res.Body)
 
@@ -103,6 +270,63 @@ func (i *IndicesStatsCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
+	if s.Dashboard {
+		stats := &indexSizeStats{}
+		err = json.Unmarshal(body, stats)
+		if err != nil {
+			return err
+		}
+		// the health is only informative, the dashboard is still useful without it
+		health, err := getClusterHealth(ctx, es)
+		if err != nil {
+			log.Warn().Err(err).Msg("could not get cluster health")
+		}
+		if err := renderStatsDashboard(os.Stdout, health, stats, s.Top); err != nil {
+			return err
+		}
+		return &cmds.ExitWithoutGlazeError{}
+	}
+
+	if s.DeletedRatio {
+		stats := &indexDocsStats{}
+		err = json.Unmarshal(body, stats)
+		if err != nil {
+			return err
+		}
+		for _, r := range computeDeletedRatios(stats, s.Threshold) {
+			row := types.NewRow(
+				types.MRP("index", r.Index),
+				types.MRP("docs", r.Docs),
+				types.MRP("deleted", r.Deleted),
+				types.MRP("ratio", r.Ratio),
+			)
+			err = gp.AddRow(ctx, row)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if s.OrderBy != "" {
+		stats := &indexActivityStats{}
+		err = json.Unmarshal(body, stats)
+		if err != nil {
+			return err
+		}
+		for _, a := range sortIndexActivity(stats, s.OrderBy, s.Ascending, s.Top) {
+			row := types.NewRow(types.MRP("index", a.Index))
+			for _, k := range statsSortKeys {
+				row.Set(k, a.Values[k])
+			}
+			err = gp.AddRow(ctx, row)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	body_ := types.NewRow()
 	err = json.Unmarshal(body, &body_)
 	if err != nil {
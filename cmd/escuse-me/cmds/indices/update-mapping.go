@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/escuse-me/pkg/mappings"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
@@ -39,6 +43,17 @@ func NewUpdateMappingCommand() (*UpdateMappingCommand, error) {
 		CommandDescription: cmds.NewCommandDescription(
 			"update-mapping",
 			cmds.WithShort("Updates the mapping of an existing index"),
+			cmds.WithLong(`
+Updates the mapping of an existing index. Before sending the update, the new mappings
+are compared to the current ones. If some changes can't be applied in place (changing the
+type or the analyzer of a field, for example), no update is sent and the command fails,
+listing these changes. Every change is also output as a row, with in_place set to false
+for the changes requiring the index to be reindexed into a new index with the new
+mappings, with 'indices reindex-plan' for example.
+
+Adding fields, adding multi-fields and changing updatable parameters such as
+ignore_above or search_analyzer are applied directly.
+`),
 			cmds.WithFlags(
 				parameters.NewParameterDefinition(
 					"index",
@@ -48,7 +63,7 @@ func NewUpdateMappingCommand() (*UpdateMappingCommand, error) {
 				),
 				parameters.NewParameterDefinition(
 					"mappings",
-					parameters.ParameterTypeFile,
+					parameters.ParameterTypeObjectFromFile,
 					parameters.WithHelp("JSON/YAML file containing updated index mappings"),
 					parameters.WithRequired(true),
 				),
@@ -75,6 +90,18 @@ func NewUpdateMappingCommand() (*UpdateMappingCommand, error) {
 					parameters.ParameterTypeBool,
 					parameters.WithHelp("Whether specified concrete indices should be ignored when unavailable (missing or closed)"),
 				),
+				parameters.NewParameterDefinition(
+					"skip_compatibility_check",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Send the update without checking that it can be applied in place, leaving it to Elasticsearch to reject it"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"non_interactive",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Update the mappings without asking for confirmation"),
+					parameters.WithDefault(false),
+				),
 			),
 			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
 		),
@@ -88,6 +115,8 @@ type UpdateMappingSettings struct {
 	AllowNoIndices    bool                   `glazed.parameter:"allow_no_indices"`
 	ExpandWildcards   []string               `glazed.parameter:"expand_wildcards"`
 	IgnoreUnavailable bool                   `glazed.parameter:"ignore_unavailable"`
+	SkipCompatibility bool                   `glazed.parameter:"skip_compatibility_check"`
+	NonInteractive    bool                   `glazed.parameter:"non_interactive"`
 }
 
 func (c *UpdateMappingCommand) RunIntoGlazeProcessor(
@@ -106,6 +135,58 @@ func (c *UpdateMappingCommand) RunIntoGlazeProcessor(
 	}
 
 	updateMappingRequest := s.Mappings
+	// accept files created from the output of get mappings or for create
+	if mappings_, ok := updateMappingRequest["mappings"].(map[string]interface{}); ok {
+		updateMappingRequest = mappings_
+	}
+
+	if !s.SkipCompatibility {
+		currentMappings, err := helpers.GetIndexMappings(ctx, es, s.Index)
+		if err != nil {
+			return errors.Wrapf(err, "could not get current mappings of %s", s.Index)
+		}
+
+		incompatibilities := []string{}
+		for _, index := range sortedIndexNames(currentMappings) {
+			ok, incompatibilities_ := mappings.IsCompatibleChange(currentMappings[index], updateMappingRequest)
+			if ok {
+				continue
+			}
+			for _, incompatibility := range incompatibilities_ {
+				incompatibilities = append(incompatibilities, "  "+index+": "+incompatibility.String())
+			}
+			for _, change := range mappings.DiffMappings(currentMappings[index], updateMappingRequest) {
+				row := types.NewRow(
+					types.MRP("index", index),
+					types.MRP("field", change.Field),
+					types.MRP("change", change.Description),
+					types.MRP("in_place", change.InPlace),
+				)
+				if err := gp.AddRow(ctx, row); err != nil {
+					return err
+				}
+			}
+		}
+		// the rows are lost when failing outside of streaming output, so the error
+		// lists the incompatibilities as well
+		if len(incompatibilities) > 0 {
+			return errors.Errorf(
+				"the mapping changes can't be applied in place to %s, reindex into a new index with the new mappings (see indices reindex-plan):\n%s",
+				s.Index, strings.Join(incompatibilities, "\n"))
+		}
+	}
+
+	ok, err := helpers.ConfirmAction(
+		os.Stderr,
+		fmt.Sprintf("Update the mappings of %s? Added fields can't be removed without reindexing.", s.Index),
+		s.NonInteractive,
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("aborted")
+	}
 
 	requestBody, err := json.Marshal(updateMappingRequest)
 	if err != nil {
@@ -152,3 +233,12 @@ func (c *UpdateMappingCommand) RunIntoGlazeProcessor(
 
 	return gp.AddRow(ctx, responseRow)
 }
+
+func sortedIndexNames[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
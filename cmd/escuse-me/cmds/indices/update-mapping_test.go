@@ -0,0 +1,58 @@
+package indices
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/escuse-me/pkg/estest"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+func TestUpdateMappingFailsOnIncompatibleChange(t *testing.T) {
+	server := estest.NewServer()
+	defer server.Close()
+	server.HandleMappings("logs-1", map[string]interface{}{
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "keyword"},
+		},
+	})
+
+	cmd, err := NewUpdateMappingCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := server.RunGlazeCommand(context.Background(), cmd, map[string]map[string]interface{}{
+		layers.DefaultSlug: {
+			"index":           "logs-1",
+			"non_interactive": true,
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"status":  map[string]interface{}{"type": "long"},
+					"message": map[string]interface{}{"type": "text"},
+				},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected the incompatible change to fail the command")
+	}
+	if !strings.Contains(err.Error(), "logs-1: status:") {
+		t.Errorf("expected the incompatible status field to be listed, got %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected a row for each change, got %d", len(rows))
+	}
+	for _, row := range rows {
+		field, _ := row.Get("field")
+		inPlace, _ := row.Get("in_place")
+		if inPlace != (field == "message") {
+			t.Errorf("unexpected in_place %v for %v", inPlace, field)
+		}
+	}
+	if n := len(server.RequestsTo(http.MethodPut, "/logs-1/_mapping")); n != 0 {
+		t.Errorf("expected no mapping update, got %d", n)
+	}
+}
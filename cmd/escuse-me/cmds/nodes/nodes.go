@@ -0,0 +1,26 @@
+package nodes
+
+import (
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	"github.com/spf13/cobra"
+)
+
+func AddToRootCommand(rootCmd *cobra.Command) error {
+	nodesCommand := &cobra.Command{
+		Use:   "nodes",
+		Short: "ES nodes related commands",
+	}
+	rootCmd.AddCommand(nodesCommand)
+
+	nodesStatsCommand, err := NewNodesStatsCommand()
+	if err != nil {
+		return err
+	}
+	nodesStatsCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(nodesStatsCommand)
+	if err != nil {
+		return err
+	}
+	nodesCommand.AddCommand(nodesStatsCmd)
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	metricJVM        = "jvm"
+	metricOS         = "os"
+	metricFS         = "fs"
+	metricIndices    = "indices"
+	metricThreadPool = "thread_pool"
+)
+
+// statsThreadPools are the thread pools whose queue and rejections are output, the
+// ones that back up first when a node is overloaded.
+var statsThreadPools = []string{"write", "search"}
+
+type NodesStatsCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &NodesStatsCommand{}
+
+func NewNodesStatsCommand() (*NodesStatsCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &NodesStatsCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"stats",
+			cmds.WithShort("Prints a summary of the resource usage of each node"),
+			cmds.WithLong(`
+Prints a row per node with its heap usage, CPU usage, available disk space, indexing and
+search activity, and the queue and rejection counts of the write and search thread pools.
+Only the columns of the requested --metric are output.
+
+Examples:
+
+   escuse-me nodes stats
+
+   escuse-me nodes stats --metric jvm,fs --node_id data-*
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"metric",
+					parameters.ParameterTypeChoiceList,
+					parameters.WithHelp("Metrics to output"),
+					parameters.WithChoices(metricJVM, metricOS, metricFS, metricIndices, metricThreadPool),
+					parameters.WithDefault([]string{metricJVM, metricOS, metricFS, metricThreadPool}),
+				),
+				parameters.NewParameterDefinition(
+					"node_id",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Nodes to output, by id, name, address or attribute (default: all nodes)"),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type NodesStatsSettings struct {
+	Metric []string `glazed.parameter:"metric"`
+	NodeID []string `glazed.parameter:"node_id"`
+}
+
+type nodesStatsResponse struct {
+	Nodes map[string]nodeStats `json:"nodes"`
+}
+
+type nodeStats struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	JVM  struct {
+		Mem struct {
+			HeapUsedPercent int64 `json:"heap_used_percent"`
+			HeapUsedInBytes int64 `json:"heap_used_in_bytes"`
+			HeapMaxInBytes  int64 `json:"heap_max_in_bytes"`
+		} `json:"mem"`
+	} `json:"jvm"`
+	OS struct {
+		CPU struct {
+			Percent     int64              `json:"percent"`
+			LoadAverage map[string]float64 `json:"load_average"`
+		} `json:"cpu"`
+	} `json:"os"`
+	FS struct {
+		Total struct {
+			TotalInBytes     int64 `json:"total_in_bytes"`
+			AvailableInBytes int64 `json:"available_in_bytes"`
+		} `json:"total"`
+	} `json:"fs"`
+	Indices struct {
+		Docs struct {
+			Count int64 `json:"count"`
+		} `json:"docs"`
+		Store struct {
+			SizeInBytes int64 `json:"size_in_bytes"`
+		} `json:"store"`
+		Indexing struct {
+			IndexTotal int64 `json:"index_total"`
+		} `json:"indexing"`
+		Search struct {
+			QueryTotal int64 `json:"query_total"`
+		} `json:"search"`
+	} `json:"indices"`
+	ThreadPool map[string]struct {
+		Threads  int64 `json:"threads"`
+		Queue    int64 `json:"queue"`
+		Active   int64 `json:"active"`
+		Rejected int64 `json:"rejected"`
+	} `json:"thread_pool"`
+}
+
+func (c *NodesStatsCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *NodesStatsCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &NodesStatsSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.NodesStatsRequest){
+		es.Nodes.Stats.WithContext(ctx),
+		es.Nodes.Stats.WithMetric(s.Metric...),
+	}
+	if len(s.NodeID) > 0 {
+		options = append(options, es.Nodes.Stats.WithNodeID(s.NodeID...))
+	}
+
+	res, err := es.Nodes.Stats(options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	response := &nodesStatsResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return err
+	}
+
+	metrics := map[string]bool{}
+	for _, metric := range s.Metric {
+		metrics[metric] = true
+	}
+
+	nodeIDs := make([]string, 0, len(response.Nodes))
+	for nodeID := range response.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Slice(nodeIDs, func(i, j int) bool {
+		return response.Nodes[nodeIDs[i]].Name < response.Nodes[nodeIDs[j]].Name
+	})
+
+	for _, nodeID := range nodeIDs {
+		if err := gp.AddRow(ctx, nodeStatsRow(nodeID, response.Nodes[nodeID], metrics)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nodeStatsRow(nodeID string, node nodeStats, metrics map[string]bool) types.Row {
+	row := types.NewRow(
+		types.MRP("node", node.Name),
+		types.MRP("node_id", nodeID),
+		types.MRP("host", node.Host),
+	)
+	if metrics[metricJVM] {
+		row.Set("heap_used_percent", node.JVM.Mem.HeapUsedPercent)
+		row.Set("heap_used_bytes", node.JVM.Mem.HeapUsedInBytes)
+		row.Set("heap_max_bytes", node.JVM.Mem.HeapMaxInBytes)
+	}
+	if metrics[metricOS] {
+		row.Set("cpu_percent", node.OS.CPU.Percent)
+		// the load average is not available on Windows
+		if load, ok := node.OS.CPU.LoadAverage["1m"]; ok {
+			row.Set("load_1m", load)
+		}
+	}
+	if metrics[metricFS] {
+		row.Set("fs_available_bytes", node.FS.Total.AvailableInBytes)
+		row.Set("fs_total_bytes", node.FS.Total.TotalInBytes)
+	}
+	if metrics[metricIndices] {
+		row.Set("docs", node.Indices.Docs.Count)
+		row.Set("store_bytes", node.Indices.Store.SizeInBytes)
+		row.Set("index_total", node.Indices.Indexing.IndexTotal)
+		row.Set("query_total", node.Indices.Search.QueryTotal)
+	}
+	if metrics[metricThreadPool] {
+		for _, pool := range statsThreadPools {
+			stats := node.ThreadPool[pool]
+			row.Set(pool+"_queue", stats.Queue)
+			row.Set(pool+"_rejected", stats.Rejected)
+		}
+	}
+	return row
+}
@@ -0,0 +1,204 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/pkg/errors"
+)
+
+// RequestCommand sends an arbitrary request to the cluster, for the APIs that have no
+// dedicated command.
+type RequestCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.WriterCommand = &RequestCommand{}
+
+func NewRequestCommand() (*RequestCommand, error) {
+	// the response is printed as is, so messages only go to stderr if asked for
+	esParameterLayer, err := es_layers.NewESParameterLayer(
+		layers.WithDefaults(map[string]interface{}{"output-summary-to-stderr": false}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &RequestCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"request",
+			cmds.WithShort("Sends an arbitrary request to the cluster and prints the response"),
+			cmds.WithLong(`
+Sends a request with --method to --path, with the connection settings of every other
+command (addresses, credentials, TLS, headers), and prints the response body. JSON
+responses are pretty-printed unless --raw is given.
+
+--path can contain a query string. The global --query-param flag adds parameters as well.
+Bodies of _bulk and _msearch requests are sent as NDJSON.
+
+The command fails if the response status is 400 or above, after printing the response.
+
+Examples:
+
+   escuse-me request --path '/_cluster/settings?include_defaults=true'
+
+   escuse-me request --method PUT --path /_ilm/policy/logs --body_file policy.json
+
+   escuse-me request --path /_cat/shards --query-param v=true
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"method",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("HTTP method of the request"),
+					parameters.WithChoices(
+						http.MethodGet, http.MethodPost, http.MethodPut,
+						http.MethodDelete, http.MethodHead, http.MethodPatch,
+					),
+					parameters.WithDefault(http.MethodGet),
+				),
+				parameters.NewParameterDefinition(
+					"path",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Path of the request, optionally with a query string"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"body",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Body of the request"),
+				),
+				parameters.NewParameterDefinition(
+					"body_file",
+					parameters.ParameterTypeStringFromFile,
+					parameters.WithHelp("File containing the body of the request (- for stdin)"),
+				),
+				parameters.NewParameterDefinition(
+					"raw",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Print the response body as is, without pretty-printing JSON"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(esParameterLayer),
+		),
+	}, nil
+}
+
+type RequestSettings struct {
+	Method   string `glazed.parameter:"method"`
+	Path     string `glazed.parameter:"path"`
+	Body     string `glazed.parameter:"body"`
+	BodyFile string `glazed.parameter:"body_file"`
+	Raw      bool   `glazed.parameter:"raw"`
+}
+
+func (c *RequestCommand) RunIntoWriter(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	w io.Writer,
+) error {
+	s := &RequestSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	if s.Body != "" && s.BodyFile != "" {
+		return errors.New("--body and --body_file are mutually exclusive")
+	}
+	body := s.Body
+	if s.BodyFile != "" {
+		body = s.BodyFile
+	}
+
+	ctx, cancel, err := es_cmds.WithCommandTimeout(ctx, parsedLayers)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	restoreSummaryWriter, err := es_cmds.WithSummaryWriter(parsedLayers, w)
+	if err != nil {
+		return err
+	}
+	defer restoreSummaryWriter()
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	path := s.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	var requestBody io.Reader
+	if body != "" {
+		requestBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, s.Method, path, requestBody)
+	if err != nil {
+		return err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", requestContentType(req.URL.Path))
+	}
+
+	// the request goes through the transport directly, so that it also works against
+	// clusters that fail the client's product check
+	res, err := es.Transport.Perform(req)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if !s.Raw && json.Valid(responseBody) {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, responseBody, "", "  "); err != nil {
+			return err
+		}
+		responseBody = buf.Bytes()
+	}
+	if _, err := w.Write(responseBody); err != nil {
+		return err
+	}
+	if len(responseBody) > 0 && !bytes.HasSuffix(responseBody, []byte("\n")) {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("request failed: %s", res.Status)
+	}
+	return nil
+}
+
+// requestContentType returns the content type of the body of a request to path, bulk
+// style APIs expecting NDJSON.
+func requestContentType(path string) string {
+	for _, suffix := range []string{"/_bulk", "/_msearch", "/_msearch/template"} {
+		if strings.HasSuffix(path, suffix) {
+			return "application/x-ndjson"
+		}
+	}
+	return "application/json"
+}
@@ -0,0 +1,159 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type TasksCancelCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &TasksCancelCommand{}
+
+func NewTasksCancelCommand() (*TasksCancelCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &TasksCancelCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"cancel",
+			cmds.WithShort("Cancels tasks"),
+			cmds.WithLong(`
+Cancels the task --task_id, or the cancellable tasks matching --actions, --nodes and
+--parent_task_id, and prints a row per cancelled task. At least one of these flags is
+required, so as not to cancel every task of the cluster by mistake.
+
+Cancelling a task only marks it as cancelled, the task stops at its next cancellation
+check. --wait_for_completion waits for the cancelled tasks to stop.
+
+Examples:
+
+   escuse-me tasks cancel --task_id oTUltX4IQMOUUVeiohTt8A:12345
+
+   escuse-me tasks cancel --actions '*reindex' --wait_for_completion
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"task_id",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Id of the task to cancel, as node:id"),
+				),
+				parameters.NewParameterDefinition(
+					"actions",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Actions of the tasks to cancel, with wildcards"),
+				),
+				parameters.NewParameterDefinition(
+					"nodes",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Nodes to cancel the tasks of, by id, name, address or attribute"),
+				),
+				parameters.NewParameterDefinition(
+					"parent_task_id",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Cancel the child tasks of this task"),
+				),
+				parameters.NewParameterDefinition(
+					"wait_for_completion",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Wait for the cancelled tasks to stop"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type TasksCancelSettings struct {
+	TaskID            string   `glazed.parameter:"task_id"`
+	Actions           []string `glazed.parameter:"actions"`
+	Nodes             []string `glazed.parameter:"nodes"`
+	ParentTaskID      string   `glazed.parameter:"parent_task_id"`
+	WaitForCompletion bool     `glazed.parameter:"wait_for_completion"`
+}
+
+func (c *TasksCancelCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &TasksCancelSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	if s.TaskID == "" && len(s.Actions) == 0 && len(s.Nodes) == 0 && s.ParentTaskID == "" {
+		return errors.New("one of --task_id, --actions, --nodes or --parent_task_id is required")
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.TasksCancelRequest){
+		es.Tasks.Cancel.WithContext(ctx),
+		es.Tasks.Cancel.WithWaitForCompletion(s.WaitForCompletion),
+	}
+	if s.TaskID != "" {
+		options = append(options, es.Tasks.Cancel.WithTaskID(s.TaskID))
+	}
+	if len(s.Actions) > 0 {
+		options = append(options, es.Tasks.Cancel.WithActions(s.Actions...))
+	}
+	if len(s.Nodes) > 0 {
+		options = append(options, es.Tasks.Cancel.WithNodes(s.Nodes...))
+	}
+	if s.ParentTaskID != "" {
+		options = append(options, es.Tasks.Cancel.WithParentTaskID(s.ParentTaskID))
+	}
+
+	res, err := es.Tasks.Cancel(options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	// the cancel API responds like the list API grouped by nodes
+	response := &tasksListResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return err
+	}
+	logTasksFailures(response.NodeFailures, response.TaskFailures)
+
+	return addNodesTasksRows(ctx, gp, response.Nodes)
+}
@@ -0,0 +1,143 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+type TasksGetCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &TasksGetCommand{}
+
+func NewTasksGetCommand() (*TasksGetCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &TasksGetCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"get",
+			cmds.WithShort("Prints a task, and its result once completed"),
+			cmds.WithLong(`
+Prints the task with its status, whether it has completed, and once completed, the
+response or the error of the operation it ran. The result of tasks started with
+wait_for_completion=false is kept by ES in the .tasks index.
+
+Examples:
+
+   escuse-me tasks get --task_id oTUltX4IQMOUUVeiohTt8A:12345
+
+   escuse-me tasks get --task_id oTUltX4IQMOUUVeiohTt8A:12345 --wait_for_completion --output json
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"task_id",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Id of the task, as node:id"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"wait_for_completion",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Wait for the task to complete"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type TasksGetSettings struct {
+	TaskID            string `glazed.parameter:"task_id"`
+	WaitForCompletion bool   `glazed.parameter:"wait_for_completion"`
+}
+
+type tasksGetResponse struct {
+	Completed bool                   `json:"completed"`
+	Task      helpers.TaskInfo       `json:"task"`
+	Response  map[string]interface{} `json:"response,omitempty"`
+	Error     *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+func (c *TasksGetCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *TasksGetCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &TasksGetSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.TasksGetRequest){
+		es.Tasks.Get.WithContext(ctx),
+		es.Tasks.Get.WithWaitForCompletion(s.WaitForCompletion),
+	}
+
+	res, err := es.Tasks.Get(s.TaskID, options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	response := &tasksGetResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return err
+	}
+
+	row := response.Task.Row()
+	row.Set("completed", response.Completed)
+	if response.Response != nil {
+		row.Set("response", response.Response)
+	}
+	if response.Error != nil {
+		row.Set("error", response.Error.Type+": "+response.Error.Reason)
+	}
+	return gp.AddRow(ctx, row)
+}
@@ -0,0 +1,276 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	groupByNodes   = "nodes"
+	groupByParents = "parents"
+	groupByNone    = "none"
+)
+
+type TasksListCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = &TasksListCommand{}
+
+func NewTasksListCommand() (*TasksListCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create Glazed parameter layer")
+	}
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create ES parameter layer")
+	}
+
+	return &TasksListCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"list",
+			cmds.WithShort("Lists the tasks running on the cluster"),
+			cmds.WithLong(`
+Prints a row per task running on the cluster, with its id, action, start time and running
+time. --detailed adds the description of the tasks and the status of the tasks reporting
+one, such as the progress of reindex and delete-by-query tasks.
+
+With --group_by parents, the child tasks are output right after their parent. With
+--group_by nodes, the name of the node running each task is added.
+
+Examples:
+
+   escuse-me tasks list --actions '*reindex' --detailed
+
+   escuse-me tasks list --nodes data-* --group_by none --output json
+`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"actions",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Actions of the tasks to list, with wildcards (e.g. *reindex, indices:data/write/*)"),
+				),
+				parameters.NewParameterDefinition(
+					"nodes",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Nodes to list the tasks of, by id, name, address or attribute (default: all nodes)"),
+				),
+				parameters.NewParameterDefinition(
+					"detailed",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Include the description and status of the tasks"),
+					parameters.WithDefault(false),
+				),
+				parameters.NewParameterDefinition(
+					"group_by",
+					parameters.ParameterTypeChoice,
+					parameters.WithHelp("How ES groups the tasks"),
+					parameters.WithChoices(groupByNodes, groupByParents, groupByNone),
+					parameters.WithDefault(groupByNodes),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer, esParameterLayer),
+		),
+	}, nil
+}
+
+type TasksListSettings struct {
+	Actions  []string `glazed.parameter:"actions"`
+	Nodes    []string `glazed.parameter:"nodes"`
+	Detailed bool     `glazed.parameter:"detailed"`
+	GroupBy  string   `glazed.parameter:"group_by"`
+}
+
+// tasksNode is a node of the responses grouped by nodes, used by the list and cancel
+// APIs.
+type tasksNode struct {
+	Name  string                      `json:"name"`
+	Host  string                      `json:"host"`
+	Tasks map[string]helpers.TaskInfo `json:"tasks"`
+}
+
+type tasksFailure struct {
+	TaskID int64  `json:"task_id"`
+	NodeID string `json:"node_id"`
+	Status string `json:"status"`
+	Reason struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"reason"`
+}
+
+type tasksNodeFailure struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type tasksListResponse struct {
+	Nodes        map[string]tasksNode `json:"nodes"`
+	Tasks        json.RawMessage      `json:"tasks"`
+	NodeFailures []tasksNodeFailure   `json:"node_failures"`
+	TaskFailures []tasksFailure       `json:"task_failures"`
+}
+
+func (c *TasksListCommand) IsIdempotent() bool {
+	return true
+}
+
+func (c *TasksListCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &TasksListSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	es, err := es_layers.NewESClientFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	options := []func(*esapi.TasksListRequest){
+		es.Tasks.List.WithContext(ctx),
+		es.Tasks.List.WithDetailed(s.Detailed),
+		es.Tasks.List.WithGroupBy(s.GroupBy),
+	}
+	if len(s.Actions) > 0 {
+		options = append(options, es.Tasks.List.WithActions(s.Actions...))
+	}
+	if len(s.Nodes) > 0 {
+		options = append(options, es.Tasks.List.WithNodes(s.Nodes...))
+	}
+
+	res, err := es.Tasks.List(options...)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	err_, isError := helpers.ParseErrorResponse(body)
+	if isError {
+		row := types.NewRowFromStruct(err_.Error, true)
+		row.Set("status", err_.Status)
+		return gp.AddRow(ctx, row)
+	}
+
+	response := &tasksListResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return err
+	}
+	logTasksFailures(response.NodeFailures, response.TaskFailures)
+
+	switch s.GroupBy {
+	case groupByNodes:
+		return addNodesTasksRows(ctx, gp, response.Nodes)
+
+	case groupByParents:
+		tasks := map[string]helpers.TaskInfo{}
+		if len(response.Tasks) > 0 {
+			if err := json.Unmarshal(response.Tasks, &tasks); err != nil {
+				return err
+			}
+		}
+		for _, task := range sortTasks(mapValues(tasks)) {
+			if err := gp.AddRow(ctx, task.Row()); err != nil {
+				return err
+			}
+			for _, child := range sortTasks(task.Children) {
+				if err := gp.AddRow(ctx, child.Row()); err != nil {
+					return err
+				}
+			}
+		}
+
+	default:
+		tasks := []helpers.TaskInfo{}
+		if len(response.Tasks) > 0 {
+			if err := json.Unmarshal(response.Tasks, &tasks); err != nil {
+				return err
+			}
+		}
+		for _, task := range sortTasks(tasks) {
+			if err := gp.AddRow(ctx, task.Row()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addNodesTasksRows outputs the tasks of a response grouped by nodes, adding the name
+// of their node.
+func addNodesTasksRows(ctx context.Context, gp middlewares.Processor, nodes map[string]tasksNode) error {
+	var tasks []helpers.TaskInfo
+	nodeNames := map[string]string{}
+	for nodeID, node := range nodes {
+		nodeNames[nodeID] = node.Name
+		tasks = append(tasks, mapValues(node.Tasks)...)
+	}
+
+	for _, task := range sortTasks(tasks) {
+		row := task.Row()
+		row.Set("node_name", nodeNames[task.Node])
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func logTasksFailures(nodeFailures []tasksNodeFailure, taskFailures []tasksFailure) {
+	for _, failure := range nodeFailures {
+		log.Warn().Str("type", failure.Type).Str("reason", failure.Reason).Msg("node failure")
+	}
+	for _, failure := range taskFailures {
+		log.Warn().
+			Str("node", failure.NodeID).
+			Int64("task", failure.TaskID).
+			Str("type", failure.Reason.Type).
+			Str("reason", failure.Reason.Reason).
+			Msg("task failure")
+	}
+}
+
+// sortTasks sorts tasks by start time, oldest first.
+func sortTasks(tasks []helpers.TaskInfo) []helpers.TaskInfo {
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].StartTimeInMillis != tasks[j].StartTimeInMillis {
+			return tasks[i].StartTimeInMillis < tasks[j].StartTimeInMillis
+		}
+		return tasks[i].TaskID() < tasks[j].TaskID()
+	})
+	return tasks
+}
+
+func mapValues(tasks map[string]helpers.TaskInfo) []helpers.TaskInfo {
+	ret := make([]helpers.TaskInfo, 0, len(tasks))
+	for _, task := range tasks {
+		ret = append(ret, task)
+	}
+	return ret
+}
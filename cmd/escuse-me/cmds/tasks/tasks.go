@@ -0,0 +1,46 @@
+package tasks
+
+import (
+	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
+	"github.com/spf13/cobra"
+)
+
+func AddToRootCommand(rootCmd *cobra.Command) error {
+	tasksCommand := &cobra.Command{
+		Use:   "tasks",
+		Short: "ES tasks related commands",
+	}
+	rootCmd.AddCommand(tasksCommand)
+
+	tasksListCommand, err := NewTasksListCommand()
+	if err != nil {
+		return err
+	}
+	tasksListCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(tasksListCommand)
+	if err != nil {
+		return err
+	}
+	tasksCommand.AddCommand(tasksListCmd)
+
+	tasksGetCommand, err := NewTasksGetCommand()
+	if err != nil {
+		return err
+	}
+	tasksGetCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(tasksGetCommand)
+	if err != nil {
+		return err
+	}
+	tasksCommand.AddCommand(tasksGetCmd)
+
+	tasksCancelCommand, err := NewTasksCancelCommand()
+	if err != nil {
+		return err
+	}
+	tasksCancelCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(tasksCancelCommand)
+	if err != nil {
+		return err
+	}
+	tasksCommand.AddCommand(tasksCancelCmd)
+
+	return nil
+}
@@ -9,8 +9,12 @@ import (
 	"github.com/go-go-golems/clay/pkg/repositories"
 	"github.com/go-go-golems/clay/pkg/sql"
 	cli_cmds "github.com/go-go-golems/escuse-me/cmd/escuse-me/cmds"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/cmds/connection"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/cmds/documents"
 	"github.com/go-go-golems/escuse-me/cmd/escuse-me/cmds/indices"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/cmds/nodes"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/cmds/tasks"
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
 	es_cmds "github.com/go-go-golems/escuse-me/pkg/cmds"
 	"github.com/go-go-golems/escuse-me/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cli"
@@ -46,7 +50,7 @@ func main() {
 		fi, err := os.Stat(os.Args[2])
 		cobra.CheckErr(err)
 		if !fi.IsDir() {
-			fmt.Printf("Expected directory, got file")
+			_, _ = fmt.Fprintln(os.Stderr, "Expected directory, got file")
 			os.Exit(1)
 		}
 
@@ -72,17 +76,17 @@ func main() {
 			options, aliasOptions,
 		)
 		if err != nil {
-			fmt.Printf("Could not load command: %v\n", err)
+			_, _ = fmt.Fprintf(os.Stderr, "Could not load command: %v\n", err)
 			os.Exit(1)
 		}
 		if len(cmds) != 1 {
-			fmt.Printf("Expected exactly one command, got %d", len(cmds))
+			_, _ = fmt.Fprintf(os.Stderr, "Expected exactly one command, got %d\n", len(cmds))
 			os.Exit(1)
 		}
 
 		cobraCommand, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(cmds[0])
 		if err != nil {
-			fmt.Printf("Could not build cobra command: %v\n", err)
+			_, _ = fmt.Fprintf(os.Stderr, "Could not build cobra command: %v\n", err)
 			os.Exit(1)
 		}
 
@@ -137,6 +141,11 @@ func initRootCmd() (*help.HelpSystem, error) {
 		os.Exit(1)
 	}
 
+	rootCmd.PersistentFlags().BoolVar(
+		&helpers.AssumeYes, "yes", false,
+		"Answer yes to every confirmation of destructive commands",
+	)
+
 	rootCmd.AddCommand(runCommandCmd)
 	return helpSystem, nil
 
@@ -234,6 +243,16 @@ func initAllCommands(helpSystem *help.HelpSystem) error {
 	}
 	rootCmd.AddCommand(infoCmd)
 
+	requestCommand, err := cli_cmds.NewRequestCommand()
+	if err != nil {
+		return err
+	}
+	requestCmd, err := es_cmds.BuildCobraCommandWithEscuseMeMiddlewares(requestCommand)
+	if err != nil {
+		return err
+	}
+	rootCmd.AddCommand(requestCmd)
+
 	serveCommand, err := cli_cmds.NewServeCommand(repositoryPaths)
 	if err != nil {
 		return err
@@ -254,6 +273,21 @@ func initAllCommands(helpSystem *help.HelpSystem) error {
 		return err
 	}
 
+	err = connection.AddToRootCommand(rootCmd)
+	if err != nil {
+		return err
+	}
+
+	err = nodes.AddToRootCommand(rootCmd)
+	if err != nil {
+		return err
+	}
+
+	err = tasks.AddToRootCommand(rootCmd)
+	if err != nil {
+		return err
+	}
+
 	listCommandsCommand, err := ls_commands.NewListCommandsCommand(allCommands,
 		ls_commands.WithCommandDescriptionOptions(
 			glazed_cmds.WithShort("Commands related to sqleton queries"),
@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/pkg/errors"
+)
+
+const (
+	ClusterTypeElasticsearch = "elasticsearch"
+	ClusterTypeOpenSearch    = "opensearch"
+)
+
+// GetClusterInfo returns the response of the root info endpoint of the cluster.
+//
+// The request goes through the transport directly: the client refuses to talk to
+// clusters that don't identify as Elasticsearch, which would prevent detecting them.
+func GetClusterInfo(ctx context.Context, es *elasticsearch.Client) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Transport.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, errors.Errorf("could not get cluster info: %s", res.Status)
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, errors.Wrap(err, "could not parse cluster info")
+	}
+	return info, nil
+}
+
+// ClusterTypeFromInfo returns the flavor of the cluster, elasticsearch or opensearch,
+// and its version, from the response of the root info endpoint.
+func ClusterTypeFromInfo(info map[string]interface{}) (string, string) {
+	clusterType := ClusterTypeElasticsearch
+	version_, _ := info["version"].(map[string]interface{})
+	if distribution, _ := version_["distribution"].(string); strings.EqualFold(distribution, ClusterTypeOpenSearch) {
+		clusterType = ClusterTypeOpenSearch
+	}
+	if tagline, _ := info["tagline"].(string); strings.Contains(strings.ToLower(tagline), ClusterTypeOpenSearch) {
+		clusterType = ClusterTypeOpenSearch
+	}
+
+	number, _ := version_["number"].(string)
+	return clusterType, number
+}
+
+// DetectClusterType returns the flavor of the cluster, elasticsearch or opensearch,
+// and its version.
+func DetectClusterType(ctx context.Context, es *elasticsearch.Client) (string, string, error) {
+	info, err := GetClusterInfo(ctx, es)
+	if err != nil {
+		return "", "", err
+	}
+	clusterType, version := ClusterTypeFromInfo(info)
+	return clusterType, version, nil
+}
+
+// MajorVersion returns the major version of a version number such as 8.11.0.
+func MajorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+	ret, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, errors.Errorf("could not parse version %s", version)
+	}
+	return ret, nil
+}
@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AssumeYes is set by the global --yes flag, and answers yes to every confirmation.
+var AssumeYes bool
+
+// ConfirmAction asks the user on stdin to confirm a destructive operation described by
+// prompt, writing the prompt to w, and returns true if the answer is yes. Closing stdin
+// without answering counts as no.
+//
+// No question is asked if nonInteractive or the global --yes flag is set. Otherwise
+// ConfirmAction fails if stdin is not a terminal, so that scripts and piped input have
+// to opt out of the confirmation explicitly instead of consuming their input as answer.
+func ConfirmAction(w io.Writer, prompt string, nonInteractive bool) (bool, error) {
+	if nonInteractive || AssumeYes {
+		return true, nil
+	}
+
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false, err
+	}
+	if stat.Mode()&os.ModeCharDevice == 0 {
+		return false, errors.New("stdin is not a terminal, can't ask for confirmation, use --yes to skip it")
+	}
+
+	_, _ = fmt.Fprintf(w, "%s [y/N] ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err == io.EOF {
+		_, _ = fmt.Fprintln(w)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DashboardSection is a titled block of a dashboard, whose rows are rendered as
+// left-aligned columns.
+type DashboardSection struct {
+	Title string
+	Rows  [][]string
+}
+
+// RenderDashboard writes the sections as a compact box-drawn summary, meant for
+// operators reading it in a terminal.
+func RenderDashboard(w io.Writer, sections []DashboardSection) error {
+	lines := make([][]string, len(sections))
+	width := 0
+	for i, section := range sections {
+		lines[i] = formatDashboardRows(section.Rows)
+		width = max(width, utf8.RuneCountInString(section.Title)+3)
+		for _, line := range lines[i] {
+			width = max(width, utf8.RuneCountInString(line))
+		}
+	}
+
+	sb := strings.Builder{}
+	for i, section := range sections {
+		left, right := "├", "┤"
+		if i == 0 {
+			left, right = "┌", "┐"
+		}
+		title := "─ " + section.Title + " "
+		sb.WriteString(left + title + strings.Repeat("─", width+2-utf8.RuneCountInString(title)) + right + "\n")
+		for _, line := range lines[i] {
+			sb.WriteString("│ " + line + strings.Repeat(" ", width-utf8.RuneCountInString(line)) + " │\n")
+		}
+	}
+	sb.WriteString("└" + strings.Repeat("─", width+2) + "┘\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func formatDashboardRows(rows [][]string) []string {
+	widths := []int{}
+	for _, row := range rows {
+		for j, cell := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], utf8.RuneCountInString(cell))
+		}
+	}
+
+	ret := make([]string, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			if j == len(row)-1 {
+				cells[j] = cell
+				continue
+			}
+			cells[j] = cell + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+		}
+		ret = append(ret, strings.Join(cells, "  "))
+	}
+	return ret
+}
+
+// FormatBytes formats a size in bytes using binary units (1.5gb).
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%db", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cb", float64(b)/float64(div), "kmgtpe"[exp])
+}
@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDedupMaxKeys bounds the memory used to remember the keys already output.
+const DefaultDedupMaxKeys = 1_000_000
+
+// DedupMiddleware drops the rows whose value for Field has already been output, keeping
+// the first occurrence. Rows without the field are always kept.
+//
+// At most MaxKeys keys are remembered. Once the limit is reached, new keys are no longer
+// recorded, so that duplicates of rows output after that point are not dropped.
+type DedupMiddleware struct {
+	Field   string
+	MaxKeys int
+
+	seen    map[string]struct{}
+	dropped int
+	full    bool
+}
+
+var _ middlewares.RowMiddleware = (*DedupMiddleware)(nil)
+
+func NewDedupMiddleware(field string, maxKeys int) *DedupMiddleware {
+	return &DedupMiddleware{
+		Field:   field,
+		MaxKeys: maxKeys,
+		seen:    map[string]struct{}{},
+	}
+}
+
+// IsNoop returns true if no field to deduplicate by is set.
+func (m *DedupMiddleware) IsNoop() bool {
+	return m.Field == ""
+}
+
+func (m *DedupMiddleware) Close(ctx context.Context) error {
+	if m.dropped > 0 {
+		log.Info().Str("field", m.Field).Int("dropped", m.dropped).Msg("dropped duplicate rows")
+	}
+	return nil
+}
+
+func (m *DedupMiddleware) Process(ctx context.Context, row types.Row) ([]types.Row, error) {
+	if m.IsNoop() {
+		return []types.Row{row}, nil
+	}
+
+	value, ok := row.Get(m.Field)
+	if !ok {
+		return []types.Row{row}, nil
+	}
+	// values can be lists or objects, which can't be used as map keys
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	key := string(b)
+
+	if _, ok := m.seen[key]; ok {
+		m.dropped++
+		return nil, nil
+	}
+
+	if len(m.seen) >= m.MaxKeys {
+		if !m.full {
+			m.full = true
+			log.Warn().
+				Str("field", m.Field).
+				Int("max_keys", m.MaxKeys).
+				Msg("too many distinct values to deduplicate, later duplicates are not dropped")
+		}
+		return []types.Row{row}, nil
+	}
+	m.seen[key] = struct{}{}
+
+	return []types.Row{row}, nil
+}
@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+// DocumentTransformMiddleware applies client-side transformations to emitted documents,
+// in order to reduce the size of exports or adapt them for re-import into a differently
+// named target.
+//
+// - DropEmpty removes fields that are null, empty strings, empty arrays or empty objects
+// - Renames renames top-level fields (old name -> new name)
+type DocumentTransformMiddleware struct {
+	DropEmpty bool
+	Renames   map[string]string
+}
+
+var _ middlewares.RowMiddleware = (*DocumentTransformMiddleware)(nil)
+
+func NewDocumentTransformMiddleware(dropEmpty bool, renames map[string]string) *DocumentTransformMiddleware {
+	if renames == nil {
+		renames = map[string]string{}
+	}
+	return &DocumentTransformMiddleware{
+		DropEmpty: dropEmpty,
+		Renames:   renames,
+	}
+}
+
+// NewDocumentTransformMiddlewareFromKeyValue creates a DocumentTransformMiddleware from the
+// map[string]interface{} returned by glazed keyValue parameters.
+func NewDocumentTransformMiddlewareFromKeyValue(
+	dropEmpty bool,
+	renames map[string]interface{},
+) *DocumentTransformMiddleware {
+	renames_ := map[string]string{}
+	for k, v := range renames {
+		if s, ok := v.(string); ok {
+			renames_[k] = s
+		}
+	}
+	return NewDocumentTransformMiddleware(dropEmpty, renames_)
+}
+
+// IsNoop returns true if the middleware doesn't transform anything and can be skipped.
+func (d *DocumentTransformMiddleware) IsNoop() bool {
+	return !d.DropEmpty && len(d.Renames) == 0
+}
+
+func (d *DocumentTransformMiddleware) Close(ctx context.Context) error {
+	return nil
+}
+
+func (d *DocumentTransformMiddleware) Process(ctx context.Context, row types.Row) ([]types.Row, error) {
+	newRow := types.NewRow()
+
+	for pair := row.Oldest(); pair != nil; pair = pair.Next() {
+		key, value := pair.Key, pair.Value
+		if d.DropEmpty && IsEmptyValue(value) {
+			continue
+		}
+		if newKey, ok := d.Renames[key]; ok {
+			key = newKey
+		}
+		newRow.Set(key, value)
+	}
+
+	return []types.Row{newRow}, nil
+}
+
+// IsEmptyValue returns true for nil, empty strings, empty arrays and empty objects.
+func IsEmptyValue(v interface{}) bool {
+	switch v_ := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v_ == ""
+	case []interface{}:
+		return len(v_) == 0
+	case map[string]interface{}:
+		return len(v_) == 0
+	case types.Row:
+		return v_.Len() == 0
+	default:
+		return false
+	}
+}
@@ -1,6 +1,15 @@
 package helpers
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
 
 type ElasticsearchError struct {
 	Error struct {
@@ -18,6 +27,45 @@ type ElasticsearchError struct {
 	Status int `json:"status"`
 }
 
+// ResponseError returns the error response as an error carrying its HTTP status.
+func (e *ElasticsearchError) ResponseError() *ESResponseError {
+	return &ESResponseError{
+		Status: e.Status,
+		Type:   e.Error.Type,
+		Reason: e.Error.Reason,
+	}
+}
+
+// ESResponseError is an error response returned by Elasticsearch. Use errors.As to
+// check its Status, for example to decide whether a failed request can be retried.
+type ESResponseError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *ESResponseError) Error() string {
+	return fmt.Sprintf("[%d] %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// IsTransient returns true for the 429, 502, 503 and 504 statuses, which are worth retrying.
+func (e *ESResponseError) IsTransient() bool {
+	return IsTransientStatus(e.Status)
+}
+
+// IsTransientStatus returns true for the 429, 502, 503 and 504 HTTP statuses, reporting
+// an overloaded or temporarily unavailable server.
+func IsTransientStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
 // ParseErrorResponse parses the JSON response and checks for the error schema.
 func ParseErrorResponse(jsonData []byte) (*ElasticsearchError, bool) {
 	var esError ElasticsearchError
@@ -29,3 +77,39 @@ func ParseErrorResponse(jsonData []byte) (*ElasticsearchError, bool) {
 	}
 	return &esError, true
 }
+
+// ErrorFromResponse returns an *ESResponseError if jsonData is an Elasticsearch error
+// response, and nil otherwise.
+func ErrorFromResponse(jsonData []byte) error {
+	esError, isError := ParseErrorResponse(jsonData)
+	if !isError {
+		return nil
+	}
+	return esError.ResponseError()
+}
+
+// IsTransientError returns true for network errors and for Elasticsearch error responses
+// with a 429, 502, 503 or 504 status, which are worth retrying.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var responseError *ESResponseError
+	if errors.As(err, &responseError) {
+		return responseError.IsTransient()
+	}
+
+	return false
+}
@@ -0,0 +1,293 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// CountDocuments returns the number of documents in index matching query, or all
+// documents if query is nil.
+func CountDocuments(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+	query map[string]interface{},
+) (int64, error) {
+	options := []func(*esapi.CountRequest){
+		es.Count.WithContext(ctx),
+		es.Count.WithIndex(index),
+	}
+	if query != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"query": query}); err != nil {
+			return 0, err
+		}
+		options = append(options, es.Count.WithBody(&buf))
+	}
+
+	res, err := es.Count(options...)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, err
+	}
+
+	return response.Count, nil
+}
+
+// RefreshIndex refreshes index, making all recent changes visible to search.
+func RefreshIndex(ctx context.Context, es *elasticsearch.Client, index string) error {
+	res, err := es.Indices.Refresh(
+		es.Indices.Refresh.WithContext(ctx),
+		es.Indices.Refresh.WithIndex(index),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetIndexSettings returns the flat settings of the indices matching index, by index name.
+// Settings that are not set explicitly are not returned.
+func GetIndexSettings(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+) (map[string]map[string]interface{}, error) {
+	res, err := es.Indices.GetSettings(
+		es.Indices.GetSettings.WithContext(ctx),
+		es.Indices.GetSettings.WithIndex(index),
+		es.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	var response map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]interface{}, len(response))
+	for index_, v := range response {
+		ret[index_] = v.Settings
+	}
+	return ret, nil
+}
+
+// GetIndexMappings returns the mappings of the indices matching index, by index name.
+func GetIndexMappings(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+) (map[string]map[string]interface{}, error) {
+	res, err := es.Indices.GetMapping(
+		es.Indices.GetMapping.WithContext(ctx),
+		es.Indices.GetMapping.WithIndex(index),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	var response map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]interface{}, len(response))
+	for index_, v := range response {
+		ret[index_] = v.Mappings
+	}
+	return ret, nil
+}
+
+// GetIndexAliases returns the aliases of the indices matching index, along with their
+// definition (filter, routing, is_write_index), by index name.
+func GetIndexAliases(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+) (map[string]map[string]map[string]interface{}, error) {
+	res, err := es.Indices.GetAlias(
+		es.Indices.GetAlias.WithContext(ctx),
+		es.Indices.GetAlias.WithIndex(index),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	var response map[string]struct {
+		Aliases map[string]map[string]interface{} `json:"aliases"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]map[string]interface{}, len(response))
+	for index_, v := range response {
+		ret[index_] = v.Aliases
+	}
+	return ret, nil
+}
+
+// PutIndexSettings updates the dynamic settings of index. A nil value resets a setting
+// to its default.
+func PutIndexSettings(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index string,
+	settings map[string]interface{},
+) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(settings); err != nil {
+		return err
+	}
+
+	res, err := es.Indices.PutSettings(
+		&buf,
+		es.Indices.PutSettings.WithContext(ctx),
+		es.Indices.PutSettings.WithIndex(index),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FieldCapsResponse is the response of the field capabilities API.
+type FieldCapsResponse struct {
+	Indices []string                               `json:"indices"`
+	Fields  map[string]map[string]FieldCapsPerType `json:"fields"`
+}
+
+// FieldCapsPerType are the capabilities of a field for one of its types. Indices is only
+// set if the field has different types across indices.
+type FieldCapsPerType struct {
+	Type         string   `json:"type"`
+	Searchable   bool     `json:"searchable"`
+	Aggregatable bool     `json:"aggregatable"`
+	Indices      []string `json:"indices"`
+}
+
+// GetFieldCaps returns the capabilities of fields in the indices matching index.
+func GetFieldCaps(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index []string,
+	fields []string,
+) (*FieldCapsResponse, error) {
+	res, err := es.FieldCaps(
+		es.FieldCaps.WithContext(ctx),
+		es.FieldCaps.WithIndex(index...),
+		es.FieldCaps.WithFields(fields...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	ret := &FieldCapsResponse{}
+	if err := json.Unmarshal(body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ReadJSONObjects reads a stream of JSON objects from r, as output by glazed with
+// --output json (either newline-delimited objects, or a single array of objects).
+// Each object is decoded as it is read, so that arbitrarily long streams can be piped
+// in line by line.
+func ReadJSONObjects(r io.Reader) ([]map[string]interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	ret := []map[string]interface{}{}
+	for i := 0; ; i++ {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not decode JSON value %d", i)
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			ret = append(ret, v)
+		case []interface{}:
+			for j, element := range v {
+				object, ok := element.(map[string]interface{})
+				if !ok {
+					return nil, errors.Errorf("element %d of JSON value %d is not an object", j, i)
+				}
+				ret = append(ret, object)
+			}
+		default:
+			return nil, errors.Errorf("JSON value %d is not an object", i)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+const (
+	// DefaultMaxFieldsPerRow is high enough for any reasonably mapped document, while
+	// protecting the output against documents with thousands of dynamic fields.
+	DefaultMaxFieldsPerRow = 500
+
+	OverflowColumn      = "_overflow"
+	OverflowCountColumn = "_overflow_count"
+)
+
+// MaxFieldsMiddleware keeps the first MaxFields fields of each row, collapsing the
+// remaining fields into a JSON object in the _overflow column, along with their number
+// in the _overflow_count column.
+type MaxFieldsMiddleware struct {
+	MaxFields int
+}
+
+var _ middlewares.RowMiddleware = (*MaxFieldsMiddleware)(nil)
+
+func NewMaxFieldsMiddleware(maxFields int) *MaxFieldsMiddleware {
+	return &MaxFieldsMiddleware{MaxFields: maxFields}
+}
+
+// IsNoop returns true if the number of fields is not limited.
+func (m *MaxFieldsMiddleware) IsNoop() bool {
+	return m.MaxFields <= 0
+}
+
+func (m *MaxFieldsMiddleware) Close(ctx context.Context) error {
+	return nil
+}
+
+func (m *MaxFieldsMiddleware) Process(ctx context.Context, row types.Row) ([]types.Row, error) {
+	if m.IsNoop() || row.Len() <= m.MaxFields {
+		return []types.Row{row}, nil
+	}
+
+	newRow := types.NewRow()
+	overflow := types.NewRow()
+	for pair := row.Oldest(); pair != nil; pair = pair.Next() {
+		if newRow.Len() < m.MaxFields {
+			newRow.Set(pair.Key, pair.Value)
+		} else {
+			overflow.Set(pair.Key, pair.Value)
+		}
+	}
+
+	b, err := json.Marshal(overflow)
+	if err != nil {
+		return nil, err
+	}
+	newRow.Set(OverflowColumn, string(b))
+	newRow.Set(OverflowCountColumn, overflow.Len())
+
+	return []types.Row{newRow}, nil
+}
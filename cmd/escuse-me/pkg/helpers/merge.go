@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DeepMerge merges src into dst recursively. Nested objects are merged key by key,
+// all other values in src replace the ones in dst.
+func DeepMerge(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = DeepMerge(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// ParsePathValue parses a "dotted.path=value" string into the nested object
+// {"dotted": {"path": value}}. The value is parsed as JSON if possible, and used as
+// a string otherwise.
+func ParsePathValue(s string) (map[string]interface{}, error) {
+	path, rawValue, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return nil, errors.Errorf("invalid parameter %q, expected path=value", s)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(rawValue), &value); err != nil {
+		value = rawValue
+	}
+
+	keys := strings.Split(path, ".")
+	ret := map[string]interface{}{keys[len(keys)-1]: value}
+	for i := len(keys) - 2; i >= 0; i-- {
+		ret = map[string]interface{}{keys[i]: ret}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,130 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// closePITTimeout bounds the request closing a point in time, which doesn't use the
+// command context.
+const closePITTimeout = 5 * time.Second
+
+// PIT is an open point in time. Searches using it may return a new id, which has to be
+// recorded with Update so that Close releases the right one.
+type PIT struct {
+	ID string
+
+	es     *elasticsearch.Client
+	closed bool
+}
+
+// PITOptions are the optional parameters of OpenPIT. The searches using a point in time
+// can't set them, they are fixed when opening it.
+type PITOptions struct {
+	// Preference selects the shard copies the point in time is opened on (e.g. _local
+	// or a custom string).
+	Preference string
+	// Routing restricts the point in time to the shards of these routing values.
+	Routing []string
+}
+
+// OpenPIT opens a point in time on the given indices, which is kept alive for keepAlive
+// (for example 1m) after each request using it. The returned PIT must be closed, usually
+// right away with defer pit.Close().
+func OpenPIT(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	index []string,
+	keepAlive string,
+	options PITOptions,
+) (*PIT, error) {
+	openOptions := []func(*esapi.OpenPointInTimeRequest){
+		es.OpenPointInTime.WithContext(ctx),
+	}
+	if options.Preference != "" {
+		openOptions = append(openOptions, es.OpenPointInTime.WithPreference(options.Preference))
+	}
+	if len(options.Routing) > 0 {
+		openOptions = append(openOptions, es.OpenPointInTime.WithRouting(strings.Join(options.Routing, ",")))
+	}
+
+	res, err := es.OpenPointInTime(index, keepAlive, openOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	if response.ID == "" {
+		return nil, errors.Errorf("could not find point in time id in response for %s", strings.Join(index, ","))
+	}
+
+	return &PIT{ID: response.ID, es: es}, nil
+}
+
+// Update records the point in time id returned by a search, if any.
+func (p *PIT) Update(id string) {
+	if id != "" {
+		p.ID = id
+	}
+}
+
+// Close releases the point in time. It is best effort, logging failures, and doesn't
+// use the command context so that it also runs after cancellation. Closing twice is a
+// no-op.
+func (p *PIT) Close() {
+	if p == nil || p.closed {
+		return
+	}
+	p.closed = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), closePITTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]interface{}{"id": p.ID})
+	if err != nil {
+		log.Warn().Err(err).Msg("could not close point in time")
+		return
+	}
+
+	res, err := p.es.ClosePointInTime(
+		p.es.ClosePointInTime.WithContext(ctx),
+		p.es.ClosePointInTime.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not close point in time")
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	if res.IsError() {
+		log.Warn().Str("status", res.Status()).Msg("could not close point in time")
+	}
+}
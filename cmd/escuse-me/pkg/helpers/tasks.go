@@ -0,0 +1,195 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// TaskPolling configures how WaitForTask polls a task.
+type TaskPolling struct {
+	// Interval is the delay between two successful polls.
+	Interval time.Duration
+	// MaxInterval caps the delay between polls, which doubles with each consecutive
+	// failed poll. It defaults to Interval.
+	MaxInterval time.Duration
+	// MaxFailures is the number of consecutive failed polls after which WaitForTask
+	// gives up.
+	MaxFailures int
+	// OnPoll, if set, is called with the task info of each successful poll, including
+	// the one finding the task completed, to report progress.
+	OnPoll func(taskID string, task map[string]interface{}) error
+}
+
+// ParseTaskPolling builds a TaskPolling from the duration strings of poll interval flags.
+func ParseTaskPolling(interval string, maxInterval string, maxFailures int) (TaskPolling, error) {
+	ret := TaskPolling{MaxFailures: maxFailures}
+	var err error
+	ret.Interval, err = time.ParseDuration(interval)
+	if err != nil {
+		return ret, errors.Wrap(err, "invalid poll interval")
+	}
+	if ret.Interval <= 0 {
+		return ret, errors.New("poll interval must be positive")
+	}
+	if maxFailures <= 0 {
+		return ret, errors.New("max poll failures must be positive")
+	}
+	if maxInterval != "" {
+		ret.MaxInterval, err = time.ParseDuration(maxInterval)
+		if err != nil {
+			return ret, errors.Wrap(err, "invalid max poll interval")
+		}
+	}
+	return ret, nil
+}
+
+// nextDelay returns the delay before the next poll after failures consecutive failed
+// polls, backing off exponentially with jitter so that clients polling a struggling
+// cluster don't retry in lockstep.
+func (p TaskPolling) nextDelay(failures int) time.Duration {
+	if failures == 0 {
+		return p.Interval
+	}
+	maxInterval := max(p.MaxInterval, p.Interval)
+	delay := p.Interval
+	for i := 0; i < failures && delay < maxInterval; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxInterval)
+	// keep at least half of the delay, randomizing the rest
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// WaitForTask polls the task until it has completed, and returns the task response
+// (the response of the operation run by the task, or its error).
+//
+// Polls failing with a transient error (see IsTransientError) are retried with an
+// increasing delay, up to polling.MaxFailures consecutive failures, the delay going back
+// to polling.Interval after a successful poll. Other errors, such as an unknown task,
+// are returned immediately.
+func WaitForTask(
+	ctx context.Context,
+	es *elasticsearch.Client,
+	taskID string,
+	polling TaskPolling,
+) (map[string]interface{}, error) {
+	failures := 0
+	for {
+		task, err := getTask(ctx, es, taskID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !IsTransientError(err) {
+				return nil, errors.Wrapf(err, "could not poll task %s", taskID)
+			}
+			failures++
+			if failures >= polling.MaxFailures {
+				return nil, errors.Wrapf(err, "giving up polling task %s after %d consecutive failures", taskID, failures)
+			}
+			log.Warn().Err(err).Str("task", taskID).Int("failures", failures).Msg("could not poll task")
+		} else {
+			failures = 0
+			if polling.OnPoll != nil {
+				if err := polling.OnPoll(taskID, task); err != nil {
+					return nil, err
+				}
+			}
+			if completed, _ := task["completed"].(bool); completed {
+				if taskError, ok := task["error"].(map[string]interface{}); ok {
+					return nil, errors.Errorf("task %s failed: %v: %v", taskID, taskError["type"], taskError["reason"])
+				}
+				response, _ := task["response"].(map[string]interface{})
+				return response, nil
+			}
+			log.Debug().Str("task", taskID).Msg("waiting for task to complete")
+		}
+
+		timer := time.NewTimer(polling.nextDelay(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func getTask(ctx context.Context, es *elasticsearch.Client, taskID string) (map[string]interface{}, error) {
+	res, err := es.Tasks.Get(
+		taskID,
+		es.Tasks.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := ErrorFromResponse(body); err != nil {
+		return nil, err
+	}
+
+	var task map[string]interface{}
+	if err := json.Unmarshal(body, &task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TaskInfo is the description of a running task returned by the tasks APIs.
+type TaskInfo struct {
+	Node               string                 `json:"node"`
+	ID                 int64                  `json:"id"`
+	Type               string                 `json:"type"`
+	Action             string                 `json:"action"`
+	Description        string                 `json:"description,omitempty"`
+	StartTimeInMillis  int64                  `json:"start_time_in_millis"`
+	RunningTimeInNanos int64                  `json:"running_time_in_nanos"`
+	Cancellable        bool                   `json:"cancellable"`
+	Cancelled          bool                   `json:"cancelled,omitempty"`
+	ParentTaskID       string                 `json:"parent_task_id,omitempty"`
+	Status             map[string]interface{} `json:"status,omitempty"`
+	// Children is only set when the tasks are grouped by parents.
+	Children []TaskInfo `json:"children,omitempty"`
+}
+
+// TaskID returns the node:id identifier of the task used by the tasks APIs.
+func (t TaskInfo) TaskID() string {
+	return fmt.Sprintf("%s:%d", t.Node, t.ID)
+}
+
+// Row returns the task as a row.
+func (t TaskInfo) Row() types.Row {
+	row := types.NewRow(
+		types.MRP("task_id", t.TaskID()),
+		types.MRP("node", t.Node),
+		types.MRP("action", t.Action),
+		types.MRP("type", t.Type),
+		types.MRP("description", t.Description),
+		types.MRP("start_time", time.UnixMilli(t.StartTimeInMillis).UTC().Format(time.RFC3339)),
+		types.MRP("running_time_seconds", float64(t.RunningTimeInNanos)/float64(time.Second)),
+		types.MRP("cancellable", t.Cancellable),
+		types.MRP("cancelled", t.Cancelled),
+		types.MRP("parent_task_id", t.ParentTaskID),
+	)
+	if t.Status != nil {
+		row.Set("status", t.Status)
+	}
+	return row
+}
@@ -0,0 +1,112 @@
+package cmds
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+const (
+	AggregationPivotWide = "wide"
+	AggregationPivotLong = "long"
+)
+
+// AggregationPivotMiddleware reshapes the bucket rows created by FlattenAggregations.
+//
+// In wide mode, every metric becomes a column: multi value metrics (stats, percentiles, ...)
+// are expanded into one column per value, named metric.value (for example price.avg).
+//
+// In long mode, every metric of a bucket becomes its own row, containing the key columns,
+// a metric column with the (expanded) metric name and a value column.
+//
+// Rows of top_hits aggregations (containing a _bucket_key column) are passed through unchanged.
+type AggregationPivotMiddleware struct {
+	Mode       string
+	KeyColumns map[string]bool
+}
+
+var _ middlewares.RowMiddleware = (*AggregationPivotMiddleware)(nil)
+
+// NewAggregationPivotMiddleware creates a pivot middleware. keyColumns are the columns
+// identifying a bucket, which are never reshaped. doc_count is always considered a key column.
+func NewAggregationPivotMiddleware(mode string, keyColumns []string) *AggregationPivotMiddleware {
+	keyColumns_ := map[string]bool{
+		"doc_count": true,
+	}
+	for _, k := range keyColumns {
+		keyColumns_[k] = true
+	}
+	return &AggregationPivotMiddleware{
+		Mode:       mode,
+		KeyColumns: keyColumns_,
+	}
+}
+
+func (a *AggregationPivotMiddleware) Close(ctx context.Context) error {
+	return nil
+}
+
+func (a *AggregationPivotMiddleware) Process(ctx context.Context, row types.Row) ([]types.Row, error) {
+	if _, ok := row.Get("_bucket_key"); ok {
+		return []types.Row{row}, nil
+	}
+
+	keys := types.NewRow()
+	metrics := types.NewRow()
+	for pair := row.Oldest(); pair != nil; pair = pair.Next() {
+		if a.KeyColumns[pair.Key] {
+			keys.Set(pair.Key, pair.Value)
+			continue
+		}
+		expandMetric(metrics, pair.Key, pair.Value)
+	}
+
+	if a.Mode != AggregationPivotLong {
+		for pair := metrics.Oldest(); pair != nil; pair = pair.Next() {
+			keys.Set(pair.Key, pair.Value)
+		}
+		return []types.Row{keys}, nil
+	}
+
+	ret := []types.Row{}
+	for pair := metrics.Oldest(); pair != nil; pair = pair.Next() {
+		row_ := copyRow(keys)
+		row_.Set("metric", pair.Key)
+		row_.Set("value", pair.Value)
+		ret = append(ret, row_)
+	}
+	if len(ret) == 0 {
+		ret = append(ret, keys)
+	}
+	return ret, nil
+}
+
+// expandMetric adds the value of a metric to row, expanding multi value metrics into
+// one column per value. The *_as_string variants are skipped.
+func expandMetric(row types.Row, name string, value interface{}) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		row.Set(name, value)
+		return
+	}
+
+	// percentiles and similar aggregations nest their results under "values"
+	if values, ok := m["values"].(map[string]interface{}); ok && len(m) <= 2 {
+		m = values
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.HasSuffix(k, "_as_string") {
+			continue
+		}
+		expandMetric(row, name+"."+k, m[k])
+	}
+}
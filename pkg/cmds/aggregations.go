@@ -0,0 +1,208 @@
+package cmds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+// FlattenAggregations turns the aggregations part of a search response into rows.
+//
+// Bucket aggregations are expanded recursively, each bucket row carrying the keys of
+// its parent buckets as columns named after the aggregations. Single value metrics
+// (avg, sum, cardinality, ...) are added as columns to the bucket they belong to, while
+// multi value metrics (stats, percentiles, ...) are added as objects.
+//
+// top_hits aggregations are emitted as child rows, one per hit, carrying the parent
+// bucket columns, the _bucket_key (the path of bucket keys joined by "/"), the hit _id
+// and the hit _source fields.
+func FlattenAggregations(aggregations map[string]interface{}) []types.Row {
+	if len(aggregations) == 0 {
+		return nil
+	}
+	return FlattenBucketAggregations(aggregations, types.NewRow(), "")
+}
+
+// FlattenBucketAggregations flattens the sub-aggregations of a single bucket, adding
+// them to row, which already contains the bucket's own columns. It returns either the
+// row itself, or the child rows of nested bucket and top_hits aggregations.
+func FlattenBucketAggregations(
+	aggregations map[string]interface{},
+	row types.Row,
+	bucketKey string,
+) []types.Row {
+	names := make([]string, 0, len(aggregations))
+	for name := range aggregations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		aggregation, ok := aggregations[name].(map[string]interface{})
+		if !ok || isBucketAggregation(aggregation) || isTopHitsAggregation(aggregation) {
+			continue
+		}
+		row.Set(name, simplifyMetricAggregation(aggregation))
+	}
+
+	children := []types.Row{}
+	for _, name := range names {
+		aggregation, ok := aggregations[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch {
+		case isBucketAggregation(aggregation):
+			children = append(children, flattenBuckets(name, aggregation["buckets"], row, bucketKey)...)
+		case isTopHitsAggregation(aggregation):
+			children = append(children, flattenTopHits(aggregation, row, bucketKey)...)
+		}
+	}
+
+	if len(children) == 0 {
+		return []types.Row{row}
+	}
+	return children
+}
+
+func flattenBuckets(name string, buckets interface{}, parent types.Row, parentKey string) []types.Row {
+	ret := []types.Row{}
+
+	addBucket := func(key interface{}, bucket map[string]interface{}) {
+		row := copyRow(parent)
+		if compositeKey, ok := key.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(compositeKey))
+			for k := range compositeKey {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			values := []string{}
+			for _, k := range keys {
+				row.Set(k, compositeKey[k])
+				values = append(values, fmt.Sprint(compositeKey[k]))
+			}
+			key = strings.Join(values, ",")
+		} else {
+			row.Set(name, key)
+		}
+		row.Set("doc_count", bucket["doc_count"])
+
+		subAggregations := map[string]interface{}{}
+		for k, v := range bucket {
+			switch k {
+			case "key", "key_as_string", "doc_count", "from", "to", "from_as_string", "to_as_string":
+				continue
+			}
+			subAggregations[k] = v
+		}
+
+		ret = append(ret, FlattenBucketAggregations(subAggregations, row, joinBucketKey(parentKey, key))...)
+	}
+
+	switch b := buckets.(type) {
+	case []interface{}:
+		for _, bucket_ := range b {
+			bucket, ok := bucket_.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, ok := bucket["key_as_string"]
+			if !ok {
+				key = bucket["key"]
+			}
+			addBucket(key, bucket)
+		}
+	case map[string]interface{}:
+		// keyed buckets, for example filters aggregations
+		keys := make([]string, 0, len(b))
+		for k := range b {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			bucket, ok := b[k].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			addBucket(k, bucket)
+		}
+	}
+
+	return ret
+}
+
+func flattenTopHits(aggregation map[string]interface{}, parent types.Row, bucketKey string) []types.Row {
+	ret := []types.Row{}
+
+	hits, _ := aggregation["hits"].(map[string]interface{})
+	hits_, _ := hits["hits"].([]interface{})
+	for _, hit_ := range hits_ {
+		hit, ok := hit_.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		row := copyRow(parent)
+		row.Set("_bucket_key", bucketKey)
+		row.Set("_id", hit["_id"])
+		if source, ok := hit["_source"].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(source))
+			for k := range source {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				row.Set(k, source[k])
+			}
+		}
+		ret = append(ret, row)
+	}
+
+	return ret
+}
+
+func isBucketAggregation(aggregation map[string]interface{}) bool {
+	_, ok := aggregation["buckets"]
+	return ok
+}
+
+func isTopHitsAggregation(aggregation map[string]interface{}) bool {
+	hits, ok := aggregation["hits"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = hits["hits"]
+	return ok
+}
+
+// simplifyMetricAggregation returns the value of single value metric aggregations
+// and leaves multi value metric aggregations untouched.
+func simplifyMetricAggregation(aggregation map[string]interface{}) interface{} {
+	value, ok := aggregation["value"]
+	if !ok {
+		return aggregation
+	}
+	for k := range aggregation {
+		if k != "value" && k != "value_as_string" {
+			return aggregation
+		}
+	}
+	return value
+}
+
+func joinBucketKey(parentKey string, key interface{}) string {
+	if parentKey == "" {
+		return fmt.Sprint(key)
+	}
+	return parentKey + "/" + fmt.Sprint(key)
+}
+
+func copyRow(row types.Row) types.Row {
+	ret := types.NewRow()
+	for pair := row.Oldest(); pair != nil; pair = pair.Next() {
+		ret.Set(pair.Key, pair.Value)
+	}
+	return ret
+}
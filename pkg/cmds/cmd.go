@@ -20,6 +20,7 @@ import (
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 	"gopkg.in/yaml.v3"
 	"io"
+	"os"
 	"strings"
 )
 
@@ -82,6 +83,18 @@ func (esc *ElasticSearchCommand) RunIntoGlazeProcessor(
 	parsedLayers *layers.ParsedLayers,
 	gp middlewares.Processor,
 ) error {
+	ctx, cancel, err := WithCommandTimeout(ctx, parsedLayers)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	restoreSummaryWriter, err := WithSummaryWriter(parsedLayers, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer restoreSummaryWriter()
+
 	es, err := esc.clientFactory(parsedLayers)
 	if err != nil {
 		return errors.Wrapf(err, "Could not create ES client")
@@ -104,6 +117,17 @@ func (esc *ElasticSearchCommand) RunIntoGlazeProcessor(
 
 	ps_ := parsedLayers.GetDataMap()
 
+	if esHelperSettings.ExplainTemplate {
+		explanation, err := esc.ExplainTemplate(parsedLayers)
+		if err != nil {
+			return errors.Wrapf(err, "Could not explain query template")
+		}
+		if err := explanation.Print(os.Stdout); err != nil {
+			return err
+		}
+		return &cmds.ExitWithoutGlazeError{}
+	}
+
 	if esHelperSettings.PrintQuery {
 		if output == "json" {
 			query, err := esc.RenderQueryToJSON(ps_)
@@ -254,13 +278,21 @@ func (esc *ElasticSearchCommand) RunQueryIntoGlaze(
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println(err)
+			PrintSummary(err)
 		}
 	}(res.Body)
 
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
 	if res.IsError() {
+		if expensiveQueryError, ok := NewExpensiveQueryError(ctx, es, body); ok {
+			return expensiveQueryError
+		}
 		var e map[string]interface{}
-		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+		if err := json.Unmarshal(body, &e); err != nil {
 			return errors.New("Error parsing the response body")
 		} else {
 			// Print the response status and error information.
@@ -270,15 +302,15 @@ func (esc *ElasticSearchCommand) RunQueryIntoGlaze(
 	}
 
 	var r ElasticSearchResult
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return err
-	}
 
 	if err := json.Unmarshal(body, &r); err != nil {
 		return errors.New("Error parsing the response body")
 	}
 
+	if esHelperSettings.OutputAggregations {
+		return esc.processAggregations(ctx, r.Aggregations, gp)
+	}
+
 	for _, hit := range r.Hits.Hits {
 		row := hit.Source
 		row.Set("_score", hit.Score)
@@ -293,6 +325,21 @@ func (esc *ElasticSearchCommand) RunQueryIntoGlaze(
 	return nil
 }
 
+// processAggregations emits the flattened aggregation buckets of the response as rows,
+// see FlattenAggregations.
+func (esc *ElasticSearchCommand) processAggregations(
+	ctx context.Context,
+	aggregations map[string]interface{},
+	gp middlewares.Processor,
+) error {
+	for _, row := range FlattenAggregations(aggregations) {
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type ElasticSearchResult struct {
 	Hits struct {
 		Hits []struct {
@@ -300,4 +347,5 @@ type ElasticSearchResult struct {
 			Score  float64                                     `json:"_score"`
 		} `json:"hits,omitempty"`
 	} `json:"hits"`
+	Aggregations map[string]interface{} `json:"aggregations,omitempty"`
 }
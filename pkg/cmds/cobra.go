@@ -19,7 +19,7 @@ func BuildCobraCommandWithEscuseMeMiddlewares(
 		cli.WithCobraShortHelpLayers(layers2.DefaultSlug, layers.EsConnectionSlug, layers.ESHelpersSlug),
 	}, options...)
 
-	return cli.BuildCobraCommandFromCommand(cmd, options_...)
+	return cli.BuildCobraCommandFromCommand(wrapEscuseMeCommand(cmd), options_...)
 }
 
 func GetCobraCommandEscuseMeMiddlewares(
@@ -41,7 +41,26 @@ func GetCobraCommandEscuseMeMiddlewares(
 			middlewares.LoadParametersFromFile(commandSettings.LoadParametersFromFile))
 	}
 
+	defaultProfileFile, err := GetDefaultProfileFile()
+	if err != nil {
+		return nil, err
+	}
+	profileFile := commandSettings.ProfileFile
+	if profileFile == "" {
+		profileFile = defaultProfileFile
+	}
+	profile := commandSettings.Profile
+	if profile == "" {
+		profile = "default"
+	}
+
 	middlewares_ = append(middlewares_,
+		middlewares.GatherFlagsFromProfiles(
+			defaultProfileFile,
+			profileFile,
+			profile,
+			parameters.WithParseStepSource("profiles"),
+		),
 		middlewares.WrapWithWhitelistedLayers(
 			[]string{
 				layers.EsConnectionSlug,
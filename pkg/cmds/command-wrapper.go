@@ -0,0 +1,187 @@
+package cmds
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-go-golems/escuse-me/cmd/escuse-me/pkg/helpers"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	commandRetryInitialBackoff = 500 * time.Millisecond
+	commandRetryMaxBackoff     = 10 * time.Second
+)
+
+// IdempotentCommand is implemented by commands that only read from the cluster,
+// and can thus be retried as a whole when command-retries is set.
+type IdempotentCommand interface {
+	IsIdempotent() bool
+}
+
+// SettingsIdempotentCommand is implemented by read-only commands that have settings
+// with side effects outside of their output rows, such as writing to a file or posting
+// to another service, which a retry would repeat. The command is only retried if
+// IsIdempotentWithSettings returns true, and IsIdempotent must return true as well.
+type SettingsIdempotentCommand interface {
+	IsIdempotentWithSettings(parsedLayers *layers.ParsedLayers) (bool, error)
+}
+
+// escuseMeGlazeCommand wraps a GlazeCommand to apply the command-timeout,
+// command-retries and output-summary-to-stderr connection settings.
+type escuseMeGlazeCommand struct {
+	cmds.GlazeCommand
+	idempotent bool
+}
+
+var _ cmds.GlazeCommand = &escuseMeGlazeCommand{}
+
+func (c *escuseMeGlazeCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	ctx, cancel, err := WithCommandTimeout(ctx, parsedLayers)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	restoreSummaryWriter, err := WithSummaryWriter(parsedLayers, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer restoreSummaryWriter()
+
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	idempotent := c.idempotent
+	if settingsIdempotentCommand, ok := c.GlazeCommand.(SettingsIdempotentCommand); ok && idempotent {
+		idempotent, err = settingsIdempotentCommand.IsIdempotentWithSettings(parsedLayers)
+		if err != nil {
+			return err
+		}
+	}
+
+	if idempotent && esSettings.CommandRetries > 0 {
+		err = c.runWithRetries(ctx, parsedLayers, gp, esSettings.CommandRetries)
+	} else {
+		err = c.GlazeCommand.RunIntoGlazeProcessor(ctx, parsedLayers, gp)
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.Wrap(err, "command timed out")
+	}
+	return err
+}
+
+// runWithRetries runs the command into a buffering processor, so that rows of a failed
+// attempt are dropped, and only forwards the rows of the successful attempt to gp.
+func (c *escuseMeGlazeCommand) runWithRetries(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+	retries int,
+) error {
+	backoff := commandRetryInitialBackoff
+
+	for attempt := 0; ; attempt++ {
+		buffer := middlewares.NewTableProcessor(
+			middlewares.WithTableMiddleware(&bufferTableMiddleware{}),
+		)
+		err := c.GlazeCommand.RunIntoGlazeProcessor(ctx, parsedLayers, buffer)
+		if err == nil {
+			return forwardRows(ctx, buffer.GetTable(), gp)
+		}
+
+		if attempt >= retries || !IsTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		log.Warn().Err(err).
+			Int("attempt", attempt+1).
+			Dur("backoff", backoff).
+			Msg("command failed with a transient error, retrying")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > commandRetryMaxBackoff {
+			backoff = commandRetryMaxBackoff
+		}
+	}
+}
+
+func forwardRows(ctx context.Context, table *types.Table, gp middlewares.Processor) error {
+	for _, row := range table.Rows {
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bufferTableMiddleware leaves the table untouched. Its only purpose is to make the
+// TableProcessor keep the rows it processed.
+type bufferTableMiddleware struct{}
+
+func (b *bufferTableMiddleware) Process(ctx context.Context, table *types.Table) (*types.Table, error) {
+	return table, nil
+}
+
+func (b *bufferTableMiddleware) Close(ctx context.Context) error {
+	return nil
+}
+
+// IsTransientError returns true for network errors and for Elasticsearch error responses
+// with a 429, 502, 503 or 504 status, which are worth retrying, see helpers.IsTransientError.
+func IsTransientError(err error) bool {
+	return helpers.IsTransientError(err)
+}
+
+// wrapEscuseMeCommand wraps glaze commands that have the es-connection layer so that
+// they honor command-timeout, command-retries and output-summary-to-stderr. Other
+// commands are returned unchanged.
+func wrapEscuseMeCommand(cmd cmds.Command) cmds.Command {
+	glazeCommand, ok := cmd.(cmds.GlazeCommand)
+	if !ok {
+		return cmd
+	}
+	if _, isBare := cmd.(cmds.BareCommand); isBare {
+		return cmd
+	}
+	if _, isWriter := cmd.(cmds.WriterCommand); isWriter {
+		return cmd
+	}
+	if _, isElasticSearchCommand := cmd.(*ElasticSearchCommand); isElasticSearchCommand {
+		// ElasticSearchCommand applies the timeout itself, since it is also loaded
+		// from repositories without going through BuildCobraCommandWithEscuseMeMiddlewares
+		return cmd
+	}
+	if _, ok := cmd.Description().Layers.Get(es_layers.EsConnectionSlug); !ok {
+		return cmd
+	}
+
+	idempotent := false
+	if idempotentCommand, ok := cmd.(IdempotentCommand); ok {
+		idempotent = idempotentCommand.IsIdempotent()
+	}
+
+	return &escuseMeGlazeCommand{
+		GlazeCommand: glazeCommand,
+		idempotent:   idempotent,
+	}
+}
@@ -0,0 +1,140 @@
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const allowExpensiveQueriesSetting = "search.allow_expensive_queries"
+
+var expensiveQueryClauseRegexp = regexp.MustCompile(`\[([^\]]+)\] queries cannot be executed when '` +
+	regexp.QuoteMeta(allowExpensiveQueriesSetting) + `' is set to false`)
+
+// ExpensiveQueryError is returned when a search is rejected because the cluster has
+// search.allow_expensive_queries set to false.
+type ExpensiveQueryError struct {
+	// Clause is the query clause that triggered the error (for example regexp or script).
+	Clause string
+	Reason string
+	// ClusterSetting is the current value of the setting, empty if it couldn't be retrieved.
+	ClusterSetting string
+}
+
+func (e *ExpensiveQueryError) Error() string {
+	clause := e.Clause
+	if clause == "" {
+		clause = "expensive"
+	}
+
+	sb := strings.Builder{}
+	_, _ = fmt.Fprintf(&sb,
+		"the search uses a %s query, which the cluster rejects because %s is set to false.\n",
+		clause, allowExpensiveQueriesSetting)
+	sb.WriteString("Expensive queries (script, regexp, wildcard, prefix, fuzzy, range on text fields, ...) " +
+		"are disabled to protect the cluster, rewrite the query without them or ask an administrator " +
+		"to enable the setting.\n")
+	if e.ClusterSetting != "" {
+		_, _ = fmt.Fprintf(&sb, "Current cluster setting: %s=%s\n", allowExpensiveQueriesSetting, e.ClusterSetting)
+	}
+	_, _ = fmt.Fprintf(&sb, "Elasticsearch error: %s", e.Reason)
+
+	return sb.String()
+}
+
+// ParseExpensiveQueryError checks whether the error response body was caused by
+// search.allow_expensive_queries being disabled.
+func ParseExpensiveQueryError(body []byte) (*ExpensiveQueryError, bool) {
+	var response struct {
+		Error struct {
+			RootCause []struct {
+				Reason string `json:"reason"`
+			} `json:"root_cause"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, false
+	}
+
+	reasons := []string{response.Error.Reason}
+	for _, rc := range response.Error.RootCause {
+		reasons = append(reasons, rc.Reason)
+	}
+	for _, reason := range reasons {
+		if !strings.Contains(reason, allowExpensiveQueriesSetting) {
+			continue
+		}
+		ret := &ExpensiveQueryError{
+			Reason: reason,
+		}
+		if m := expensiveQueryClauseRegexp.FindStringSubmatch(reason); m != nil {
+			ret.Clause = m[1]
+		}
+		return ret, true
+	}
+
+	return nil, false
+}
+
+// GetAllowExpensiveQueries returns the current value of search.allow_expensive_queries,
+// including the default value if it isn't set explicitly.
+func GetAllowExpensiveQueries(ctx context.Context, es *elasticsearch.Client) (string, error) {
+	res, err := es.Cluster.GetSettings(
+		es.Cluster.GetSettings.WithContext(ctx),
+		es.Cluster.GetSettings.WithIncludeDefaults(true),
+		es.Cluster.GetSettings.WithFlatSettings(true),
+		es.Cluster.GetSettings.WithFilterPath("*."+allowExpensiveQueriesSetting),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(res.Body)
+
+	if res.IsError() {
+		return "", errors.Errorf("could not get cluster settings: %s", res.Status())
+	}
+
+	settings := map[string]map[string]interface{}{}
+	if err := json.NewDecoder(res.Body).Decode(&settings); err != nil {
+		return "", err
+	}
+
+	// transient settings take precedence over persistent ones, which take precedence over defaults
+	for _, k := range []string{"transient", "persistent", "defaults"} {
+		if v, ok := settings[k][allowExpensiveQueriesSetting]; ok {
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	return "", errors.Errorf("%s not found in cluster settings", allowExpensiveQueriesSetting)
+}
+
+// NewExpensiveQueryError returns an ExpensiveQueryError if the error response body was
+// caused by search.allow_expensive_queries, enriched with the current cluster setting.
+// Retrieving the setting is best effort, since it requires cluster monitoring privileges.
+func NewExpensiveQueryError(ctx context.Context, es *elasticsearch.Client, body []byte) (*ExpensiveQueryError, bool) {
+	ret, ok := ParseExpensiveQueryError(body)
+	if !ok {
+		return nil, false
+	}
+
+	setting, err := GetAllowExpensiveQueries(ctx, es)
+	if err != nil {
+		log.Debug().Err(err).Msg("could not retrieve search.allow_expensive_queries")
+	} else {
+		ret.ClusterSetting = setting
+	}
+
+	return ret, true
+}
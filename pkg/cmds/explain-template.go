@@ -0,0 +1,244 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template/parse"
+
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/helpers/templating"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateParameterUsage describes how a command parameter is used by the query template.
+type TemplateParameterUsage struct {
+	Name string
+	// Value is nil if the parameter was not set.
+	Value interface{}
+	Used  bool
+}
+
+// TemplateExplanation maps the parameters of a command to its rendered query.
+type TemplateExplanation struct {
+	Parameters []TemplateParameterUsage
+	// Missing lists the variables referenced by the template that are not parameters.
+	Missing []string
+	Query   string
+}
+
+// ExplainTemplate renders the query of the command and records which of the command
+// parameters influence the rendered query, and which template variables are not
+// provided by any parameter.
+//
+// A parameter that was set is considered used if rendering the query without it fails
+// or changes the rendered query. A parameter that wasn't set is considered used if the
+// template references it.
+func (esc *ElasticSearchCommand) ExplainTemplate(parsedLayers *layers.ParsedLayers) (*TemplateExplanation, error) {
+	ps_ := parsedLayers.GetDataMap()
+	query, err := esc.RenderQueryToYAML(ps_)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err := esc.templateVariables()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &TemplateExplanation{
+		Query: query,
+	}
+
+	defined := map[string]bool{}
+	if defaultLayer, ok := esc.Layers.Get(layers.DefaultSlug); ok {
+		defaultLayer.GetParameterDefinitions().ForEach(func(p *parameters.ParameterDefinition) {
+			defined[p.Name] = true
+
+			value, ok := ps_[p.Name]
+			if !ok {
+				ret.Parameters = append(ret.Parameters, TemplateParameterUsage{
+					Name: p.Name,
+					Used: variables[p.Name],
+				})
+				return
+			}
+
+			without := make(map[string]interface{}, len(ps_))
+			for k, v := range ps_ {
+				if k != p.Name {
+					without[k] = v
+				}
+			}
+			query_, err := esc.RenderQueryToYAML(without)
+			ret.Parameters = append(ret.Parameters, TemplateParameterUsage{
+				Name:  p.Name,
+				Value: value,
+				Used:  err != nil || query_ != query,
+			})
+		})
+	}
+
+	for v := range variables {
+		if _, ok := ps_[v]; !ok && !defined[v] {
+			ret.Missing = append(ret.Missing, v)
+		}
+	}
+	sort.Strings(ret.Missing)
+
+	return ret, nil
+}
+
+// Print writes the parameters and the rendered query in a human-readable form.
+func (t *TemplateExplanation) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PARAMETER\tVALUE\tUSED")
+	for _, p := range t.Parameters {
+		used := "no"
+		if p.Used {
+			used = "yes"
+		}
+		value := "<unset>"
+		if p.Value != nil {
+			value = fmt.Sprint(p.Value)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, value, used)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(t.Missing) > 0 {
+		_, _ = fmt.Fprintf(w, "\nMissing variables: %s\n", strings.Join(t.Missing, ", "))
+	}
+
+	_, err := fmt.Fprintf(w, "\nQuery:\n%s\n", t.Query)
+	return err
+}
+
+// templateVariables returns the names of the top-level variables referenced by the
+// query template.
+func (esc *ElasticSearchCommand) templateVariables() (map[string]bool, error) {
+	variables := map[string]bool{}
+
+	if esc.QueryStringTemplate != "" {
+		tmpl, err := templating.CreateTemplate("query").Parse(esc.QueryStringTemplate)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				collectTemplateFields(t.Tree.Root, variables)
+			}
+		}
+	} else if esc.QueryNodeTemplate != nil {
+		locals := map[string]bool{}
+		collectEmrichenVariables(esc.QueryNodeTemplate.node, variables, locals)
+		for k := range locals {
+			delete(variables, k)
+		}
+	}
+
+	return variables, nil
+}
+
+// collectTemplateFields collects the fields of the template data accessed by the nodes.
+// Fields accessed inside range and with blocks are skipped, since the dot is rebound there.
+func collectTemplateFields(node parse.Node, variables map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, n_ := range n.Nodes {
+			collectTemplateFields(n_, variables)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, variables)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			collectTemplateFields(c, variables)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			collectTemplateFields(a, variables)
+		}
+	case *parse.FieldNode:
+		variables[n.Ident[0]] = true
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			variables[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		collectTemplateFields(n.Node, variables)
+	case *parse.IfNode:
+		collectTemplateFields(n.Pipe, variables)
+		collectTemplateFields(n.List, variables)
+		collectTemplateFields(n.ElseList, variables)
+	case *parse.RangeNode:
+		collectTemplateFields(n.Pipe, variables)
+		collectTemplateFields(n.ElseList, variables)
+	case *parse.WithNode:
+		collectTemplateFields(n.Pipe, variables)
+		collectTemplateFields(n.ElseList, variables)
+	case *parse.TemplateNode:
+		collectTemplateFields(n.Pipe, variables)
+	}
+}
+
+var formatVariableRegexp = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)[^{}]*\}`)
+var lookupVariableRegexp = regexp.MustCompile(`^\$?\.?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// collectEmrichenVariables collects the variables referenced by the emrichen tags of
+// the node, as well as the local variables bound by !Loop and !With.
+func collectEmrichenVariables(node *yaml.Node, variables map[string]bool, locals map[string]bool) {
+	if node == nil {
+		return
+	}
+
+	// tags can be composed, for example !Not,Exists
+	tags := strings.Split(node.Tag, ",")
+	switch "!" + strings.TrimPrefix(tags[len(tags)-1], "!") {
+	case "!Var", "!Exists":
+		if node.Kind == yaml.ScalarNode {
+			variables[strings.SplitN(node.Value, ".", 2)[0]] = true
+		}
+	case "!Lookup", "!LookupAll":
+		if m := lookupVariableRegexp.FindStringSubmatch(node.Value); m != nil {
+			variables[m[1]] = true
+		}
+	case "!Format":
+		for _, m := range formatVariableRegexp.FindAllStringSubmatch(node.Value, -1) {
+			variables[m[1]] = true
+		}
+	case "!Loop":
+		locals["item"] = true
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			switch node.Content[i].Value {
+			case "as", "index_as", "previous_as":
+				locals[node.Content[i+1].Value] = true
+			}
+		}
+	case "!With":
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != "vars" {
+				continue
+			}
+			vars := node.Content[i+1]
+			for j := 0; j+1 < len(vars.Content); j += 2 {
+				locals[vars.Content[j].Value] = true
+			}
+		}
+	}
+
+	for _, c := range node.Content {
+		collectEmrichenVariables(c, variables, locals)
+	}
+}
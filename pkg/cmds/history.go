@@ -0,0 +1,100 @@
+package cmds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultHistorySize is the number of entries kept in the query history, older
+// entries are dropped when new queries are recorded.
+const DefaultHistorySize = 1000
+
+// HistoryEntry is a search recorded in the query history.
+type HistoryEntry struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Index     []string        `json:"index,omitempty"`
+	Query     json.RawMessage `json:"query"`
+	Hits      int64           `json:"hits"`
+}
+
+// GetDefaultHistoryFile returns the path of the query history file,
+// ~/.config/escuse-me/history.ndjson on linux.
+func GetDefaultHistoryFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "could not get user config directory")
+	}
+	return filepath.Join(configDir, "escuse-me", "history.ndjson"), nil
+}
+
+// LoadHistory reads the entries of the history file, oldest first. A missing file
+// is an empty history.
+func LoadHistory(historyFile string) ([]HistoryEntry, error) {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []HistoryEntry{}, nil
+		}
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	ret := []HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		entry := HistoryEntry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, errors.Wrapf(err, "could not parse history file %s", historyFile)
+		}
+		ret = append(ret, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// AppendHistory records entry in the history file, keeping at most maxEntries entries.
+// The history only ever leaves the machine if the user copies it, so it is written
+// readable by the current user only.
+func AppendHistory(historyFile string, entry HistoryEntry, maxEntries int) error {
+	entries, err := LoadHistory(historyFile)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry)
+	if maxEntries > 0 && len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first so that an interrupted write doesn't lose the history
+	tmpFile := historyFile + ".tmp"
+	if err := os.WriteFile(tmpFile, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, historyFile)
+}
@@ -0,0 +1,43 @@
+package layers
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	CompressRequestBodyAuto   = "auto"
+	CompressRequestBodyAlways = "always"
+	CompressRequestBodyNever  = "never"
+
+	// AutoCompressThreshold is the size above which request bodies are compressed with
+	// compress-request-body auto. Smaller bodies gain too little to be worth the CPU.
+	AutoCompressThreshold = 1 << 20
+)
+
+// CompressLargeBody gzips body if compress-request-body is auto and body is larger
+// than AutoCompressThreshold, and returns the body to send along with the headers to
+// send it with. Commands sending large payloads, such as bulk requests, use it. With
+// compress-request-body always, the client compresses every body itself.
+func (s *EsClientSettings) CompressLargeBody(body []byte) ([]byte, map[string]string, error) {
+	if s.CompressRequestBody != CompressRequestBodyAuto || len(body) < AutoCompressThreshold {
+		return body, nil, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	log.Info().
+		Int("size", len(body)).
+		Int("compressed_size", buf.Len()).
+		Msg("compressed request body")
+	return buf.Bytes(), map[string]string{"Content-Encoding": "gzip"}, nil
+}
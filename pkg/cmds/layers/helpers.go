@@ -8,9 +8,11 @@ import (
 const ESHelpersSlug = "es-helpers"
 
 type ESHelperSettings struct {
-	PrintQuery bool   `glazed.parameter:"print-query"`
-	Explain    bool   `glazed.parameter:"explain"`
-	Index      string `glazed.parameter:"es-index"`
+	PrintQuery         bool   `glazed.parameter:"print-query"`
+	ExplainTemplate    bool   `glazed.parameter:"explain-template"`
+	Explain            bool   `glazed.parameter:"explain"`
+	Index              string `glazed.parameter:"es-index"`
+	OutputAggregations bool   `glazed.parameter:"output-aggregations"`
 }
 
 func NewESHelpersParameterLayer(
@@ -23,6 +25,12 @@ func NewESHelpersParameterLayer(
 			parameters.WithHelp("Prints the query that will be executed"),
 			parameters.WithDefault(false),
 		),
+		parameters.NewParameterDefinition(
+			"explain-template",
+			parameters.ParameterTypeBool,
+			parameters.WithHelp("Prints the parameters, whether the query template uses them, and the rendered query"),
+			parameters.WithDefault(false),
+		),
 		parameters.NewParameterDefinition(
 			"explain",
 			parameters.ParameterTypeBool,
@@ -34,6 +42,12 @@ func NewESHelpersParameterLayer(
 			parameters.ParameterTypeString,
 			parameters.WithHelp("The index to search in"),
 		),
+		parameters.NewParameterDefinition(
+			"output-aggregations",
+			parameters.ParameterTypeBool,
+			parameters.WithHelp("Output the flattened aggregation buckets instead of the hits"),
+			parameters.WithDefault(false),
+		),
 	))
 	ret, err := layers.NewParameterLayer(ESHelpersSlug, "ES Helpers", options_...)
 	if err != nil {
@@ -4,6 +4,10 @@ import (
 	_ "embed"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"net/http"
 )
 
 //go:embed "flags/es.yaml"
@@ -24,11 +28,93 @@ type EsClientSettings struct {
 	ServiceToken            string   `glazed.parameter:"service-token"`
 	CertificateFingerprint  string   `glazed.parameter:"certificate-fingerprint"`
 	RetryOnStatus           []int    `glazed.parameter:"retry-on-status"`
+	RetryOnTimeout          bool     `glazed.parameter:"retry-on-timeout"`
 	DisableRetry            bool     `glazed.parameter:"disable-retry"`
 	MaxRetries              int      `glazed.parameter:"max-retries"`
 	EnableMetrics           bool     `glazed.parameter:"enable-metrics"`
 	EnableDebugLogger       bool     `glazed.parameter:"enable-debug-logger"`
 	EnableCompatibilityMode bool     `glazed.parameter:"enable-compatibility-mode"`
+	XOpaqueID               string   `glazed.parameter:"x-opaque-id"`
+	QueryParams             []string `glazed.parameter:"query-param"`
+	DiscoverNodesOnStart    bool     `glazed.parameter:"discover-nodes-on-start"`
+	DiscoverNodesOnFailure  bool     `glazed.parameter:"discover-nodes-on-failure"`
+	CommandTimeout          string   `glazed.parameter:"command-timeout"`
+	CommandRetries          int      `glazed.parameter:"command-retries"`
+	CompressRequestBody     string   `glazed.parameter:"compress-request-body"`
+	OutputSummaryToStderr   bool     `glazed.parameter:"output-summary-to-stderr"`
+}
+
+const redactedValue = "<redacted>"
+
+var secretParameters = map[string]bool{
+	"password":      true,
+	"api-key":       true,
+	"service-token": true,
+}
+
+// IsSecretParameter returns true if the es-connection parameter holds credentials,
+// which should be redacted when displayed.
+func IsSecretParameter(name string) bool {
+	return secretParameters[name]
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// AuthMethod returns a short description of the credentials that will be used
+// to authenticate against the cluster.
+func (s *EsClientSettings) AuthMethod() string {
+	switch {
+	case s.ApiKey != "":
+		return "api-key"
+	case s.ServiceToken != "":
+		return "service-token"
+	case s.Username != "":
+		return "basic"
+	default:
+		return "none"
+	}
+}
+
+// GetSummary returns the resolved connection settings as a row, with all secrets
+// (passwords, API keys, service tokens) redacted. If verbose is true, the retry and
+// client behaviour settings are included as well.
+func (s *EsClientSettings) GetSummary(verbose bool) types.Row {
+	ret := types.NewRow(
+		types.MRP("addresses", s.Addresses),
+		types.MRP("cloud_id", s.CloudId),
+		types.MRP("auth", s.AuthMethod()),
+		types.MRP("username", s.Username),
+		types.MRP("password", redact(s.Password)),
+		types.MRP("api_key", redact(s.ApiKey)),
+		types.MRP("service_token", redact(s.ServiceToken)),
+		types.MRP("x_opaque_id", s.XOpaqueID),
+		types.MRP("query_params", s.QueryParams),
+		types.MRP("compress_request_body", s.CompressRequestBody),
+	)
+	if !verbose {
+		return ret
+	}
+
+	ret.Set("certificate_fingerprint", s.CertificateFingerprint)
+	ret.Set("retry_on_status", s.RetryOnStatus)
+	ret.Set("retry_on_timeout", s.RetryOnTimeout)
+	ret.Set("disable_retry", s.DisableRetry)
+	ret.Set("max_retries", s.MaxRetries)
+	ret.Set("enable_metrics", s.EnableMetrics)
+	ret.Set("enable_debug_logger", s.EnableDebugLogger)
+	ret.Set("enable_compatibility_mode", s.EnableCompatibilityMode)
+	ret.Set("discover_nodes_on_start", s.DiscoverNodesOnStart)
+	ret.Set("discover_nodes_on_failure", s.DiscoverNodesOnFailure)
+	ret.Set("command_timeout", s.CommandTimeout)
+	ret.Set("command_retries", s.CommandRetries)
+	ret.Set("output_summary_to_stderr", s.OutputSummaryToStderr)
+
+	return ret
 }
 
 func NewESParameterLayer(options ...layers.ParameterLayerOptions) (*EsParameterLayer, error) {
@@ -73,9 +159,63 @@ func NewESClientFromParsedLayers(
 		EnableMetrics:           settings.EnableMetrics,
 		EnableDebugLogger:       settings.EnableDebugLogger,
 		EnableCompatibilityMode: settings.EnableCompatibilityMode,
+		DiscoverNodesOnStart:    settings.DiscoverNodesOnStart,
+		CompressRequestBody:     settings.CompressRequestBody == CompressRequestBodyAlways,
 		// TODO(manuel, 2023-02-07) This should be a plunger.Logger
 		Logger: nil,
 	}
-	es, err := elasticsearch.NewClient(cfg)
+	if settings.XOpaqueID != "" {
+		cfg.Header = http.Header{}
+		cfg.Header.Set("X-Opaque-Id", settings.XOpaqueID)
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if len(settings.QueryParams) > 0 {
+		// a custom transport disables the client's own certificate fingerprint handling
+		if settings.CertificateFingerprint != "" {
+			return nil, errors.New("query-param can't be combined with certificate-fingerprint")
+		}
+		params, err := parseQueryParams(settings.QueryParams)
+		if err != nil {
+			return nil, err
+		}
+		transport = &queryParamsTransport{
+			params: params,
+			next:   transport,
+		}
+	}
+	retryOnTimeout := settings.RetryOnTimeout && !settings.DisableRetry
+	if retryOnTimeout {
+		if settings.CertificateFingerprint != "" {
+			return nil, errors.New("retry-on-timeout can't be combined with certificate-fingerprint")
+		}
+		transport = &retryOnTimeoutTransport{
+			retries: settings.MaxRetries,
+			next:    transport,
+		}
+	}
+	if transport != http.DefaultTransport {
+		cfg.Transport = transport
+	}
+
+	// the client is only known once it has been created, but RetryOnError has to be
+	// configured beforehand
+	var es *elasticsearch.Client
+	if retryOnTimeout || settings.DiscoverNodesOnFailure {
+		cfg.RetryOnError = func(req *http.Request, err error) bool {
+			// retryOnTimeoutTransport already retried these with a backoff
+			if retryOnTimeout && isIdempotentRequest(req) && isTimeoutError(err) {
+				return false
+			}
+			if !settings.DiscoverNodesOnFailure || req.Context().Err() != nil || es == nil {
+				return true
+			}
+			if err_ := es.DiscoverNodes(); err_ != nil {
+				log.Warn().Err(err_).Msg("could not rediscover nodes after connection failure")
+			}
+			return true
+		}
+	}
+
+	es, err = elasticsearch.NewClient(cfg)
 	return es, err
 }
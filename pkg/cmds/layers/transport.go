@@ -0,0 +1,124 @@
+package layers
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// queryParamsTransport adds a fixed set of query string parameters to every request.
+// It is used as an escape hatch for ES parameters that escuse-me doesn't expose as flags.
+type queryParamsTransport struct {
+	params url.Values
+	next   http.RoundTripper
+}
+
+func (t *queryParamsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the transport must not modify the original request
+	req_ := req.Clone(req.Context())
+	q := req_.URL.Query()
+	for k, vs := range t.params {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	req_.URL.RawQuery = q.Encode()
+
+	return t.next.RoundTrip(req_)
+}
+
+// parseQueryParams parses a list of key=value strings into url.Values.
+func parseQueryParams(params []string) (url.Values, error) {
+	ret := url.Values{}
+	for _, p := range params {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, errors.Errorf("invalid query parameter %q, expected key=value", p)
+		}
+		ret.Add(k, v)
+	}
+	return ret, nil
+}
+
+const (
+	timeoutRetryInitialBackoff = 500 * time.Millisecond
+	timeoutRetryMaxBackoff     = 10 * time.Second
+)
+
+// retryOnTimeoutTransport retries idempotent requests failing with a timeout or a
+// connection error, which RetryOnStatus can't cover, backing off exponentially between
+// attempts. It is used for flaky links to remote clusters.
+type retryOnTimeoutTransport struct {
+	retries int
+	next    http.RoundTripper
+}
+
+func (t *retryOnTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := timeoutRetryInitialBackoff
+	for attempt := 0; ; attempt++ {
+		req_ := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, errors.New("can't retry request, its body can't be replayed")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req_ = req.Clone(req.Context())
+			req_.Body = body
+		}
+
+		res, err := t.next.RoundTrip(req_)
+		if err == nil || attempt >= t.retries || !isIdempotentRequest(req) ||
+			!isTimeoutError(err) || req.Context().Err() != nil {
+			return res, err
+		}
+
+		log.Warn().Err(err).
+			Str("method", req.Method).
+			Str("path", req.URL.Path).
+			Int("attempt", attempt+1).
+			Dur("backoff", backoff).
+			Msg("request failed with a timeout or connection error, retrying")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, timeoutRetryMaxBackoff)
+	}
+}
+
+// isIdempotentRequest returns true for the HTTP methods that can safely be sent twice.
+// Searches sent with POST are not considered idempotent, as POST is also used to index.
+func isIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTimeoutError returns true for network timeouts and connection errors.
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,44 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Profiles maps a profile name to the parameter values it sets, per layer:
+//
+//	staging:
+//	  es-connection:
+//	    addresses: https://staging:9200
+//	    username: escuse-me
+type Profiles map[string]map[string]map[string]interface{}
+
+// GetDefaultProfileFile returns the path of the profiles file used when --profile-file
+// is not given, ~/.config/escuse-me/profiles.yaml on linux.
+func GetDefaultProfileFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "could not get user config directory")
+	}
+	return filepath.Join(configDir, "escuse-me", "profiles.yaml"), nil
+}
+
+// LoadProfiles parses the given profiles file.
+func LoadProfiles(profileFile string) (Profiles, error) {
+	f, err := os.Open(profileFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	ret := Profiles{}
+	if err := yaml.NewDecoder(f).Decode(&ret); err != nil {
+		return nil, errors.Wrapf(err, "could not parse profiles file %s", profileFile)
+	}
+	return ret, nil
+}
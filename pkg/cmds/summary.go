@@ -0,0 +1,44 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+)
+
+// SummaryWriter receives the human-readable messages of commands (progress, summaries,
+// non-fatal errors), so that stdout only contains the structured output and can be
+// redirected safely, as in escuse-me indices ls --output json > indices.json.
+var SummaryWriter io.Writer = os.Stderr
+
+// PrintSummary writes a human-readable message to SummaryWriter.
+func PrintSummary(a ...interface{}) {
+	_, _ = fmt.Fprintln(SummaryWriter, a...)
+}
+
+// PrintSummaryf writes a formatted human-readable message to SummaryWriter.
+func PrintSummaryf(format string, a ...interface{}) {
+	_, _ = fmt.Fprintf(SummaryWriter, format, a...)
+}
+
+// WithSummaryWriter points SummaryWriter to stderr, or to stdout if the
+// output-summary-to-stderr connection setting is off, for the duration of a command.
+// stdout is where the command writes its output. The returned restore function puts
+// back the previous SummaryWriter and must always be called.
+func WithSummaryWriter(parsedLayers *layers.ParsedLayers, stdout io.Writer) (func(), error) {
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return func() {}, err
+	}
+
+	previous := SummaryWriter
+	if esSettings.OutputSummaryToStderr {
+		SummaryWriter = os.Stderr
+	} else {
+		SummaryWriter = stdout
+	}
+	return func() { SummaryWriter = previous }, nil
+}
@@ -0,0 +1,76 @@
+package cmds
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	cmd_middlewares "github.com/go-go-golems/glazed/pkg/cmds/middlewares"
+)
+
+func parseSummaryLayers(t *testing.T, esParameterLayer *es_layers.EsParameterLayer, values map[string]interface{}) *layers.ParsedLayers {
+	t.Helper()
+	parsedLayers := layers.NewParsedLayers()
+	err := cmd_middlewares.ExecuteMiddlewares(
+		layers.NewParameterLayers(layers.WithLayers(esParameterLayer)),
+		parsedLayers,
+		cmd_middlewares.UpdateFromMap(map[string]map[string]interface{}{
+			es_layers.EsConnectionSlug: values,
+		}),
+		cmd_middlewares.SetFromDefaults(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsedLayers
+}
+
+func TestWithSummaryWriter(t *testing.T) {
+	esParameterLayer, err := es_layers.NewESParameterLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writerParameterLayer, err := es_layers.NewESParameterLayer(
+		layers.WithDefaults(map[string]interface{}{"output-summary-to-stderr": false}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		esParameterLayer *es_layers.EsParameterLayer
+		values           map[string]interface{}
+		toStderr         bool
+	}{
+		{"default", esParameterLayer, map[string]interface{}{}, true},
+		{"disabled", esParameterLayer, map[string]interface{}{"output-summary-to-stderr": false}, false},
+		{"writer default", writerParameterLayer, map[string]interface{}{}, false},
+		{"writer enabled", writerParameterLayer, map[string]interface{}{"output-summary-to-stderr": true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsedLayers := parseSummaryLayers(t, tt.esParameterLayer, tt.values)
+			stdout := &bytes.Buffer{}
+			restore, err := WithSummaryWriter(parsedLayers, stdout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.toStderr && SummaryWriter != os.Stderr {
+				t.Errorf("expected the summary to go to stderr")
+			}
+			if !tt.toStderr && SummaryWriter != stdout {
+				t.Errorf("expected the summary to go to stdout")
+			}
+
+			restore()
+			if SummaryWriter != os.Stderr {
+				t.Errorf("expected the summary writer to be restored")
+			}
+		})
+	}
+}
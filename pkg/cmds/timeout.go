@@ -0,0 +1,37 @@
+package cmds
+
+import (
+	"context"
+	"time"
+
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/pkg/errors"
+)
+
+// WithCommandTimeout returns a context bounded by the command-timeout connection setting.
+// Since the commands pass the context to all their ES requests, in-flight requests are
+// cancelled once the timeout expires. The returned cancel function must always be called.
+func WithCommandTimeout(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+) (context.Context, context.CancelFunc, error) {
+	esSettings, err := es_layers.NewESClientSettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return ctx, func() {}, err
+	}
+	if esSettings.CommandTimeout == "" {
+		return ctx, func() {}, nil
+	}
+
+	timeout, err := time.ParseDuration(esSettings.CommandTimeout)
+	if err != nil {
+		return ctx, func() {}, errors.Wrap(err, "invalid command-timeout")
+	}
+	if timeout <= 0 {
+		return ctx, func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
@@ -0,0 +1,71 @@
+package estest
+
+import (
+	"context"
+
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	cmd_middlewares "github.com/go-go-golems/glazed/pkg/cmds/middlewares"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+)
+
+// rowCollector is a row middleware keeping the rows output by a command.
+type rowCollector struct {
+	rows []types.Row
+}
+
+func (c *rowCollector) Process(ctx context.Context, row types.Row) ([]types.Row, error) {
+	c.rows = append(c.rows, row)
+	return []types.Row{row}, nil
+}
+
+func (c *rowCollector) Close(ctx context.Context) error {
+	return nil
+}
+
+// RunGlazeCommand runs cmd connected to the server and returns the rows it output,
+// along with its error. values are the parameter values by layer slug, the parameters
+// not given keeping their defaults:
+//
+//	rows, err := server.RunGlazeCommand(ctx, cmd, map[string]map[string]interface{}{
+//		layers.DefaultSlug: {"index": []string{"tasks"}},
+//	})
+//
+// The rows output before an error are returned as well.
+func (s *Server) RunGlazeCommand(
+	ctx context.Context,
+	cmd cmds.GlazeCommand,
+	values map[string]map[string]interface{},
+) ([]types.Row, error) {
+	connection := map[string]interface{}{
+		"addresses":     []string{s.URL},
+		"disable-retry": true,
+	}
+	for k, v := range values[es_layers.EsConnectionSlug] {
+		connection[k] = v
+	}
+	values_ := map[string]map[string]interface{}{}
+	for slug, v := range values {
+		values_[slug] = v
+	}
+	values_[es_layers.EsConnectionSlug] = connection
+
+	parsedLayers := layers.NewParsedLayers()
+	err := cmd_middlewares.ExecuteMiddlewares(
+		cmd.Description().Layers,
+		parsedLayers,
+		cmd_middlewares.UpdateFromMap(values_),
+		cmd_middlewares.SetFromDefaults(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := &rowCollector{}
+	gp := middlewares.NewTableProcessor()
+	gp.AddRowMiddleware(collector)
+	err = cmd.RunIntoGlazeProcessor(ctx, parsedLayers, gp)
+	return collector.rows, err
+}
@@ -0,0 +1,200 @@
+package estest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrorResponse returns an Elasticsearch error response body.
+func ErrorResponse(status int, type_ string, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":   type_,
+			"reason": reason,
+			"root_cause": []interface{}{
+				map[string]interface{}{"type": type_, "reason": reason},
+			},
+		},
+		"status": status,
+	}
+}
+
+// SearchResponse returns a search response body with a hit for each document, using
+// the position of the document as _id.
+func SearchResponse(documents ...map[string]interface{}) map[string]interface{} {
+	hits := make([]interface{}, 0, len(documents))
+	for i, document := range documents {
+		hits = append(hits, map[string]interface{}{
+			"_index":  "estest",
+			"_id":     fmt.Sprintf("%d", i),
+			"_score":  1.0,
+			"_source": document,
+		})
+	}
+
+	return map[string]interface{}{
+		"took":      1,
+		"timed_out": false,
+		"_shards": map[string]interface{}{
+			"total": 1, "successful": 1, "skipped": 0, "failed": 0,
+		},
+		"hits": map[string]interface{}{
+			"total":     map[string]interface{}{"value": len(documents), "relation": "eq"},
+			"max_score": 1.0,
+			"hits":      hits,
+		},
+	}
+}
+
+// HandleSearch answers searches on index, which can be a pattern segment such as "*",
+// with a hit for each document.
+func (s *Server) HandleSearch(index string, documents ...map[string]interface{}) {
+	response := SearchResponse(documents...)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		s.HandleJSON(method, "/"+index+"/_search", http.StatusOK, response)
+	}
+}
+
+// HandleBulk answers bulk requests by acknowledging every action as successful.
+func (s *Server) HandleBulk() {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		items, err := bulkItems(req.Body)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse(http.StatusBadRequest, "parse_exception", err.Error()))
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"took":   1,
+			"errors": false,
+			"items":  items,
+		})
+	}
+	for _, pattern := range []string{"/_bulk", "/*/_bulk"} {
+		s.Handle(http.MethodPost, pattern, handler)
+		s.Handle(http.MethodPut, pattern, handler)
+	}
+}
+
+// bulkItems returns a successful response item for each action line of a bulk body.
+func bulkItems(body io.Reader) ([]interface{}, error) {
+	items := []interface{}{}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	expectSource := false
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if expectSource {
+			expectSource = false
+			continue
+		}
+
+		var action map[string]map[string]interface{}
+		if err := json.Unmarshal(line, &action); err != nil {
+			return nil, err
+		}
+		for type_, meta := range action {
+			id, ok := meta["_id"]
+			if !ok {
+				id = fmt.Sprintf("estest-%d", len(items))
+			}
+			result := map[string]interface{}{
+				"index":  "created",
+				"create": "created",
+				"update": "updated",
+				"delete": "deleted",
+			}[type_]
+			items = append(items, map[string]interface{}{
+				type_: map[string]interface{}{
+					"_index":   meta["_index"],
+					"_id":      id,
+					"_version": 1,
+					"result":   result,
+					"status":   http.StatusCreated,
+				},
+			})
+			expectSource = type_ != "delete"
+		}
+	}
+	return items, scanner.Err()
+}
+
+// HandleReindex answers reindex requests started without waiting for completion with
+// taskID, and reports the task as completed with created documents after it has been
+// polled polls times.
+func (s *Server) HandleReindex(taskID string, polls int, created int) {
+	s.HandleJSON(http.MethodPost, "/_reindex", http.StatusOK, map[string]interface{}{
+		"task": taskID,
+	})
+
+	var mu sync.Mutex
+	polled := 0
+	s.Handle(http.MethodGet, "/_tasks/"+taskID, func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		polled++
+		completed := polled > polls
+		mu.Unlock()
+
+		status := map[string]interface{}{
+			"total":   created,
+			"created": 0,
+			"updated": 0,
+			"deleted": 0,
+		}
+		response := map[string]interface{}{
+			"completed": completed,
+			"task": map[string]interface{}{
+				"action": "indices:data/write/reindex",
+				"status": status,
+			},
+		}
+		if completed {
+			status["created"] = created
+			response["response"] = map[string]interface{}{
+				"took":     1,
+				"total":    created,
+				"created":  created,
+				"failures": []interface{}{},
+			}
+		}
+		WriteJSON(w, http.StatusOK, response)
+	})
+}
+
+// HandleAliases answers alias listings with aliases, by index, and acknowledges alias
+// updates.
+func (s *Server) HandleAliases(aliases map[string][]string) {
+	response := map[string]interface{}{}
+	for index, aliases_ := range aliases {
+		indexAliases := map[string]interface{}{}
+		for _, alias := range aliases_ {
+			indexAliases[alias] = map[string]interface{}{}
+		}
+		response[index] = map[string]interface{}{"aliases": indexAliases}
+	}
+
+	for _, pattern := range []string{"/_alias", "/_aliases", "/*/_alias", "/*/_alias/*", "/_alias/*"} {
+		s.HandleJSON(http.MethodGet, pattern, http.StatusOK, response)
+	}
+	s.HandleJSON(http.MethodPost, "/_aliases", http.StatusOK, map[string]interface{}{
+		"acknowledged": true,
+	})
+}
+
+// HandleMappings answers mapping requests on index with mappings, and acknowledges
+// mapping updates.
+func (s *Server) HandleMappings(index string, mappings map[string]interface{}) {
+	s.HandleJSON(http.MethodGet, "/"+index+"/_mapping", http.StatusOK, map[string]interface{}{
+		index: map[string]interface{}{"mappings": mappings},
+	})
+	s.HandleJSON(http.MethodPut, "/"+index+"/_mapping", http.StatusOK, map[string]interface{}{
+		"acknowledged": true,
+	})
+}
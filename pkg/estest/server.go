@@ -0,0 +1,224 @@
+// Package estest provides an in-memory stub of the Elasticsearch HTTP API, to exercise
+// commands without a live cluster.
+//
+// A Server answers requests with programmable handlers, matched on the method and the
+// path of the request. Path patterns are split on "/" and "*" matches any single
+// segment, so "/*/_search" matches a search on any index:
+//
+//	server := estest.NewServer()
+//	defer server.Close()
+//	server.HandleJSON("POST", "/*/_search", http.StatusOK, estest.SearchResponse(
+//		map[string]interface{}{"name": "foo"},
+//	))
+//	es, err := server.NewClient()
+//
+// Every request is recorded and can be inspected with Requests.
+package estest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	es_layers "github.com/go-go-golems/escuse-me/pkg/cmds/layers"
+)
+
+// Request is a request received by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Body   []byte
+}
+
+// DecodeBody decodes the JSON body of the request into v.
+func (r Request) DecodeBody(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+type route struct {
+	method   string
+	segments []string
+	handler  http.HandlerFunc
+}
+
+func (r route) matches(method string, path string) bool {
+	if r.method != method {
+		return false
+	}
+	segments := splitPath(path)
+	if len(segments) != len(r.segments) {
+		return false
+	}
+	for i, segment := range r.segments {
+		if segment != "*" && segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+// Server is a stub Elasticsearch cluster. Handlers registered later take precedence
+// over earlier ones, so that tests can override the defaults.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   []route
+	requests []Request
+}
+
+// NewServer starts a Server answering the root endpoint like an Elasticsearch 8 node,
+// which the client checks before its first request. Every other endpoint returns 404
+// until a handler is registered for it.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.HandleJSON(http.MethodGet, "/", http.StatusOK, map[string]interface{}{
+		"name":         "estest",
+		"cluster_name": "estest",
+		"version": map[string]interface{}{
+			"number":         "8.11.0",
+			"build_flavor":   "default",
+			"lucene_version": "9.8.0",
+		},
+		"tagline": "You Know, for Search",
+	})
+	return s
+}
+
+// Handle registers handler for requests with method on paths matching pattern.
+func (s *Server) Handle(method string, pattern string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append(s.routes, route{
+		method:   method,
+		segments: splitPath(pattern),
+		handler:  handler,
+	})
+}
+
+// HandleJSON registers a handler answering requests with method on paths matching
+// pattern with status and response encoded as JSON.
+func (s *Server) HandleJSON(method string, pattern string, status int, response interface{}) {
+	s.Handle(method, pattern, func(w http.ResponseWriter, _ *http.Request) {
+		WriteJSON(w, status, response)
+	})
+}
+
+// HandleError registers a handler answering requests with method on paths matching
+// pattern with an Elasticsearch error response.
+func (s *Server) HandleError(method string, pattern string, status int, type_ string, reason string) {
+	s.HandleJSON(method, pattern, status, ErrorResponse(status, type_, reason))
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestsTo returns the requests received so far with method on paths matching pattern.
+func (s *Server) RequestsTo(method string, pattern string) []Request {
+	r := route{method: method, segments: splitPath(pattern)}
+	ret := []Request{}
+	for _, request := range s.Requests() {
+		if r.matches(request.Method, request.Path) {
+			ret = append(ret, request)
+		}
+	}
+	return ret
+}
+
+// Settings returns connection settings pointing at the server.
+func (s *Server) Settings() *es_layers.EsClientSettings {
+	return &es_layers.EsClientSettings{
+		Addresses:    []string{s.URL},
+		DisableRetry: true,
+	}
+}
+
+// NewClient returns a client connected to the server.
+func (s *Server) NewClient() (*elasticsearch.Client, error) {
+	settings := s.Settings()
+	return elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:    settings.Addresses,
+		DisableRetry: settings.DisableRetry,
+	})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := readBody(req)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse(http.StatusBadRequest, "parse_exception", err.Error()))
+		return
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Body:   body,
+	})
+	var handler http.HandlerFunc
+	for i := len(s.routes) - 1; i >= 0; i-- {
+		if s.routes[i].matches(req.Method, req.URL.Path) {
+			handler = s.routes[i].handler
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if handler == nil {
+		WriteJSON(w, http.StatusNotFound, ErrorResponse(
+			http.StatusNotFound,
+			"estest_no_handler",
+			fmt.Sprintf("no handler for %s %s", req.Method, req.URL.Path),
+		))
+		return
+	}
+	handler(w, req)
+}
+
+// readBody returns the body of req, decompressed if it was sent gzipped like
+// Elasticsearch accepts it.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Header.Get("Content-Encoding") != "gzip" {
+		return io.ReadAll(req.Body)
+	}
+	r, err := gzip.NewReader(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	return io.ReadAll(r)
+}
+
+// WriteJSON writes response as JSON with status, with the product header the client
+// requires.
+func WriteJSON(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
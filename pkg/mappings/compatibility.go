@@ -0,0 +1,207 @@
+// Package mappings contains helpers to reason about Elasticsearch index mappings
+// without asking the cluster.
+package mappings
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Incompatibility is a change to an existing field (or to the mapping itself) that
+// can't be applied to an existing index, and requires reindexing into a new index.
+type Incompatibility struct {
+	// Field is the full path of the field, empty for mapping-level changes
+	Field  string
+	Reason string
+}
+
+func (i Incompatibility) String() string {
+	if i.Field == "" {
+		return i.Reason
+	}
+	return i.Field + ": " + i.Reason
+}
+
+// updatableFieldParameters are the field mapping parameters that can be changed on an
+// existing field.
+var updatableFieldParameters = map[string]bool{
+	"ignore_above":          true,
+	"ignore_malformed":      true,
+	"search_analyzer":       true,
+	"search_quote_analyzer": true,
+	"meta":                  true,
+	"coerce":                true,
+	"eager_global_ordinals": true,
+	"fielddata":             true,
+	"dynamic":               true,
+}
+
+// updatableMappingParameters are the top-level mapping options that can be changed on
+// an existing index.
+var updatableMappingParameters = map[string]bool{
+	"dynamic":           true,
+	"dynamic_templates": true,
+	"date_detection":    true,
+	"numeric_detection": true,
+	"runtime":           true,
+	"_meta":             true,
+}
+
+// IsCompatibleChange returns whether the desired mappings can be applied in place to
+// an index whose mappings are current, as with a PUT _mapping request. Both are the
+// content of the "mappings" object of an index, with "properties" at the top.
+//
+// The compatible changes are:
+//   - adding new fields, including new properties to object fields
+//   - adding multi-fields to existing fields
+//   - changing the parameters listed in updatableFieldParameters
+//   - disabling norms
+//
+// Anything else changing an existing field is incompatible, most notably changing its
+// type or its analyzer. Omitting an existing field is fine, as fields can't be removed
+// and the update only ever adds to the mappings.
+func IsCompatibleChange(current, desired map[string]interface{}) (bool, []Incompatibility) {
+	current, desired = normalize(current), normalize(desired)
+
+	ret := []Incompatibility{}
+	for _, k := range sortedKeys(desired) {
+		if k == "properties" || updatableMappingParameters[k] {
+			continue
+		}
+		if v, ok := current[k]; !ok || !reflect.DeepEqual(v, desired[k]) {
+			ret = append(ret, Incompatibility{
+				Reason: fmt.Sprintf("mapping parameter %s can't be changed", k),
+			})
+		}
+	}
+
+	ret = append(ret, compareProperties("", asMap(current["properties"]), asMap(desired["properties"]))...)
+
+	return len(ret) == 0, ret
+}
+
+func compareProperties(prefix string, current, desired map[string]interface{}) []Incompatibility {
+	ret := []Incompatibility{}
+	for _, name := range sortedKeys(desired) {
+		currentField, ok := current[name]
+		if !ok {
+			// new fields can always be added
+			continue
+		}
+		ret = append(ret, compareField(prefix+name, asMap(currentField), asMap(desired[name]))...)
+	}
+	return ret
+}
+
+func compareField(path string, current, desired map[string]interface{}) []Incompatibility {
+	currentType, desiredType := fieldType(current), fieldType(desired)
+	if currentType != desiredType {
+		return []Incompatibility{{
+			Field:  path,
+			Reason: fmt.Sprintf("type can't be changed from %s to %s", currentType, desiredType),
+		}}
+	}
+
+	ret := []Incompatibility{}
+	for _, k := range unionKeys(current, desired) {
+		currentValue, inCurrent := current[k]
+		desiredValue, inDesired := desired[k]
+
+		switch k {
+		case "type":
+			continue
+		case "properties":
+			ret = append(ret, compareProperties(path+".", asMap(currentValue), asMap(desiredValue))...)
+			continue
+		case "fields":
+			// multi-fields can be added, existing ones follow the same rules as fields
+			ret = append(ret, compareProperties(path+".", asMap(currentValue), asMap(desiredValue))...)
+			continue
+		}
+
+		if !inDesired || reflect.DeepEqual(currentValue, desiredValue) {
+			// omitted parameters are only an issue if they differ from the default,
+			// which can't be known here without the cluster
+			if !inDesired && inCurrent && !updatableFieldParameters[k] {
+				ret = append(ret, Incompatibility{
+					Field:  path,
+					Reason: fmt.Sprintf("parameter %s is set to %v and would be reset", k, currentValue),
+				})
+			}
+			continue
+		}
+		if updatableFieldParameters[k] {
+			continue
+		}
+		if k == "norms" && desiredValue == false {
+			continue
+		}
+
+		reason := fmt.Sprintf("parameter %s can't be changed from %v to %v", k, formatValue(currentValue, inCurrent), desiredValue)
+		if k == "analyzer" {
+			reason = fmt.Sprintf("analyzer can't be changed from %v to %v, the indexed terms would differ",
+				formatValue(currentValue, inCurrent), desiredValue)
+		}
+		ret = append(ret, Incompatibility{Field: path, Reason: reason})
+	}
+
+	return ret
+}
+
+// fieldType returns the type of a field mapping, object fields omitting it.
+func fieldType(field map[string]interface{}) string {
+	if t, ok := field["type"].(string); ok {
+		return t
+	}
+	return "object"
+}
+
+func formatValue(v interface{}, ok bool) interface{} {
+	if !ok {
+		return "<default>"
+	}
+	return v
+}
+
+// normalize makes the mappings comparable, mappings read from YAML files having
+// integers where mappings read from Elasticsearch have float64 numbers.
+func normalize(m map[string]interface{}) map[string]interface{} {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	ret := map[string]interface{}{}
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return m
+	}
+	return ret
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := map[string]interface{}{}
+	for k := range a {
+		keys[k] = nil
+	}
+	for k := range b {
+		keys[k] = nil
+	}
+	return sortedKeys(keys)
+}
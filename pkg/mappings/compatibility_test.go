@@ -0,0 +1,243 @@
+package mappings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func properties(fields map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"properties": fields}
+}
+
+func TestIsCompatibleChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		current map[string]interface{}
+		desired map[string]interface{}
+		// incompatible are the fields of the expected incompatibilities, "" for the
+		// mapping-level ones
+		incompatible []string
+	}{
+		{
+			name: "new field",
+			current: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword"},
+			}),
+			desired: properties(map[string]interface{}{
+				"status":  map[string]interface{}{"type": "keyword"},
+				"message": map[string]interface{}{"type": "text"},
+			}),
+		},
+		{
+			name: "omitted field",
+			current: properties(map[string]interface{}{
+				"status":  map[string]interface{}{"type": "keyword"},
+				"message": map[string]interface{}{"type": "text"},
+			}),
+			desired: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword"},
+			}),
+		},
+		{
+			name: "new property in an object",
+			current: properties(map[string]interface{}{
+				"user": properties(map[string]interface{}{
+					"name": map[string]interface{}{"type": "text"},
+				}),
+			}),
+			desired: properties(map[string]interface{}{
+				"user": properties(map[string]interface{}{
+					"name": map[string]interface{}{"type": "text"},
+					"age":  map[string]interface{}{"type": "integer"},
+				}),
+			}),
+		},
+		{
+			name: "added multi-field",
+			current: properties(map[string]interface{}{
+				"title": map[string]interface{}{"type": "text"},
+			}),
+			desired: properties(map[string]interface{}{
+				"title": map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"raw": map[string]interface{}{"type": "keyword"},
+					},
+				},
+			}),
+		},
+		{
+			name: "changed multi-field type",
+			current: properties(map[string]interface{}{
+				"title": map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"raw": map[string]interface{}{"type": "keyword"},
+					},
+				},
+			}),
+			desired: properties(map[string]interface{}{
+				"title": map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"raw": map[string]interface{}{"type": "wildcard"},
+					},
+				},
+			}),
+			incompatible: []string{"title.raw"},
+		},
+		{
+			name: "updatable parameter change",
+			current: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+			}),
+			desired: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword", "ignore_above": 1024},
+			}),
+		},
+		{
+			name: "disabling norms",
+			current: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text"},
+			}),
+			desired: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "norms": false},
+			}),
+		},
+		{
+			name: "enabling norms",
+			current: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "norms": false},
+			}),
+			desired: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "norms": true},
+			}),
+			incompatible: []string{"message"},
+		},
+		{
+			name: "type change",
+			current: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword"},
+			}),
+			desired: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "long"},
+			}),
+			incompatible: []string{"status"},
+		},
+		{
+			name: "object to field",
+			current: properties(map[string]interface{}{
+				"user": properties(map[string]interface{}{
+					"name": map[string]interface{}{"type": "text"},
+				}),
+			}),
+			desired: properties(map[string]interface{}{
+				"user": map[string]interface{}{"type": "keyword"},
+			}),
+			incompatible: []string{"user"},
+		},
+		{
+			name: "type change in an object",
+			current: properties(map[string]interface{}{
+				"user": properties(map[string]interface{}{
+					"age": map[string]interface{}{"type": "integer"},
+				}),
+			}),
+			desired: properties(map[string]interface{}{
+				"user": properties(map[string]interface{}{
+					"age": map[string]interface{}{"type": "keyword"},
+				}),
+			}),
+			incompatible: []string{"user.age"},
+		},
+		{
+			name: "analyzer change",
+			current: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "analyzer": "standard"},
+			}),
+			desired: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "analyzer": "english"},
+			}),
+			incompatible: []string{"message"},
+		},
+		{
+			name: "search analyzer change",
+			current: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "search_analyzer": "standard"},
+			}),
+			desired: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "search_analyzer": "english"},
+			}),
+		},
+		{
+			name: "omitted non-updatable parameter",
+			current: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text", "analyzer": "english"},
+			}),
+			desired: properties(map[string]interface{}{
+				"message": map[string]interface{}{"type": "text"},
+			}),
+			incompatible: []string{"message"},
+		},
+		{
+			name: "omitted updatable parameter",
+			current: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+			}),
+			desired: properties(map[string]interface{}{
+				"status": map[string]interface{}{"type": "keyword"},
+			}),
+		},
+		{
+			name:    "updatable mapping parameter change",
+			current: map[string]interface{}{"dynamic": "true"},
+			desired: map[string]interface{}{"dynamic": "strict"},
+		},
+		{
+			name:         "mapping-level parameter change",
+			current:      map[string]interface{}{},
+			desired:      map[string]interface{}{"_source": map[string]interface{}{"enabled": false}},
+			incompatible: []string{""},
+		},
+		{
+			name: "int and float64 numbers",
+			current: properties(map[string]interface{}{
+				"price": map[string]interface{}{"type": "scaled_float", "scaling_factor": float64(100)},
+			}),
+			desired: properties(map[string]interface{}{
+				"price": map[string]interface{}{"type": "scaled_float", "scaling_factor": 100},
+			}),
+		},
+		{
+			name: "int and float64 numbers with different values",
+			current: properties(map[string]interface{}{
+				"price": map[string]interface{}{"type": "scaled_float", "scaling_factor": float64(100)},
+			}),
+			desired: properties(map[string]interface{}{
+				"price": map[string]interface{}{"type": "scaled_float", "scaling_factor": 1000},
+			}),
+			incompatible: []string{"price"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, incompatibilities := IsCompatibleChange(tt.current, tt.desired)
+
+			fields := []string{}
+			for _, incompatibility := range incompatibilities {
+				fields = append(fields, incompatibility.Field)
+			}
+			expected := tt.incompatible
+			if expected == nil {
+				expected = []string{}
+			}
+			if !reflect.DeepEqual(fields, expected) {
+				t.Errorf("expected incompatibilities on %v, got %v", expected, incompatibilities)
+			}
+			if ok != (len(expected) == 0) {
+				t.Errorf("expected compatible to be %v, got %v", len(expected) == 0, ok)
+			}
+		})
+	}
+}
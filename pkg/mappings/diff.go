@@ -0,0 +1,99 @@
+package mappings
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change is a difference between the current and the desired mappings of an index.
+type Change struct {
+	// Field is the full path of the field, empty for mapping-level changes
+	Field       string
+	Description string
+	// InPlace is true if the change can be applied to the existing index with a PUT
+	// _mapping request, false if it requires reindexing.
+	InPlace bool
+}
+
+// DiffMappings lists the changes the desired mappings make to the current ones, sorted
+// by field. The changes that can't be applied in place are the incompatibilities
+// returned by IsCompatibleChange, the others are new fields and multi-fields and
+// changes to updatable parameters.
+func DiffMappings(current, desired map[string]interface{}) []Change {
+	current, desired = normalize(current), normalize(desired)
+
+	ret := []Change{}
+	for _, k := range sortedKeys(desired) {
+		if !updatableMappingParameters[k] {
+			continue
+		}
+		if v, ok := current[k]; !ok || !reflect.DeepEqual(v, desired[k]) {
+			ret = append(ret, Change{
+				Description: fmt.Sprintf("mapping parameter %s changed from %v to %v", k, formatValue(v, ok), desired[k]),
+				InPlace:     true,
+			})
+		}
+	}
+	ret = append(ret, diffProperties("", asMap(current["properties"]), asMap(desired["properties"]), false)...)
+
+	_, incompatibilities := IsCompatibleChange(current, desired)
+	for _, incompatibility := range incompatibilities {
+		ret = append(ret, Change{
+			Field:       incompatibility.Field,
+			Description: incompatibility.Reason,
+		})
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Field < ret[j].Field
+	})
+	return ret
+}
+
+// diffProperties lists the changes that can be applied in place to the fields of
+// current, multiField being true for the multi-fields of a field.
+func diffProperties(prefix string, current, desired map[string]interface{}, multiField bool) []Change {
+	ret := []Change{}
+	for _, name := range sortedKeys(desired) {
+		path := prefix + name
+		desiredField := asMap(desired[name])
+		currentField_, ok := current[name]
+		if !ok {
+			kind := "field"
+			if multiField {
+				kind = "multi-field"
+			}
+			ret = append(ret, Change{
+				Field:       path,
+				Description: fmt.Sprintf("new %s of type %s", kind, fieldType(desiredField)),
+				InPlace:     true,
+			})
+			continue
+		}
+
+		currentField := asMap(currentField_)
+		if fieldType(currentField) != fieldType(desiredField) {
+			// reported as an incompatibility
+			continue
+		}
+		for _, k := range sortedKeys(desiredField) {
+			currentValue, inCurrent := currentField[k]
+			if reflect.DeepEqual(currentValue, desiredField[k]) {
+				continue
+			}
+			if updatableFieldParameters[k] || (k == "norms" && desiredField[k] == false) {
+				ret = append(ret, Change{
+					Field: path,
+					Description: fmt.Sprintf("parameter %s changed from %v to %v",
+						k, formatValue(currentValue, inCurrent), desiredField[k]),
+					InPlace: true,
+				})
+			}
+		}
+
+		ret = append(ret, diffProperties(path+".", asMap(currentField["properties"]), asMap(desiredField["properties"]), false)...)
+		ret = append(ret, diffProperties(path+".", asMap(currentField["fields"]), asMap(desiredField["fields"]), true)...)
+	}
+	return ret
+}
@@ -0,0 +1,53 @@
+package mappings
+
+import "math"
+
+const (
+	// DefaultTotalFieldsLimit is the default of index.mapping.total_fields.limit.
+	DefaultTotalFieldsLimit = 1000
+	// totalFieldsLimitMargin leaves room for fields added dynamically after the index
+	// has been created.
+	totalFieldsLimitMargin = 1.2
+)
+
+// CountFields returns the number of fields of mappings as counted against
+// index.mapping.total_fields.limit: object fields, leaf fields, multi-fields and
+// runtime fields.
+func CountFields(mappings map[string]interface{}) int {
+	count := 0
+	if properties, ok := mappings["properties"].(map[string]interface{}); ok {
+		count += countProperties(properties)
+	}
+	if runtime, ok := mappings["runtime"].(map[string]interface{}); ok {
+		count += len(runtime)
+	}
+	return count
+}
+
+func countProperties(properties map[string]interface{}) int {
+	count := 0
+	for _, field := range properties {
+		count++
+		field_, ok := field.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if properties_, ok := field_["properties"].(map[string]interface{}); ok {
+			count += countProperties(properties_)
+		}
+		if fields, ok := field_["fields"].(map[string]interface{}); ok {
+			count += countProperties(fields)
+		}
+	}
+	return count
+}
+
+// SuggestTotalFieldsLimit returns a index.mapping.total_fields.limit leaving a safety
+// margin above fieldCount, and false if the default limit is enough.
+func SuggestTotalFieldsLimit(fieldCount int) (int, bool) {
+	limit := int(math.Ceil(float64(fieldCount) * totalFieldsLimitMargin))
+	if limit <= DefaultTotalFieldsLimit {
+		return DefaultTotalFieldsLimit, false
+	}
+	return limit, true
+}
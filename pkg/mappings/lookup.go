@@ -0,0 +1,151 @@
+package mappings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldMapping is the mapping of a single field along with what it inherits from the
+// objects containing it.
+type FieldMapping struct {
+	Path    string
+	Mapping map[string]interface{}
+	// Dynamic is the effective dynamic setting (true, false, strict or runtime) of the
+	// object containing the field, which applies to new fields added next to it.
+	Dynamic string
+	// NestedPath is the path of the closest nested field containing the field.
+	NestedPath string
+	// MultiFieldOf is the path of the field this field is a multi-field of.
+	MultiFieldOf string
+	// Runtime is true for fields of the runtime section of the mappings.
+	Runtime bool
+	// AliasOf is the path of the alias field the field was looked up through, Path
+	// being the concrete field the alias points to.
+	AliasOf string
+}
+
+// Type returns the type of the field, object fields omitting it.
+func (f *FieldMapping) Type() string {
+	return fieldType(f.Mapping)
+}
+
+// LookupField returns the mapping of the field at the dotted path, which can go through
+// object and nested fields as well as multi-fields (title.keyword). Field names
+// containing dots, as used with subobjects: false, are matched as well.
+//
+// Alias fields are resolved to the field they point to, with AliasOf set to path.
+func LookupField(mappings map[string]interface{}, path string) (*FieldMapping, bool) {
+	ret, ok := lookupField(mappings, path)
+	if !ok || ret.Type() != "alias" {
+		return ret, ok
+	}
+	// aliases can't point to other aliases, a single resolution is enough
+	target, _ := ret.Mapping["path"].(string)
+	concrete, ok := lookupField(mappings, target)
+	if !ok {
+		// dangling alias, return the alias itself
+		return ret, true
+	}
+	concrete.AliasOf = path
+	return concrete, true
+}
+
+func lookupField(mappings map[string]interface{}, path string) (*FieldMapping, bool) {
+	if runtime, ok := mappings["runtime"].(map[string]interface{}); ok {
+		if field, ok := runtime[path].(map[string]interface{}); ok {
+			return &FieldMapping{
+				Path:    path,
+				Mapping: field,
+				Dynamic: dynamicSetting(mappings, "true"),
+				Runtime: true,
+			}, true
+		}
+	}
+
+	properties, ok := mappings["properties"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	ret := &FieldMapping{Path: path}
+	if !lookupProperties(properties, strings.Split(path, "."), "", dynamicSetting(mappings, "true"), ret) {
+		return nil, false
+	}
+	return ret, true
+}
+
+func lookupProperties(
+	properties map[string]interface{},
+	segments []string,
+	prefix string,
+	dynamic string,
+	ret *FieldMapping,
+) bool {
+	// try the longest names first, a field named "a.b" shadowing the field b of a
+	for i := len(segments); i > 0; i-- {
+		field, ok := properties[strings.Join(segments[:i], ".")].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := strings.Join(append(splitPrefix(prefix), segments[:i]...), ".")
+		if i == len(segments) {
+			ret.Mapping = field
+			ret.Dynamic = dynamic
+			return true
+		}
+
+		if fieldType(field) == "nested" {
+			nestedPath := ret.NestedPath
+			ret.NestedPath = path
+			if lookupChildren(field, segments[i:], path, dynamic, ret) {
+				return true
+			}
+			ret.NestedPath = nestedPath
+			continue
+		}
+		if lookupChildren(field, segments[i:], path, dynamic, ret) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupChildren looks up the remaining segments in the properties of an object field,
+// or in the multi-fields of a leaf field.
+func lookupChildren(
+	field map[string]interface{},
+	segments []string,
+	path string,
+	dynamic string,
+	ret *FieldMapping,
+) bool {
+	if properties, ok := field["properties"].(map[string]interface{}); ok {
+		if lookupProperties(properties, segments, path, dynamicSetting(field, dynamic), ret) {
+			return true
+		}
+	}
+	if fields, ok := field["fields"].(map[string]interface{}); ok && len(segments) == 1 {
+		if multiField, ok := fields[segments[0]].(map[string]interface{}); ok {
+			ret.Mapping = multiField
+			ret.Dynamic = dynamic
+			ret.MultiFieldOf = path
+			return true
+		}
+	}
+	return false
+}
+
+// dynamicSetting returns the dynamic setting of an object mapping, or inherited if it
+// doesn't set it.
+func dynamicSetting(mapping map[string]interface{}, inherited string) string {
+	if dynamic, ok := mapping["dynamic"]; ok {
+		return fmt.Sprint(dynamic)
+	}
+	return inherited
+}
+
+func splitPrefix(prefix string) []string {
+	if prefix == "" {
+		return nil
+	}
+	return []string{prefix}
+}
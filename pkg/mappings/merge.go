@@ -0,0 +1,82 @@
+package mappings
+
+import (
+	"sort"
+)
+
+// Conflict is a field (or mapping parameter) that an index maps differently from the
+// indices merged before it.
+type Conflict struct {
+	Index string
+	Incompatibility
+}
+
+func (c Conflict) String() string {
+	return c.Index + ": " + c.Incompatibility.String()
+}
+
+// MergeMappings merges the mappings of several indices, by index name, into mappings
+// that can hold the documents of all of them, as needed to reindex them into a single
+// index. The indices are merged in name order, each adding the fields the previous
+// ones don't have.
+//
+// A field mapped differently by two indices is a conflict, using the same rules as
+// IsCompatibleChange: different types and non-updatable parameters conflict, while
+// updatable parameters keep the value of the first index mapping the field. The
+// merged mappings are only valid if no conflict is returned.
+func MergeMappings(mappings map[string]map[string]interface{}) (map[string]interface{}, []Conflict) {
+	indices := make([]string, 0, len(mappings))
+	for index := range mappings {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	var merged map[string]interface{}
+	conflicts := []Conflict{}
+	for _, index := range indices {
+		m := normalize(mappings[index])
+		if merged == nil {
+			merged = m
+			continue
+		}
+
+		_, incompatibilities := IsCompatibleChange(merged, m)
+		for _, incompatibility := range incompatibilities {
+			conflicts = append(conflicts, Conflict{Index: index, Incompatibility: incompatibility})
+		}
+		addMissing(merged, m)
+	}
+	if merged == nil {
+		merged = map[string]interface{}{}
+	}
+
+	return merged, conflicts
+}
+
+// addMissing adds the entries of src missing from dst, recursing into the properties
+// and multi-fields of the fields present in both.
+func addMissing(dst, src map[string]interface{}) {
+	for k, v := range src {
+		dstValue, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		srcMap, srcIsMap := v.(map[string]interface{})
+		if !dstIsMap || !srcIsMap {
+			continue
+		}
+		switch k {
+		case "properties", "fields":
+			for name, field := range srcMap {
+				dstField, ok := dstMap[name]
+				if !ok {
+					dstMap[name] = field
+					continue
+				}
+				addMissing(asMap(dstField), asMap(field))
+			}
+		}
+	}
+}